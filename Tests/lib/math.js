@@ -0,0 +1,27 @@
+// small integer helpers shared by the test programs
+
+function max(a, b) {
+	var r = b
+	if (a > b) {
+		r = a
+	}
+	return r
+}
+
+function min(a, b) {
+	var r = b
+	if (a < b) {
+		r = a
+	}
+	return r
+}
+
+function clamp(x, lo, hi) {
+	var r = max(x, lo)
+	r = min(r, hi)
+	return r
+}
+
+function modMul(x, y, mod) {
+	return (x * y) % mod
+}
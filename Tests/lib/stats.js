@@ -0,0 +1,8 @@
+// statistics on three values, built on math.js
+
+// @import "math.js"
+
+function spread(a, b, c) {
+	var s = math.max(math.max(a, b), c) - math.min(math.min(a, b), c)
+	return s
+}
@@ -0,0 +1,20 @@
+// error: $maxdepth must be declared as a positive number in count
+// a $maxdepth which bounds nothing
+
+var $parties = 1
+var $intsize = 8
+
+var in_0 = 0
+var out_0 = 0
+var truncated = false
+
+function count(x) {
+	var $maxdepth = 0
+	var n = 0
+	if (x != 0) {
+		n = 1 + count(x >> 1)
+	}
+	return n
+}
+
+out_0 = count(in_0)
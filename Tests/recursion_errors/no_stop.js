@@ -0,0 +1,18 @@
+// error: the recursion of sum does not stop, it calls sum($n=3) again
+// a recursion calling a function with the same dollar parameters again
+
+var $parties = 1
+var $intsize = 8
+
+var in_0 = [0, 0, 0, 0]
+var out_0 = 0
+
+function sum($n) {
+	var r = in_0[$n]
+	if ($n > 0) {
+		r = r + sum($n)
+	}
+	return r
+}
+
+out_0 = sum(3)
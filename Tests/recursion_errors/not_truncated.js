@@ -0,0 +1,19 @@
+// error: the calls of count nested deeper than the $maxdepth of 4 are not executed, declare var truncated = false
+// a $maxdepth without the variable telling when the bound is reached
+
+var $parties = 1
+var $intsize = 8
+
+var in_0 = 0
+var out_0 = 0
+
+function count(x) {
+	var $maxdepth = 4
+	var n = 0
+	if (x != 0) {
+		n = 1 + count(x >> 1)
+	}
+	return n
+}
+
+out_0 = count(in_0)
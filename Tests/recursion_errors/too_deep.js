@@ -0,0 +1,18 @@
+// error: the recursion of up cannot be proven to stop, it goes deeper than 1000 calls
+// a recursion whose dollar parameter never reaches the condition which stops it
+
+var $parties = 1
+var $intsize = 8
+
+var in_0 = 0
+var out_0 = 0
+
+function up($n) {
+	var r = in_0
+	if ($n != 0) {
+		r = r + up($n + 1)
+	}
+	return r
+}
+
+out_0 = up(1)
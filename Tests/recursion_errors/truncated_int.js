@@ -0,0 +1,20 @@
+// error: the variable truncated set by the calls deeper than a $maxdepth must be a boolean
+// a variable truncated which is not a boolean
+
+var $parties = 1
+var $intsize = 8
+
+var in_0 = 0
+var out_0 = 0
+var truncated = 0
+
+function count(x) {
+	var $maxdepth = 4
+	var n = 0
+	if (x != 0) {
+		n = 1 + count(x >> 1)
+	}
+	return n
+}
+
+out_0 = count(in_0)
@@ -0,0 +1,18 @@
+// error: the depth of the recursive function count cannot be bounded
+// a recursion without dollar parameter nor $maxdepth
+
+var $parties = 1
+var $intsize = 8
+
+var in_0 = 0
+var out_0 = 0
+
+function count(x) {
+	var n = 0
+	if (x != 0) {
+		n = 1 + count(x >> 1)
+	}
+	return n
+}
+
+out_0 = count(in_0)
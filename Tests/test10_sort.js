@@ -0,0 +1,25 @@
+var $parties = 2
+var $intsize = 8
+
+var in_0 = [0, 0, 0, 0, 0, 0, 0]
+var in_1 = [{id: 0, score: 0}, {id: 0, score: 0}, {id: 0, score: 0}, {id: 0, score: 0}, {id: 0, score: 0}]
+var out_0 = {sorted: [0, 0, 0, 0, 0, 0, 0], median: 0}
+var out_1 = [{id: 0, score: 0}, {id: 0, score: 0}, {id: 0, score: 0}, {id: 0, score: 0}, {id: 0, score: 0}]
+
+// median of the values of party 0
+out_0.sorted = Sort(in_0)
+out_0.median = out_0.sorted[3]
+
+// ranking of party 1 by score, the best first
+for (var $i = 0; $i < 5; $i++) {
+	in_1[$i].score = -in_1[$i].score
+}
+out_1 = SortBy(in_1, "score")
+for (var $j = 0; $j < 5; $j++) {
+	out_1[$j].score = -out_1[$j].score
+}
+
+// a tie for the first place goes to the larger id
+if (out_1[0].score == out_1[1].score) {
+	CompareExchangeBy(out_1, 1, 0, "id")
+}
@@ -0,0 +1,41 @@
+// Private set intersection of 2 parties, giving the intersection to every party.
+// Generated with: psi -parties 2 -size 6 -width 16 -method pairwise
+
+var $parties = 2
+var $intsize = 16
+var $size = 6
+var $max = 32767
+
+var in_0 = {n: 0, set: [0, 0, 0, 0, 0, 0]}
+var in_1 = {n: 0, set: [0, 0, 0, 0, 0, 0]}
+var out_0 = {n: 0, set: [0, 0, 0, 0, 0, 0]}
+var out_1 = {n: 0, set: [0, 0, 0, 0, 0, 0]}
+
+// the elements of the party 0 found in all the other sets
+var common = [0, 0, 0, 0, 0, 0]
+var count = 0
+var found = false
+for (var $i = 0; $i < $size; $i++) {
+	var member = $i < in_0.n
+	found = false
+	for (var $j = 0; $j < $size; $j++) {
+		found = found || ($j < in_1.n && in_0.set[$i] == in_1.set[$j])
+	}
+	member = member && found
+	common[$i] = $max
+	if (member) {
+		common[$i] = in_0.set[$i]
+		count = count + 1
+	}
+}
+
+var first = Sort(common)
+var result = {n: 0, set: [0, 0, 0, 0, 0, 0]}
+result.n = count
+for (var $k = 0; $k < $size; $k++) {
+	if ($k < count) {
+		result.set[$k] = first[$k]
+	}
+}
+out_0 = result
+out_1 = result
@@ -0,0 +1,61 @@
+// Private set intersection of 3 parties, giving the number of common elements to every party.
+// Generated with: psi -parties 3 -size 5 -width 16 -method sort -cardinality
+
+var $parties = 3
+var $intsize = 16
+var $size = 5
+var $max = 32767
+
+var in_0 = {n: 0, set: [0, 0, 0, 0, 0]}
+var in_1 = {n: 0, set: [0, 0, 0, 0, 0]}
+var in_2 = {n: 0, set: [0, 0, 0, 0, 0]}
+var out_0 = 0
+var out_1 = 0
+var out_2 = 0
+
+// the elements of all the sets, the padding being replaced by $max
+var all = [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0]
+var maxes = 0
+for (var $i = 0; $i < $size; $i++) {
+	all[$i] = $max
+	if ($i < in_0.n) {
+		all[$i] = in_0.set[$i]
+		if (in_0.set[$i] == $max) {
+			maxes = maxes + 1
+		}
+	}
+	all[5 + $i] = $max
+	if ($i < in_1.n) {
+		all[5 + $i] = in_1.set[$i]
+		if (in_1.set[$i] == $max) {
+			maxes = maxes + 1
+		}
+	}
+	all[10 + $i] = $max
+	if ($i < in_2.n) {
+		all[10 + $i] = in_2.set[$i]
+		if (in_2.set[$i] == $max) {
+			maxes = maxes + 1
+		}
+	}
+}
+var sorted = Sort(all)
+
+// an element is in all the sets when it starts a run of $parties equal elements
+var count = 0
+for (var $k = 0; $k < 13; $k++) {
+	var start = sorted[$k] != $max
+	for (var $t = 1; $t < $parties; $t++) {
+		start = start && sorted[$k] == sorted[$k + $t]
+	}
+	if (start) {
+		count = count + 1
+	}
+}
+if (maxes == $parties) {
+	count = count + 1
+}
+
+out_0 = count
+out_1 = count
+out_2 = count
@@ -0,0 +1,58 @@
+// calls to functions writing globals, in nested if statements on secret conditions
+
+var $parties = 2
+var $intsize = 8
+
+var in_0 = [0, 0, 0]
+var in_1 = {a: 0, b: 0}
+var out_0 = {acc: [0, 0, 0], n: 0, last: 0, r: 0}
+
+var acc = [0, 0, 0]
+var stats = {n: 0, last: 0}
+
+function record(x) {
+	stats.n++
+	stats.last = x
+	if (x > 10) {
+		acc[0] = acc[0] + x
+	} else {
+		acc[1] = acc[1] - 1
+	}
+	return stats.n
+}
+
+function pure(x, y) {
+	return x * y + 1
+}
+
+var r = 0
+var t = 0
+for (var $i = 0; $i < 3; $i++) {
+	if (in_0[$i] > in_1.a) {
+		if (in_0[$i] != in_1.b) {
+			r = r + twice(in_0[$i])
+		} else {
+			record(in_1.b)
+			acc[2]++
+		}
+	} else if (in_0[$i] < in_1.b) {
+		t = pure(in_0[$i], in_1.a)
+		r = r - t
+	}
+}
+if (r > 3) {
+	acc[1] = pure(acc[1], r)
+}
+out_0.acc = acc
+out_0.n = stats.n
+out_0.last = stats.last
+out_0.r = r
+
+function twice(x) {
+	var r = 0
+	r = record(x)
+	if (x < 0) {
+		r = r + record(-x)
+	}
+	return r
+}
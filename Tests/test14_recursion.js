@@ -0,0 +1,62 @@
+// recursions unrolled at compile time, bounded by a dollar parameter or by $maxdepth
+
+var $parties = 2
+var $intsize = 16
+
+var in_0 = [0, 0, 0, 0, 0]
+var in_1 = [0, 0, 0, 0, 0]
+var out_0 = {dot: 0, halvings: 0, parity: 0, calls: 0, truncated: false}
+
+var calls = 0
+// set by the calls deeper than a $maxdepth, which are not executed
+var truncated = false
+
+// sum of the products of the items $lo to $hi - 1, by halves
+function dot($lo, $hi) {
+	var r = 0
+	if ($hi - $lo == 1) {
+		r = in_0[$lo] * in_1[$lo]
+	} else {
+		var $mid = ($lo + $hi) / 2
+		r = dot($lo, $mid) + dot($mid, $hi)
+	}
+	return r
+}
+
+// number of halvings of x until it is 0, at most 5 for x < 32
+function halvings(x) {
+	var $maxdepth = 6
+	var n = 0
+	if (x != 0) {
+		n = 1 + halvings(x >> 1)
+	}
+	return n
+}
+
+// 1 when x is even, by mutual recursion
+function even(x) {
+	var $maxdepth = 10
+	var r = 1
+	calls++
+	if (x > 0) {
+		r = odd(x - 1)
+	}
+	return r
+}
+
+function odd(x) {
+	var r = 0
+	calls++
+	if (x > 0) {
+		r = even(x - 1)
+	}
+	return r
+}
+
+out_0.dot = dot(0, 5)
+out_0.halvings = halvings(in_0[0] & 31)
+if (in_1[0] > 0) {
+	out_0.parity = even(in_1[1] & 7) + odd(in_1[2] & 3) * 10
+}
+out_0.calls = calls
+out_0.truncated = truncated
@@ -0,0 +1,53 @@
+// functions compiled once per types and known values of their arguments
+
+var $parties = 2
+var $intsize = 16
+
+var in_0 = [0, 0, 0, 0, 0]
+var in_1 = [0, 0, 0]
+var out_0 = {ends: 0, picks: 0, scaled: 0, local: 0, order: 0}
+
+var n = 0
+
+// sum of the first and second items of an array of any length
+function ends(a) {
+	var r = a[0] + a[1]
+	return r
+}
+
+function pick(c, x, y) {
+	var r = y
+	if (c) {
+		r = x
+	}
+	return r
+}
+
+function scale(x, k) {
+	var r = x
+	if (k > 1) {
+		r = x * k
+	}
+	return r
+}
+
+function triple(x) {
+	var t = [0, 0, 0]
+	t[0] = x
+	t[1] = x + 1
+	t[2] = x + 2
+	var s = ends(t) + t[2]
+	return s
+}
+
+function diff(a, b) {
+	var d = a - b
+	return d
+}
+
+out_0.ends = ends(in_0) * 100 + ends(in_1)
+out_0.picks = pick(true, in_0[0], in_1[0]) + pick(false, in_0[1], in_1[1]) * 10 + pick(in_0[2] > in_1[2], 1, 2) * 100
+out_0.scaled = scale(in_0[3], 3) + scale(in_0[4], 1) + scale(in_1[0], in_1[1])
+out_0.local = triple(in_1[2])
+n = in_0[0]
+out_0.order = diff(n, n++) * 10 + n
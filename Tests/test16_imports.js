@@ -0,0 +1,16 @@
+// functions imported from other files
+
+// @import "lib/math.js"
+// @import "lib/stats" as st
+
+var $parties = 2
+var $intsize = 16
+
+var in_0 = [0, 0]
+var in_1 = 0
+var out_0 = {largest: 0, clamped: 0, spread: 0, product: 0}
+
+out_0.largest = math.max(math.max(in_0[0], in_0[1]), in_1)
+out_0.clamped = math.clamp(in_1, 10, 100)
+out_0.spread = st.spread(in_0[0], in_0[1], in_1)
+out_0.product = math.modMul(in_0[0], in_1, 97)
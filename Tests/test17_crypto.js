@@ -0,0 +1,20 @@
+// functions of the standard library of cryptographic primitives
+
+var $parties = 2
+var $intsize = 40
+
+var in_0 = {key: [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0], stream: [0, 0, 0, 0, 0, 0, 0, 0], a: 0}
+var in_1 = {block: [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0], message: [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0], nonce: [0, 0, 0], b: 0}
+var out_0 = {cipher: [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0], digest: [0, 0, 0, 0, 0, 0, 0, 0], keystream: [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0], product: 0}
+
+// the initial hash value of SHA-256
+var iv = [0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19]
+
+// a product modulo n, 2^80 modulo n giving it back from the Montgomery product
+var n = 1000003
+var r2 = 554286
+
+out_0.cipher = AES128(in_0.key, in_1.block)
+out_0.digest = SHA256Compress(iv, in_1.message)
+out_0.keystream = ChaCha20Block(in_0.stream, 1, in_1.nonce)
+out_0.product = MontMul(MontMul(in_0.a, in_1.b, n), r2, n)
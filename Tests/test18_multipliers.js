@@ -0,0 +1,20 @@
+// products of secret integers above the threshold of Karatsuba's algorithm,
+// and products by known values
+
+var $parties = 2
+var $intsize = 48
+var $k = -1000003
+
+var in_0 = [0, 0]
+var in_1 = 0
+var out_0 = {secret: 0, sum: 0, constant: 0, negative: 0, known: 0, assigned: 0}
+
+out_0.secret = in_0[0] * in_1
+out_0.sum = (in_0[0] + in_0[1]) * (in_1 - 12)
+out_0.constant = in_0[1] * 140737488355327
+out_0.negative = $k * in_1
+var three = 3
+out_0.known = in_0[0] * three * 21845
+var x = in_1
+x = x * 255
+out_0.assigned = x
@@ -0,0 +1,20 @@
+// divisions and moduli by known integers, which are multiplications by
+// their reciprocals, and by a secret integer
+
+var $parties = 2
+var $intsize = 32
+var $m = 1000
+
+var in_0 = [0, 0]
+var in_1 = 0
+var out_0 = {third: 0, seventh: 0, mod: 0, shift: 0, bias: 0, negative: 0, known: 0, secret: 0}
+
+out_0.third = in_0[0] / 3
+out_0.seventh = (in_0[0] + in_0[1]) / 7
+out_0.mod = in_1 % $m
+out_0.shift = in_0[1] / 16
+out_0.bias = in_1 % -8
+out_0.negative = in_0[1] / -10
+var d = 641
+out_0.known = in_1 % d
+out_0.secret = in_0[0] % (in_1 / 1000 + 1)
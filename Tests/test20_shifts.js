@@ -0,0 +1,18 @@
+// shifts and rotations by known and secret amounts, and bitwise NOT
+
+var $parties = 2
+var $intsize = 32
+
+var in_0 = [0, 0]
+var in_1 = 0
+var out_0 = {left: 0, signed: 0, unsigned: 0, rotated: 0, not: 0, sigma: 0, overflow: 0}
+
+var s = in_1 & 31
+out_0.left = in_0[0] << s
+out_0.signed = in_0[1] >> s
+out_0.unsigned = in_0[1] >>> s
+out_0.rotated = RotateLeft(in_0[0], s)
+out_0.not = ~in_0[0] & in_0[1]
+var x = in_0[0]
+out_0.sigma = RotateLeft(x, 30) ^ RotateLeft(x, 19) ^ RotateLeft(x, 10) ^ (x >>> 3)
+out_0.overflow = in_0[1] >> in_1
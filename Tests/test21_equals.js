@@ -0,0 +1,14 @@
+// equality of integers which differ only by their second bit, which the
+// equality test used to skip
+
+var $parties = 2
+var $intsize = 8
+
+var in_0 = 0
+var in_1 = 0
+var out_0 = {equal: false, different: false, two: false, zero: false}
+
+out_0.equal = in_0 == in_1
+out_0.different = in_0 != in_1
+out_0.two = in_0 == 2
+out_0.zero = in_1 - 2 == 0
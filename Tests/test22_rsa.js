@@ -0,0 +1,22 @@
+// modular exponentiation of RSA, by squaring and multiplying for the bits of
+// the secret exponent
+
+var $parties = 2
+var $intsize = 16
+
+var in_0 = 0 // base
+var in_1 = 0 // exponent
+var out_0 = 1
+
+var mod = 19 // in_0 ^ in_1
+for (var $i = 0; $i < $intsize; $i++) {
+	if (GetWire(in_1, 0)) {
+		out_0 = modMul(out_0, in_0, mod)
+	}
+	in_1 = in_1 >> 1
+	in_0 = modMul(in_0, in_0, mod)
+}
+
+function modMul(x, y, mod) {
+	return (x * y) % mod
+}
@@ -1,11 +1,26 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
 	builder "ixxoprivacy/pkg/builder"
+	"ixxoprivacy/pkg/checker"
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
 	"ixxoprivacy/pkg/garbler"
+	"ixxoprivacy/pkg/optimizer"
+	"ixxoprivacy/pkg/profiler"
+	"ixxoprivacy/pkg/psi"
 	"ixxoprivacy/pkg/runner"
+	"ixxoprivacy/pkg/transport"
+	typ "ixxoprivacy/pkg/types"
 	"log"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/mitchellh/cli"
 )
@@ -13,17 +28,30 @@ import (
 type buildCommand struct{}
 type runCommand struct{}
 type garbleCommand struct{}
+type profileCommand struct{}
+type dotCommand struct{}
+type optimizeCommand struct{}
+type libCommand struct{}
+type checkCommand struct{}
+type schemaCommand struct{}
+type psiCommand struct{}
+type serveCommand struct{}
+type evaluateCommand struct{}
 
 func (c *buildCommand) Help() string {
 	return "This command builds a circuit from a javascript file. Note that the Javascript has specific conventions for MPC, refer to the documentation."
 }
 func (c *buildCommand) Run(args []string) int {
-	if len(args) != 1 {
+	if len(args) == 0 {
 		log.Println("You have to provide the name of the file to build")
 		return 1
 	}
 	//var filename := args[0]
 	fileName := args[0]
+	// the other arguments are libraries of functions the program can call
+	for _, lib := range args[1:] {
+		compiler.AddLibrary(circ.RetrieveLibrary(lib))
+	}
 	builder.BuildCircuit(fileName)
 	return 0
 }
@@ -33,13 +61,26 @@ func (c *buildCommand) Synopsis() string {
 }
 
 func (c *runCommand) Help() string {
-	return "Runs a circuit. You have to provide the compiled circuit file as a parameter as the first argument, and the list of imput"
+	return `Runs a circuit. You have to provide the compiled circuit file as a parameter as the first argument, and the list of imput
+Usage: run [-debug] file.re|file.js [input.json...]
+  -debug   compile a javascript file and run it statement by statement, with breakpoints
+           on lines and functions and the values of the variables`
 }
 func (c *runCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	debugMode := flags.Bool("debug", false, "run a javascript file in the debugger")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	args = flags.Args()
 	if len(args) == 0 {
 		log.Println("You have to provide the name of the file to run")
 		return 1
 	}
+	if *debugMode {
+		runner.DebugProgram(args[0], args[1:])
+		return 0
+	}
 	compiledCircuit := args[0]
 	inputFiles := args[1:]
 	runner.RunCircuit(compiledCircuit, inputFiles)
@@ -51,13 +92,27 @@ func (c *runCommand) Synopsis() string {
 }
 
 func (c *garbleCommand) Help() string {
-	return "Garbles a circuit. Add true as a second argument to see the garbled circuit in debug mode."
+	return `Garbles a circuit. Add true as a second argument to see the garbled circuit in debug mode.
+Usage: garble [-stream] [-workers n] circuit.re [true|false]
+  -stream     write the tables to a .tables file as they are produced, with bounded memory
+  -workers n  number of goroutines garbling independent gates at the same time with -stream`
 }
 func (c *garbleCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("garble", flag.ContinueOnError)
+	stream := flags.Bool("stream", false, "stream the tables to a file")
+	workers := flags.Int("workers", 1, "number of workers")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	args = flags.Args()
 	if len(args) == 0 {
 		log.Println("You have to provide the name of the file to garble")
 		return 1
 	}
+	if *stream {
+		garbler.GarbleStreamCompiledCircuit(args[0], 8, *workers)
+		return 0
+	}
 	if len(args) == 1 {
 		garbler.GarbleCompiledCircuit(args[0], false, 8)
 	} else {
@@ -76,6 +131,368 @@ func (c *garbleCommand) Synopsis() string {
 	return "Garbles a circuit"
 }
 
+func (c *profileCommand) Help() string {
+	return "Compiles a javascript file and prints its source annotated with the number of non-XOR and XOR gates of every line. An optional second argument is a regular expression selecting the functions to list."
+}
+func (c *profileCommand) Run(args []string) int {
+	if len(args) == 0 || len(args) > 2 {
+		log.Println("You have to provide the name of the file to profile")
+		return 1
+	}
+	pattern := ""
+	if len(args) == 2 {
+		pattern = args[1]
+	}
+	if err := profiler.ProfileJS(args[0], pattern, os.Stdout); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+func (c *profileCommand) Synopsis() string {
+	return "Prints the gates count of a javascript file line by line"
+}
+
+func (c *dotCommand) Help() string {
+	return `Exports a compiled circuit to the DOT language of Graphviz.
+Usage: dot [-func i] [-collapse] [-o file.dot] circuit.re
+  -func i      draw the function of index i instead of the main function
+  -collapse    draw mass copies and replications as a single node
+  -o file.dot  write the graph to a file instead of the standard output`
+}
+func (c *dotCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("dot", flag.ContinueOnError)
+	fun := flags.Int("func", -1, "index of the function to draw")
+	collapse := flags.Bool("collapse", false, "collapse mass copies and replications")
+	output := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() != 1 {
+		log.Println("You have to provide the name of the circuit to export")
+		return 1
+	}
+	circuit := circ.RetrieveCircuit(flags.Arg(0))
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	opts := circ.DotOptions{Collapse: *collapse}
+	var err error
+	if *fun < 0 {
+		err = circuit.WriteDot(w, opts)
+	} else {
+		err = circuit.WriteFuncDot(w, *fun, opts)
+	}
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+func (c *dotCommand) Synopsis() string {
+	return "Exports a circuit to Graphviz"
+}
+
+func (c *optimizeCommand) Help() string {
+	return `Optimizes a compiled circuit, renumbers its wires to reuse the ones which are no longer needed,
+and prints the number of gates and wires removed by every pass.
+Usage: optimize [-o file.re] circuit.re
+  -o file.re  write the optimized circuit to a file instead of replacing the original one`
+}
+func (c *optimizeCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	output := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() != 1 {
+		log.Println("You have to provide the name of the circuit to optimize")
+		return 1
+	}
+	circuit := circ.RetrieveCircuit(flags.Arg(0))
+	for _, r := range optimizer.Optimize(&circuit) {
+		r.Print(os.Stdout)
+	}
+	if *output == "" {
+		*output = flags.Arg(0)
+	}
+	circuit.SaveToFile(*output)
+	return 0
+}
+func (c *optimizeCommand) Synopsis() string {
+	return "Reduces the number of non-XOR gates of a circuit"
+}
+
+func (c *libCommand) Help() string {
+	return `Compiles functions of a javascript file into a library which can be linked into other circuits
+by giving it to the build command after the file to build. The library is saved with the .lib extension.
+Usage: lib file.js function...`
+}
+func (c *libCommand) Run(args []string) int {
+	if len(args) < 2 {
+		log.Println("You have to provide the name of the file and of the functions to compile")
+		return 1
+	}
+	builder.BuildLibrary(args[0], args[1:])
+	return 0
+}
+func (c *libCommand) Synopsis() string {
+	return "Compiles functions into a library of precompiled functions"
+}
+
+func (c *checkCommand) Help() string {
+	return `Compiles a javascript file and checks that the circuit gives the same outputs as the program run
+as plain JavaScript, the integers of the program wrapping around at $intsize bits like the ones of the circuit.
+The program is run on the inputs of the json files given, or on random inputs when there are none.
+Usage: check [-runs n] [-seed s] file.js [input.json...]
+  -runs n   number of random inputs to check the program on
+  -seed s   seed of the random inputs`
+}
+func (c *checkCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	runs := flags.Int("runs", 1000, "number of random inputs")
+	seed := flags.Int64("seed", 1, "seed of the random inputs")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() == 0 {
+		log.Println("You have to provide the name of the file to check")
+		return 1
+	}
+	ch, err := checker.NewChecker(flags.Arg(0))
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	var mismatches []checker.Mismatch
+	if flags.NArg() > 1 {
+		inputs, err := checker.ReadInputs(flags.Args()[1:])
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		if mismatches, err = ch.Check(inputs); err != nil {
+			log.Println(err)
+			return 1
+		}
+	} else {
+		var undefined int
+		mismatches, undefined, err = ch.Fuzz(*runs, rand.New(rand.NewSource(*seed)))
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		fmt.Println(*runs-undefined, "runs checked,", undefined, "runs with unspecified results")
+	}
+	for _, m := range mismatches {
+		fmt.Println(m)
+	}
+	if len(mismatches) > 0 {
+		return 1
+	}
+	return 0
+}
+func (c *checkCommand) Synopsis() string {
+	return "Checks a circuit against its program run as plain JavaScript"
+}
+
+func (c *schemaCommand) Help() string {
+	return `Emits the JSON Schemas of the inputs and outputs of the parties of a circuit, or of a javascript file.
+The schemas are written in a single JSON document {"inputs": [...], "outputs": [...]} indexed by party,
+or with -o in the files input-N.schema.json and output-N.schema.json of a directory.
+Usage: schema [-o dir] file.re|file.js`
+}
+func (c *schemaCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	output := flags.String("o", "", "output directory")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() != 1 {
+		log.Println("You have to provide the name of the circuit or of the javascript file")
+		return 1
+	}
+	var circuit circ.Circuit
+	if strings.HasSuffix(flags.Arg(0), ".js") {
+		var err error
+		if circuit, err = compiler.CircuitFromJS(flags.Arg(0)); err != nil {
+			log.Println(err)
+			return 1
+		}
+	} else {
+		circuit = circ.RetrieveCircuit(flags.Arg(0))
+	}
+
+	schemas := map[string][]interface{}{"inputs": nil, "outputs": nil}
+	for party := 0; party < int(circuit.Parties); party++ {
+		schemas["inputs"] = append(schemas["inputs"], partySchema(circuit.Inputs, party, "input"))
+		schemas["outputs"] = append(schemas["outputs"], partySchema(circuit.Outputs, party, "output"))
+	}
+	if *output == "" {
+		raw, _ := json.MarshalIndent(schemas, "", "  ")
+		fmt.Println(string(raw))
+		return 0
+	}
+	for _, kind := range []string{"input", "output"} {
+		for party, schema := range schemas[kind+"s"] {
+			raw, _ := json.MarshalIndent(schema, "", "  ")
+			path := filepath.Join(*output, fmt.Sprintf("%s-%d.schema.json", kind, party))
+			if err := ioutil.WriteFile(path, append(raw, '\n'), 0644); err != nil {
+				log.Println(err)
+				return 1
+			}
+		}
+	}
+	return 0
+}
+func (c *schemaCommand) Synopsis() string {
+	return "Emits the JSON Schemas of the inputs and outputs of a circuit"
+}
+
+// partySchema returns the schema of the input or output of a party
+func partySchema(vars []*circ.Var, party int, kind string) map[string]interface{} {
+	t := typ.VoidType
+	if party < len(vars) && vars[party] != nil {
+		t = vars[party].Type
+	}
+	schema := t.Schema()
+	schema["$schema"] = typ.SchemaDraft
+	schema["title"] = fmt.Sprintf("%s of party %d", kind, party)
+	return schema
+}
+
+func (c *psiCommand) Help() string {
+	return `Writes the javascript program of a private set intersection of the sets of integers of several parties,
+or with -pad the input of a party from its set given as a JSON list.
+Usage: psi [-parties n] [-size m] [-width w] [-method pairwise|sort] [-cardinality] [-o file.js]
+       psi -pad [-size m] [-width w] [-o entry.json] set.json
+  -parties n    number of parties
+  -size m       public maximum number of elements of the sets
+  -width w      number of bits of the elements
+  -method       pairwise compares the elements of the party 0 to all the others,
+                sort sorts all the elements together, which is cheaper for large sets
+  -cardinality  output only the number of common elements instead of the intersection
+  -o file       write to a file instead of the standard output`
+}
+func (c *psiCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("psi", flag.ContinueOnError)
+	parties := flags.Int("parties", 2, "number of parties")
+	size := flags.Int("size", 16, "maximum size of the sets")
+	width := flags.Int("width", 32, "number of bits of the elements")
+	method := flags.String("method", "pairwise", "pairwise or sort")
+	cardinality := flags.Bool("cardinality", false, "output only the number of common elements")
+	pad := flags.Bool("pad", false, "write the input of a party")
+	output := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	m, err := psi.ParseMethod(*method)
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+	p := psi.Params{Parties: *parties, Size: *size, Width: *width, Method: m, Cardinality: *cardinality}
+
+	var out []byte
+	if *pad {
+		if flags.NArg() != 1 {
+			log.Println("You have to provide the JSON file of the set to pad")
+			return 1
+		}
+		raw, err := ioutil.ReadFile(flags.Arg(0))
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		var set []interface{}
+		if err := json.Unmarshal(raw, &set); err != nil {
+			log.Println("Error in the set file", flags.Arg(0), ":", err)
+			return 1
+		}
+		inp, err := p.Pad(set)
+		if err != nil {
+			log.Println("Error in the set file", flags.Arg(0), ":", err)
+			return 1
+		}
+		out, _ = json.Marshal(inp)
+		out = append(out, '\n')
+	} else {
+		src, err := psi.Program(p)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		out = []byte(src)
+	}
+	if *output == "" {
+		os.Stdout.Write(out)
+		return 0
+	}
+	if err := ioutil.WriteFile(*output, out, 0644); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+func (c *psiCommand) Synopsis() string {
+	return "Writes the program and the inputs of a private set intersection"
+}
+
+func (c *serveCommand) Help() string {
+	return `Garbles a circuit for an evaluator connecting to an address, and streams the tables to it
+as they are garbled, the evaluator evaluating them as they come. The inputs of all the parties
+are given to the garbler, which prints the outputs of the parties.
+Usage: serve [-workers n] address circuit.re input.json...
+  -workers n  number of goroutines garbling independent gates at the same time`
+}
+func (c *serveCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	workers := flags.Int("workers", 1, "number of workers")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() < 2 {
+		log.Println("You have to provide the address to listen to and the name of the circuit")
+		return 1
+	}
+	runner.ServeCircuit(flags.Arg(0), flags.Arg(1), flags.Args()[2:], *workers)
+	return 0
+}
+func (c *serveCommand) Synopsis() string {
+	return "Garbles a circuit for an evaluator, streaming the tables"
+}
+
+func (c *evaluateCommand) Help() string {
+	return `Connects to a garbler started with serve and evaluates the circuit as its tables come.
+Usage: evaluate [-window bytes] [-workers n] address circuit.re
+  -window bytes  number of bytes of tables the garbler may send in advance
+  -workers n     number of goroutines evaluating independent gates at the same time`
+}
+func (c *evaluateCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	window := flags.Int("window", transport.DefaultWindow, "number of bytes sent in advance")
+	workers := flags.Int("workers", 1, "number of workers")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if flags.NArg() != 2 {
+		log.Println("You have to provide the address of the garbler and the name of the circuit")
+		return 1
+	}
+	runner.EvaluateCircuit(flags.Arg(0), flags.Arg(1), *window, *workers)
+	return 0
+}
+func (c *evaluateCommand) Synopsis() string {
+	return "Evaluates a circuit streamed by a garbler"
+}
+
 func main() {
 	c := cli.NewCLI("rockengine", "0.0.1")
 	c.Args = os.Args[1:]
@@ -89,6 +506,33 @@ func main() {
 		"garble": func() (cli.Command, error) {
 			return &garbleCommand{}, nil
 		},
+		"profile": func() (cli.Command, error) {
+			return &profileCommand{}, nil
+		},
+		"dot": func() (cli.Command, error) {
+			return &dotCommand{}, nil
+		},
+		"optimize": func() (cli.Command, error) {
+			return &optimizeCommand{}, nil
+		},
+		"lib": func() (cli.Command, error) {
+			return &libCommand{}, nil
+		},
+		"check": func() (cli.Command, error) {
+			return &checkCommand{}, nil
+		},
+		"schema": func() (cli.Command, error) {
+			return &schemaCommand{}, nil
+		},
+		"psi": func() (cli.Command, error) {
+			return &psiCommand{}, nil
+		},
+		"serve": func() (cli.Command, error) {
+			return &serveCommand{}, nil
+		},
+		"evaluate": func() (cli.Command, error) {
+			return &evaluateCommand{}, nil
+		},
 	}
 
 	exitStatus, err := c.Run()
@@ -80,3 +80,22 @@ func BuildCircuit(fileName string) {
 		circuit.Print("")
 	}
 }
+
+// BuildLibrary compiles the functions of the given names from a JavaScript file
+// into a library of objects which can be linked into other circuits. The
+// library is saved next to the file, with the .lib extension.
+func BuildLibrary(fileName string, names []string) {
+	lib, err := compiler.ObjectsFromJS(fileName, names...)
+	if err != nil {
+		fmt.Println("Compilation error:")
+		fmt.Println(err)
+		return
+	}
+
+	outputFileName := strings.TrimSuffix(fileName, ".js") + ".lib"
+	lib.SaveToFile(outputFileName)
+	fmt.Println("Library saved to", outputFileName)
+	for _, o := range lib.Objects {
+		fmt.Println(o.Name, "- XORgates", o.Funcs[0].XORgates, "NonXORgates", o.Funcs[0].NonXORgates, "Wires", o.Wires)
+	}
+}
@@ -4,7 +4,12 @@ import (
 	"encoding/gob"
 	"fmt"
 	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+	ip "ixxoprivacy/pkg/interpreter"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -24,3 +29,92 @@ func TestFileReading(t *testing.T) {
 	}
 	file.Close()
 }
+
+// intInput encodes integers as the input of a party
+func intInput(size int, vals ...int64) *circ.UserInOut {
+	in := circ.NewUIO()
+	for _, v := range vals {
+		for i := 0; i < size; i++ {
+			in.Add(v&(1<<uint(i)) != 0)
+		}
+	}
+	return in
+}
+
+func TestLibrary(t *testing.T) {
+	fmt.Println("Starting TestLibrary")
+	lib, err := compiler.ObjectsFromJS("testdata/mathlib.js", "mulAdd", "absDiff", "square")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Objects) != 3 || len(lib.Find("square").Funcs) != 2 {
+		t.Fatal("wrong objects in library")
+	}
+	path := filepath.Join(t.TempDir(), "mathlib.lib")
+	lib.SaveToFile(path)
+
+	C1, _ := compiler.CircuitFromJS("testdata/mathlib.js")
+	compiler.AddLibrary(circ.RetrieveLibrary(path))
+	defer compiler.ClearLibraries()
+	C2, err := compiler.CircuitFromJS("testdata/linked.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(C2.Funcs) != 4 {
+		t.Errorf("linked circuit has %d functions instead of 4", len(C2.Funcs))
+	}
+
+	for _, in := range [][2]int64{{0, 0}, {5, 9}, {12, 3}, {-7, 100}, {250, -3}} {
+		inputs := []*circ.UserInOut{intInput(16, in[0]), intInput(16, in[1])}
+		out1 := ip.Interprete(C1, inputs)
+		out2 := ip.Interprete(C2, inputs)
+		for party := range out1 {
+			if !out1[party].Equals(out2[party]) {
+				t.Errorf("inputs %v: outputs of party %d differ", in, party)
+			}
+		}
+	}
+}
+
+// TestLinkErrors checks that the programs calling the functions of a library
+// with other integers or other types of arguments are not compiled
+func TestLinkErrors(t *testing.T) {
+	fmt.Println("Starting TestLinkErrors")
+	lib, err := compiler.ObjectsFromJS("testdata/mathlib.js", "mulAdd", "absDiff", "square")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler.AddLibrary(lib)
+	defer compiler.ClearLibraries()
+
+	header := `var $parties = 2
+var $intsize = %d
+var in_0 = 0
+var in_1 = 0
+var out_0 = 0
+`
+	for _, test := range []struct {
+		name    string
+		intsize int
+		body    string
+		want    string
+	}{
+		{"intsize", 32, "out_0 = square(in_0)", "compiled for a $intsize of 16 instead of 32"},
+		{"boolean argument", 16, "out_0 = square(in_0 < in_1)", "the argument 1 of a call to square is of type bool"},
+		{"array argument", 16, "var a = [in_0, in_1]\nout_0 = absDiff(in_0, a)", "the argument 2 of a call to absDiff is of type"},
+		{"in a function", 16, "out_0 = f(in_0)\nfunction f(x) {\n\treturn mulAdd(x, x, x == 0)\n}", "the argument 3 of a call to mulAdd"},
+		{"arguments missing", 16, "out_0 = mulAdd(in_0, in_1)", "mulAdd is called with 2 arguments but its object takes 3"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "program.js")
+			source := fmt.Sprintf(header, test.intsize) + test.body + "\n"
+			if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := compiler.CircuitFromJS(path)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("the compilation gives the error %v instead of %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+var $parties = 2
+var $intsize = 16
+
+var in_0 = 0
+var in_1 = 0
+var out_0 = 0
+var out_1 = 0
+
+out_0 = mulAdd(in_0, in_1, in_1) + absDiff(in_0, in_1)
+out_1 = square(absDiff(in_1, in_0 + in_1))
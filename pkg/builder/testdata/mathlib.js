@@ -0,0 +1,30 @@
+var $parties = 2
+var $intsize = 16
+
+var in_0 = 0
+var in_1 = 0
+var out_0 = 0
+var out_1 = 0
+
+out_0 = mulAdd(in_0, in_1, in_1) + absDiff(in_0, in_1)
+out_1 = square(absDiff(in_1, in_0 + in_1))
+
+/**********************************************/
+
+function mulAdd(a, b, c){
+	var r = a * b + c
+	return r
+}
+
+function absDiff(a, b){
+	var d = a - b
+	if (a < b){
+		d = b - a
+	}
+	return d
+}
+
+function square(x){
+	var s = mulAdd(x, x, x)
+	return s
+}
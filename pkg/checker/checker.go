@@ -0,0 +1,278 @@
+package checker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+	ip "ixxoprivacy/pkg/interpreter"
+	typ "ixxoprivacy/pkg/types"
+
+	"github.com/robertkrimen/otto"
+	"github.com/robertkrimen/otto/ast"
+)
+
+/*
+ * This package tests the compiler by differential testing: a program is both
+ * compiled into a circuit run by the interpreter and run as plain JavaScript
+ * by otto, and the outputs of the two are compared. The program run by otto
+ * is rewritten to compute on integers of $intsize bits wrapping around like
+ * the ones of the circuit, see rewrite.go.
+ *
+ * The integers of the JavaScript program being float numbers, only the
+ * programs whose $intsize is at most MaxIntSize can be checked.
+ */
+
+// MaxIntSize is the largest $intsize of the programs which can be checked
+const MaxIntSize = 53
+
+// ErrUndefined is returned when a program is run on inputs for which its
+// result is not specified, such as inputs leading to a division by zero
+var ErrUndefined = errors.New("the program divides by zero")
+
+// A Checker runs a program both as a circuit and as plain JavaScript
+type Checker struct {
+	Circuit circ.Circuit
+	prog    *ast.Program
+}
+
+// A Mismatch is an output of a circuit which differs from the output of the program
+type Mismatch struct {
+	Inputs   []interface{} // inputs of all the parties
+	Party    int
+	Expected interface{} // output of the program run by otto
+	Got      interface{} // output of the circuit
+}
+
+func (m Mismatch) String() string {
+	inputs, _ := json.Marshal(m.Inputs)
+	expected, _ := json.Marshal(m.Expected)
+	got, _ := json.Marshal(m.Got)
+	return fmt.Sprintf("inputs %s: out_%d is %s instead of %s", inputs, m.Party, got, expected)
+}
+
+// NewChecker compiles a program and prepares it to be run by otto
+func NewChecker(fileName string) (*Checker, error) {
+	C, err := compiler.CircuitFromJS(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if C.IntSize > MaxIntSize {
+		return nil, fmt.Errorf("$intsize %d is above %d", C.IntSize, MaxIntSize)
+	}
+	// the program is parsed again, the compiler changing the tree it compiles
+	prog, err := compiler.ParseProgram(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := rewrite(prog); err != nil {
+		return nil, err
+	}
+	return &Checker{Circuit: C, prog: prog}, nil
+}
+
+// Run runs the program with otto on the inputs of all the parties, given as
+// values decoded from JSON, and returns the outputs of all the parties.
+func (ch *Checker) Run(inputs []interface{}) ([]interface{}, error) {
+	vm := otto.New()
+	h := &helpers{size: uint(ch.Circuit.IntSize)}
+	h.define(vm)
+	for party, in := range inputs {
+		if err := vm.Set("$in_"+strconv.Itoa(party), in); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := vm.Run(ch.prog); err != nil {
+		return nil, err
+	}
+	if h.undefined {
+		return nil, ErrUndefined
+	}
+
+	outputs := make([]interface{}, ch.Circuit.Parties)
+	for party := range outputs {
+		// the outputs are read back through JSON to get plain Go values
+		s, err := vm.Run("JSON.stringify(out_" + strconv.Itoa(party) + ")")
+		if err != nil || !s.IsString() {
+			continue
+		}
+		if err := json.Unmarshal([]byte(s.String()), &outputs[party]); err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+// Encode returns the inputs of all the parties as given to the interpreter
+func (ch *Checker) Encode(inputs []interface{}) ([]*circ.UserInOut, error) {
+	uios := make([]*circ.UserInOut, len(inputs))
+	for party, in := range inputs {
+		uios[party] = circ.NewUIO()
+		if t := ch.Circuit.Inputs[party].Type; t.BaseType != typ.VOID {
+			if err := encode(in, t, uios[party]); err != nil {
+				return nil, fmt.Errorf("in_%d: %v", party, err)
+			}
+		}
+	}
+	return uios, nil
+}
+
+// Check runs the program on inputs both as a circuit and with otto, and
+// returns the outputs which differ.
+func (ch *Checker) Check(inputs []interface{}) ([]Mismatch, error) {
+	uios, err := ch.Encode(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return ch.compare(inputs, ip.Interprete(ch.Circuit, uios))
+}
+
+// compare runs the program with otto and compares its outputs to the ones of the circuit
+func (ch *Checker) compare(inputs []interface{}, outputs []*circ.UserInOut) ([]Mismatch, error) {
+	expected, err := ch.Run(inputs)
+	if err != nil {
+		return nil, err
+	}
+	mismatches := make([]Mismatch, 0)
+	for party, out := range ch.Circuit.Outputs {
+		if out == nil || out.Type.BaseType == typ.VOID {
+			continue
+		}
+		want := circ.NewUIO()
+		if err := encode(expected[party], out.Type, want); err != nil {
+			return nil, fmt.Errorf("out_%d: %v", party, err)
+		}
+		// the outputs are compared as bits, which wraps the output of otto around
+		if outputs[party] == nil || !want.Equals(outputs[party]) {
+			m := Mismatch{Inputs: inputs, Party: party, Expected: ip.GetGoValue(want, out.Type)}
+			if outputs[party] != nil {
+				m.Got = ip.GetGoValue(outputs[party], out.Type)
+			}
+			mismatches = append(mismatches, m)
+		}
+	}
+	return mismatches, nil
+}
+
+// Fuzz checks the program on a number of random inputs, and returns the
+// outputs which differ along with the number of runs whose result is not
+// specified.
+func (ch *Checker) Fuzz(runs int, r *rand.Rand) (mismatches []Mismatch, undefined int, err error) {
+	batch := make([][]*circ.UserInOut, runs)
+	values := make([][]interface{}, runs)
+	for i := range batch {
+		values[i] = make([]interface{}, ch.Circuit.Parties)
+		for party, in := range ch.Circuit.Inputs {
+			if in.Type.BaseType != typ.VOID {
+				values[i][party] = RandomValue(in.Type, r)
+			}
+		}
+		if batch[i], err = ch.Encode(values[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	outputs := ip.InterpreteBatch(ch.Circuit, batch)
+	for i := range batch {
+		ms, err := ch.compare(values[i], outputs[i])
+		if err == ErrUndefined {
+			undefined++
+			continue
+		}
+		if err != nil {
+			return mismatches, undefined, err
+		}
+		mismatches = append(mismatches, ms...)
+	}
+	return mismatches, undefined, nil
+}
+
+// RandomValue returns a random value of a type, as it would be decoded from JSON
+func RandomValue(t *typ.Type, r *rand.Rand) interface{} {
+	switch t.BaseType {
+	case typ.BOOL:
+		return r.Intn(2) == 1
+	case typ.INT:
+		return float64(r.Int63n(1<<t.L) - 1<<(t.L-1))
+	case typ.UINT:
+		return float64(r.Int63n(1 << t.L))
+	case typ.ARRAY:
+		a := make([]interface{}, t.L)
+		for i := range a {
+			a[i] = RandomValue(t.SubType, r)
+		}
+		return a
+	case typ.OBJECT:
+		o := make(map[string]interface{})
+		for i, st := range t.List {
+			o[t.Keys[i]] = RandomValue(st, r)
+		}
+		return o
+	}
+	return nil
+}
+
+// encode appends the bits of a value decoded from JSON to a buffer, the
+// integers being wrapped around to the size of their type
+func encode(v interface{}, t *typ.Type, uio *circ.UserInOut) error {
+	switch t.BaseType {
+	case typ.BOOL:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%v is no boolean", v)
+		}
+		uio.Add(b)
+	case typ.INT, typ.UINT:
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%v is no number", v)
+		}
+		x := int64(f)
+		for i := typ.Num(0); i < t.L; i++ {
+			uio.Add(x>>i&1 == 1)
+		}
+	case typ.ARRAY:
+		a, ok := v.([]interface{})
+		if !ok || len(a) != int(t.L) {
+			return fmt.Errorf("%v is no array of length %d", v, t.L)
+		}
+		for _, x := range a {
+			if err := encode(x, t.SubType, uio); err != nil {
+				return err
+			}
+		}
+	case typ.OBJECT:
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v is no object", v)
+		}
+		for i, st := range t.List {
+			if err := encode(o[t.Keys[i]], st, uio); err != nil {
+				return fmt.Errorf("%s: %v", t.Keys[i], err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type")
+	}
+	return nil
+}
+
+// ReadInputs reads the inputs of all the parties from JSON files
+func ReadInputs(fileNames []string) ([]interface{}, error) {
+	inputs := make([]interface{}, len(fileNames))
+	for party, fileName := range fileNames {
+		raw, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &inputs[party]); err != nil {
+			return nil, fmt.Errorf("%s: %v", fileName, err)
+		}
+	}
+	return inputs, nil
+}
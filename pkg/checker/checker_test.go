@@ -0,0 +1,171 @@
+package checker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ixxoprivacy/pkg/compiler"
+)
+
+// excluded are the test programs which are not fuzzed, with the reason why
+var excluded = map[string]string{
+	// the built-in functions of the standard library, such as AES128, are
+	// circuits which have no JavaScript implementation for otto to run
+	"test17_crypto": "calls the standard library",
+	// a run takes seconds, the matrices being of 64x64 integers
+	"test7_matrix64": "too long to run",
+}
+
+// testProgram matches the file names of the test programs, as in the golden
+// tests of the engine package
+var testProgram = regexp.MustCompile(`^test\d+(_\w+)?\.js$`)
+
+// TestFuzz checks every test program whose integers otto can compute on
+func TestFuzz(t *testing.T) {
+	fmt.Println("Starting TestFuzz")
+	files, err := ioutil.ReadDir("../../Tests/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, f := range files {
+		if !testProgram.MatchString(f.Name()) {
+			continue
+		}
+		name := strings.TrimSuffix(f.Name(), ".js")
+		t.Run(name, func(t *testing.T) {
+			if reason, ok := excluded[name]; ok {
+				fmt.Println("Skipping", name+":", reason)
+				t.Skip(reason)
+			}
+			path := "../../Tests/" + f.Name()
+			C, err := compiler.CircuitFromJS(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if C.IntSize > MaxIntSize {
+				reason := fmt.Sprintf("$intsize %d is above %d", C.IntSize, MaxIntSize)
+				fmt.Println("Skipping", name+":", reason)
+				t.Skip(reason)
+			}
+			// the largest circuits are run fewer times
+			runs := 200
+			if C.NonXORgates > 1000000 {
+				runs = 20
+			}
+			ch, err := NewChecker(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mismatches, undefined, err := ch.Fuzz(runs, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if undefined == runs {
+				t.Error("no run could be checked")
+			}
+			for _, m := range mismatches {
+				t.Error(m)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fmt.Println("Starting TestCheck")
+	ch, err := NewChecker("../../Tests/test2.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make([]string, ch.Circuit.Parties)
+	for party := range files {
+		files[party] = "../../Tests/entry2-" + strconv.Itoa(party) + ".json"
+	}
+	inputs, err := ReadInputs(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the outputs of the program run by otto wrap around like the ones of the circuit
+	outputs, err := ch.Run([]interface{}{float64(100), float64(100), float64(100), float64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outputs[2] != float64(22) {
+		t.Errorf("out_2 is %v instead of 22", outputs[2])
+	}
+	mismatches, err := ch.Check(inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mismatches {
+		t.Error(m)
+	}
+
+	ch, err = NewChecker("../../Tests/test0.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ch.Check([]interface{}{float64(0), float64(3)}); err != ErrUndefined {
+		t.Errorf("division by zero gave %v instead of ErrUndefined", err)
+	}
+}
+
+// TestShiftSemantics checks the shifts where the programs intentionally
+// differ from JavaScript, whose shifts are on 32 bits and take the amount
+// modulo 32: on integers of 16 bits, an amount out of range fills the whole
+// integer and >>> stays within 16 bits.
+func TestShiftSemantics(t *testing.T) {
+	fmt.Println("Starting TestShiftSemantics")
+	path := filepath.Join(t.TempDir(), "shifts.js")
+	source := `var $parties = 2
+var $intsize = 16
+var in_0 = 0
+var in_1 = 0
+var out_0 = {left: 0, signed: 0, unsigned: 0, rotated: 0}
+out_0.left = in_0 << in_1
+out_0.signed = in_0 >> in_1
+out_0.unsigned = in_0 >>> in_1
+out_0.rotated = RotateLeft(in_0, in_1)
+`
+	if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := NewChecker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		amount float64
+		want   map[string]interface{} // JavaScript gives -10, -3, 2147483645 and -9 for 33
+	}{
+		{1, map[string]interface{}{"left": -10, "signed": -3, "unsigned": 32765, "rotated": -9}},
+		{16, map[string]interface{}{"left": 0, "signed": -1, "unsigned": 0, "rotated": -5}},
+		{33, map[string]interface{}{"left": 0, "signed": -1, "unsigned": 0, "rotated": -9}},
+		{-1, map[string]interface{}{"left": 0, "signed": -1, "unsigned": 0, "rotated": -3}},
+	} {
+		inputs := []interface{}{float64(-5), c.amount}
+		outputs, err := ch.Run(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := outputs[0].(map[string]interface{})
+		for key, want := range c.want {
+			if fmt.Sprint(got[key]) != fmt.Sprint(want) {
+				t.Errorf("%s of -5 by %v is %v instead of %v", key, c.amount, got[key], want)
+			}
+		}
+		mismatches, err := ch.Check(inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range mismatches {
+			t.Error(m)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package checker
+
+import (
+	"ixxoprivacy/pkg/compiler"
+
+	"github.com/robertkrimen/otto"
+)
+
+// sortingHelpers run the sorting networks of the circuits, so that the items
+// with equal keys end up in the same order as in the circuits
+const sortingHelpers = `
+function $cmpx(a, i, j, key) {
+	var x = key === undefined ? a[i] : a[i][key]
+	var y = key === undefined ? a[j] : a[j][key]
+	if (x > y) {
+		var t = a[i]
+		a[i] = a[j]
+		a[j] = t
+	}
+}
+function $sort(a, key) {
+	var b = a.slice()
+	var net = $network(b.length)
+	for (var k = 0; k < net.length; k++) {
+		$cmpx(b, net[k][0], net[k][1], key)
+	}
+	return b
+}`
+
+// helpers computes the operations of a program on integers of a given size,
+// as the circuit compiled from the program does
+type helpers struct {
+	size      uint
+	undefined bool // a division by zero happened, whose result is not specified
+}
+
+// wrap wraps an integer around to a signed integer of the size of the helpers
+func (h *helpers) wrap(x int64) int64 {
+	x &= 1<<h.size - 1
+	if x >= 1<<(h.size-1) {
+		x -= 1 << h.size
+	}
+	return x
+}
+
+// bits returns the bits of a wrapped integer as an unsigned integer
+func (h *helpers) bits(x int64) int64 {
+	return x & (1<<h.size - 1)
+}
+
+// define sets the helpers as functions of the otto runtime
+func (h *helpers) define(vm *otto.Otto) {
+	arg := func(call otto.FunctionCall, i int) int64 {
+		x, _ := call.Argument(i).ToInteger()
+		return h.wrap(x)
+	}
+	result := func(x int64) otto.Value {
+		v, _ := otto.ToValue(h.wrap(x))
+		return v
+	}
+	// binary defines a helper on two integers, or two booleans for the bitwise operators
+	binary := func(name string, op func(a, b int64) int64) {
+		vm.Set(name, func(call otto.FunctionCall) otto.Value {
+			if call.Argument(0).IsBoolean() {
+				a, _ := call.Argument(0).ToBoolean()
+				b, _ := call.Argument(1).ToBoolean()
+				r := op(conv(a), conv(b)) & 1
+				v, _ := otto.ToValue(r == 1)
+				return v
+			}
+			return result(op(arg(call, 0), arg(call, 1)))
+		})
+	}
+
+	vm.Set("$wrap", func(call otto.FunctionCall) otto.Value {
+		v := call.Argument(0)
+		if !v.IsNumber() {
+			return v
+		}
+		return result(arg(call, 0))
+	})
+	vm.Set("$quo", func(call otto.FunctionCall) otto.Value {
+		a, _ := call.Argument(0).ToInteger()
+		b, _ := call.Argument(1).ToInteger()
+		if b == 0 {
+			h.undefined = true
+			return otto.UndefinedValue()
+		}
+		v, _ := otto.ToValue(a / b)
+		return v
+	})
+	vm.Set("$neg", func(call otto.FunctionCall) otto.Value {
+		return result(-arg(call, 0))
+	})
+
+	binary("$add", func(a, b int64) int64 { return a + b })
+	binary("$sub", func(a, b int64) int64 { return a - b })
+	binary("$mul", func(a, b int64) int64 { return a * b })
+	binary("$and", func(a, b int64) int64 { return a & b })
+	binary("$or", func(a, b int64) int64 { return a | b })
+	binary("$xor", func(a, b int64) int64 { return a ^ b })
+	binary("$div", func(a, b int64) int64 {
+		if b == 0 {
+			h.undefined = true
+			return 0
+		}
+		return a / b
+	})
+	binary("$mod", func(a, b int64) int64 {
+		if b == 0 {
+			h.undefined = true
+			return 0
+		}
+		return a % b
+	})
+	// the shifts fill the integer with zeros, but for the right shift which
+	// fills it with its sign, and entirely when the amount is out of range
+	binary("$shl", func(a, b int64) int64 {
+		if b < 0 || b >= int64(h.size) {
+			return 0
+		}
+		return a << uint(b)
+	})
+	binary("$shr", func(a, b int64) int64 {
+		if b < 0 || b >= int64(h.size) {
+			return a >> 63
+		}
+		return a >> uint(b)
+	})
+	binary("$ushr", func(a, b int64) int64 {
+		if b < 0 || b >= int64(h.size) {
+			return 0
+		}
+		return h.bits(a) >> uint(b)
+	})
+	vm.Set("$not", func(call otto.FunctionCall) otto.Value {
+		return result(^arg(call, 0))
+	})
+
+	vm.Set("$network", func(call otto.FunctionCall) otto.Value {
+		n, _ := call.Argument(0).ToInteger()
+		net := make([]interface{}, 0)
+		for _, c := range compiler.SortingNetwork(int(n)) {
+			net = append(net, []interface{}{c[0], c[1]})
+		}
+		v, _ := vm.ToValue(net)
+		return v
+	})
+	vm.Run(sortingHelpers)
+
+	vm.Set("$rotl", func(call otto.FunctionCall) otto.Value {
+		a := h.bits(arg(call, 0))
+		b := h.bits(arg(call, 1)) % int64(h.size)
+		return result(a<<uint(b) | a>>(h.size-uint(b)))
+	})
+	vm.Set("$getwire", func(call otto.FunctionCall) otto.Value {
+		a := h.bits(arg(call, 0))
+		i, _ := call.Argument(1).ToInteger()
+		v, _ := otto.ToValue(a>>uint(i)&1 == 1)
+		return v
+	})
+	vm.Set("$setwire", func(call otto.FunctionCall) otto.Value {
+		a := arg(call, 0)
+		i, _ := call.Argument(1).ToInteger()
+		b, _ := call.Argument(2).ToBoolean()
+		a &^= 1 << uint(i)
+		if b {
+			a |= 1 << uint(i)
+		}
+		return result(a)
+	})
+}
+
+func conv(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
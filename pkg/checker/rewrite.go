@@ -0,0 +1,295 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	tk "github.com/robertkrimen/otto/token"
+)
+
+/*
+ * The program given to otto is rewritten so that it computes on integers of
+ * $intsize bits like the circuit does, instead of on the float numbers of
+ * JavaScript. Every operator on secret values is replaced by a call to one of
+ * the helpers of helpers.go, which wraps its result around like the gates of
+ * the circuit. The expressions only made of literals and dollar variables are
+ * public values computed by the compiler, and are left as they are apart from
+ * the division which is an integer division.
+ */
+
+// binaryHelpers are the helpers replacing the arithmetic and bitwise operators
+var binaryHelpers = map[tk.Token]string{
+	tk.PLUS:                 "$add",
+	tk.MINUS:                "$sub",
+	tk.MULTIPLY:             "$mul",
+	tk.SLASH:                "$div",
+	tk.REMAINDER:            "$mod",
+	tk.SHIFT_LEFT:           "$shl",
+	tk.SHIFT_RIGHT:          "$shr",
+	tk.UNSIGNED_SHIFT_RIGHT: "$ushr",
+	tk.AND:                  "$and",
+	tk.OR:                   "$or",
+	tk.EXCLUSIVE_OR:         "$xor",
+}
+
+// reservedHelpers are the helpers replacing the functions reserved by the compiler
+var reservedHelpers = map[string]string{
+	"RotateLeft": "$rotl",
+	"GetWire":    "$getwire",
+	"SetWire":    "$setwire",
+
+	"Sort":              "$sort",
+	"SortBy":            "$sort",
+	"CompareExchange":   "$cmpx",
+	"CompareExchangeBy": "$cmpx",
+}
+
+// inputName returns the name of the variable holding the injected input of a
+// party when name is the input variable of this party
+func inputName(name string) (string, bool) {
+	if !strings.HasPrefix(name, "in_") {
+		return "", false
+	}
+	return "$" + name, true
+}
+
+// isPublic tells whether a variable is a dollar variable known at compile time
+func isPublic(name string) bool {
+	return strings.HasPrefix(name, "$")
+}
+
+// call returns a call to a helper
+func call(helper string, args ...ast.Expression) ast.Expression {
+	return &ast.CallExpression{Callee: &ast.Identifier{Name: helper}, ArgumentList: args}
+}
+
+// A rewriter rewrites the statements and expressions of a program in place
+type rewriter struct {
+	done map[*ast.FunctionLiteral]bool
+	err  error
+}
+
+// rewrite rewrites a program to be run by otto, the initializers of the input
+// variables being replaced by the inputs injected.
+func rewrite(prog *ast.Program) error {
+	rw := &rewriter{done: make(map[*ast.FunctionLiteral]bool)}
+	for _, s := range prog.Body {
+		if vs, ok := s.(*ast.VariableStatement); ok {
+			for _, e := range vs.List {
+				if ve, ok := e.(*ast.VariableExpression); ok {
+					if in, ok := inputName(ve.Name); ok {
+						ve.Initializer = call("$wrap", &ast.Identifier{Name: in})
+					}
+				}
+			}
+		}
+	}
+	for i, s := range prog.Body {
+		prog.Body[i] = rw.stmt(s)
+	}
+	for _, d := range prog.DeclarationList {
+		if fd, ok := d.(*ast.FunctionDeclaration); ok {
+			rw.function(fd.Function)
+		}
+	}
+	return rw.err
+}
+
+func (rw *rewriter) fail(format string, a ...interface{}) {
+	if rw.err == nil {
+		rw.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (rw *rewriter) function(f *ast.FunctionLiteral) {
+	if rw.done[f] {
+		return
+	}
+	rw.done[f] = true
+	f.Body = rw.stmt(f.Body)
+}
+
+func (rw *rewriter) stmt(s ast.Statement) ast.Statement {
+	switch s := s.(type) {
+	case nil:
+	case *ast.BlockStatement:
+		for i, s2 := range s.List {
+			s.List[i] = rw.stmt(s2)
+		}
+	case *ast.ExpressionStatement:
+		s.Expression, _ = rw.expr(s.Expression)
+	case *ast.VariableStatement:
+		for i, e := range s.List {
+			s.List[i], _ = rw.expr(e)
+		}
+	case *ast.IfStatement:
+		s.Test, _ = rw.expr(s.Test)
+		s.Consequent = rw.stmt(s.Consequent)
+		s.Alternate = rw.stmt(s.Alternate)
+	case *ast.ForStatement:
+		s.Initializer, _ = rw.expr(s.Initializer)
+		s.Test, _ = rw.expr(s.Test)
+		s.Update, _ = rw.expr(s.Update)
+		s.Body = rw.stmt(s.Body)
+	case *ast.WhileStatement:
+		s.Test, _ = rw.expr(s.Test)
+		s.Body = rw.stmt(s.Body)
+	case *ast.DoWhileStatement:
+		s.Test, _ = rw.expr(s.Test)
+		s.Body = rw.stmt(s.Body)
+	case *ast.ReturnStatement:
+		s.Argument = rw.wrapped(s.Argument)
+	case *ast.FunctionStatement:
+		rw.function(s.Function)
+	case *ast.EmptyStatement, *ast.BranchStatement:
+	default:
+		rw.fail("unsupported statement %T", s)
+	}
+	return s
+}
+
+// wrapped rewrites an expression whose value is wrapped around when public
+func (rw *rewriter) wrapped(e ast.Expression) ast.Expression {
+	e, public := rw.expr(e)
+	if public {
+		return call("$wrap", e)
+	}
+	return e
+}
+
+// expr rewrites an expression and tells whether its value is public
+func (rw *rewriter) expr(e ast.Expression) (ast.Expression, bool) {
+	switch e := e.(type) {
+	case nil:
+		return nil, true
+
+	case *ast.NumberLiteral, *ast.BooleanLiteral:
+		return e, true
+
+	case *ast.StringLiteral:
+		// the keys given to the sorting functions
+		return e, true
+
+	case *ast.Identifier:
+		return e, isPublic(e.Name)
+
+	case *ast.VariableExpression:
+		if isPublic(e.Name) {
+			e.Initializer, _ = rw.expr(e.Initializer)
+		} else if e.Initializer != nil {
+			e.Initializer = rw.wrapped(e.Initializer)
+		}
+		return e, false
+
+	case *ast.ArrayLiteral:
+		for i, v := range e.Value {
+			e.Value[i] = rw.wrapped(v)
+		}
+		return e, false
+
+	case *ast.ObjectLiteral:
+		for i, p := range e.Value {
+			e.Value[i].Value = rw.wrapped(p.Value)
+		}
+		return e, false
+
+	case *ast.BracketExpression:
+		e.Left, _ = rw.expr(e.Left)
+		e.Member, _ = rw.expr(e.Member)
+		return e, false
+
+	case *ast.DotExpression:
+		e.Left, _ = rw.expr(e.Left)
+		return e, false
+
+	case *ast.AssignExpression:
+		if e.Operator != tk.ASSIGN {
+			rw.fail("unsupported assignment operator %s", e.Operator)
+		}
+		left, public := rw.expr(e.Left)
+		e.Left = left
+		if public {
+			e.Right, _ = rw.expr(e.Right)
+		} else {
+			e.Right = rw.wrapped(e.Right)
+		}
+		return e, public
+
+	case *ast.SequenceExpression:
+		for i, e2 := range e.Sequence {
+			e.Sequence[i], _ = rw.expr(e2)
+		}
+		return e, false
+
+	case *ast.BinaryExpression:
+		left, lpub := rw.expr(e.Left)
+		right, rpub := rw.expr(e.Right)
+		e.Left, e.Right = left, right
+		if e.Comparison || e.Operator == tk.LOGICAL_AND || e.Operator == tk.LOGICAL_OR {
+			// the public side of a comparison is turned into a secret value
+			if lpub && !rpub {
+				e.Left = call("$wrap", left)
+			} else if rpub && !lpub {
+				e.Right = call("$wrap", right)
+			}
+			return e, lpub && rpub
+		}
+		helper, ok := binaryHelpers[e.Operator]
+		if !ok {
+			rw.fail("unsupported operator %s", e.Operator)
+			return e, false
+		}
+		if lpub && rpub {
+			if e.Operator == tk.SLASH {
+				return call("$quo", left, right), true
+			}
+			return e, true
+		}
+		return call(helper, left, right), false
+
+	case *ast.UnaryExpression:
+		operand, public := rw.expr(e.Operand)
+		e.Operand = operand
+		switch {
+		case public || e.Operator == tk.NOT:
+			return e, public
+		case e.Operator == tk.MINUS:
+			return call("$neg", operand), false
+		case e.Operator == tk.BITWISE_NOT:
+			return call("$not", operand), false
+		case e.Operator == tk.INCREMENT:
+			return &ast.AssignExpression{Operator: tk.ASSIGN, Left: operand, Right: call("$add", operand, &ast.NumberLiteral{Literal: "1", Value: int64(1)})}, false
+		case e.Operator == tk.DECREMENT:
+			return &ast.AssignExpression{Operator: tk.ASSIGN, Left: operand, Right: call("$sub", operand, &ast.NumberLiteral{Literal: "1", Value: int64(1)})}, false
+		}
+		rw.fail("unsupported operator %s", e.Operator)
+		return e, false
+
+	case *ast.CallExpression:
+		for i, a := range e.ArgumentList {
+			e.ArgumentList[i], _ = rw.expr(a)
+		}
+		id, ok := e.Callee.(*ast.Identifier)
+		if !ok {
+			rw.fail("unsupported call of %T", e.Callee)
+			return e, false
+		}
+		helper, ok := reservedHelpers[id.Name]
+		if !ok {
+			return e, false
+		}
+		e.Callee = &ast.Identifier{Name: helper}
+		if id.Name == "SetWire" {
+			// the bit of the variable is set by assigning it a new value
+			return &ast.AssignExpression{Operator: tk.ASSIGN, Left: e.ArgumentList[0], Right: e}, false
+		}
+		return e, false
+
+	case *ast.FunctionLiteral:
+		rw.function(e)
+		return e, false
+	}
+	rw.fail("unsupported expression %T", e)
+	return e, false
+}
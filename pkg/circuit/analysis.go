@@ -0,0 +1,96 @@
+package circuit
+
+import (
+	typ "ixxoprivacy/pkg/types"
+)
+
+// Reads calls fn on every wire read by a command which is not a function call,
+// in the order in which they are read.
+func (c Command) Reads(fn func(w typ.Num)) {
+	switch c.Kind {
+	case COPY, OUTPUT, REPLICATE:
+		fn(c.X)
+	case MASS_COPY, MASS_OUTPUT:
+		for i := typ.Num(0); i < c.Y; i++ {
+			fn(c.X + i)
+		}
+	default:
+		if c.IsGate() {
+			fn(c.X)
+			fn(c.Y)
+		}
+	}
+}
+
+// Writes calls fn on every wire written by a command which is not a function call,
+// in the order in which they are written.
+func (c Command) Writes(fn func(w typ.Num)) {
+	switch c.Kind {
+	case COPY, INPUT:
+		fn(c.To)
+	case MASS_COPY, MASS_INPUT, REPLICATE:
+		for i := typ.Num(0); i < c.Y; i++ {
+			fn(c.To + i)
+		}
+	default:
+		if c.IsGate() {
+			fn(c.To)
+		}
+	}
+}
+
+// A Usage summarises the effect of a call to a function on the wires of the
+// circuit: Reads contains the wires whose value is read before being written
+// by the function, Writes the wires it writes.
+type Usage struct {
+	Reads  map[typ.Num]bool
+	Writes map[typ.Num]bool
+}
+
+// Usages computes the usage of every function of the circuit, with the same
+// indexes as C.Funcs.
+func (C Circuit) Usages() []Usage {
+	usages := make([]Usage, len(C.Funcs))
+	done := make([]bool, len(C.Funcs))
+	var usageOf func(i typ.Num) Usage
+	usageOf = func(i typ.Num) Usage {
+		if !done[i] {
+			usages[i] = C.Funcs[i].usage(usageOf)
+			done[i] = true
+		}
+		return usages[i]
+	}
+	for i := range C.Funcs {
+		usageOf(typ.Num(i))
+	}
+	return usages
+}
+
+// usage computes the usage of a function given a way to obtain the usage of the
+// functions it calls.
+func (f Function) usage(usageOf func(i typ.Num) Usage) Usage {
+	u := Usage{make(map[typ.Num]bool), make(map[typ.Num]bool)}
+	read := func(w typ.Num) {
+		if !u.Writes[w] {
+			u.Reads[w] = true
+		}
+	}
+	write := func(w typ.Num) {
+		u.Writes[w] = true
+	}
+	for _, com := range f.Commands {
+		if com.Kind == FUNCTION_CALL {
+			called := usageOf(com.X)
+			for w := range called.Reads {
+				read(w)
+			}
+			for w := range called.Writes {
+				write(w)
+			}
+		} else {
+			com.Reads(read)
+			com.Writes(write)
+		}
+	}
+	return u
+}
@@ -0,0 +1,32 @@
+package circuit
+
+import (
+	"fmt"
+	"testing"
+
+	typ "ixxoprivacy/pkg/types"
+)
+
+func TestUsages(t *testing.T) {
+	fmt.Println("Starting TestUsages")
+	C := NewCircuit(8, 1)
+	// f0 reads 1 and 2 and writes 3, f1 writes 1, calls f0 and reads 3 and 4
+	f0 := NewFunctionPt()
+	f0.PushNonFunctionCall(Command{GATE_8, 1, 2, 3})
+	f1 := NewFunctionPt()
+	f1.PushNonFunctionCall(Command{COPY, 5, 0, 1})
+	f1.PushFunctionCall(Command{FUNCTION_CALL, 0, 0, 0}, f0.XORgates, f0.NonXORgates)
+	f1.PushNonFunctionCall(Command{MASS_COPY, 3, 2, 7})
+	C.Funcs = append(C.Funcs, f0, f1)
+
+	usages := C.Usages()
+	want := []Usage{
+		{Reads: map[typ.Num]bool{1: true, 2: true}, Writes: map[typ.Num]bool{3: true}},
+		{Reads: map[typ.Num]bool{5: true, 2: true, 4: true}, Writes: map[typ.Num]bool{1: true, 3: true, 7: true, 8: true}},
+	}
+	for i, u := range usages {
+		if fmt.Sprint(u.Reads) != fmt.Sprint(want[i].Reads) || fmt.Sprint(u.Writes) != fmt.Sprint(want[i].Writes) {
+			t.Errorf("f%d reads %v and writes %v instead of %v and %v", i, u.Reads, u.Writes, want[i].Reads, want[i].Writes)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	typ "ixxoprivacy/pkg/types"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -22,7 +23,7 @@ var dk1 DecodingKey = [2]bool{true, true}
 var dk2 DecodingKey = [2]bool{false, true}
 var dk3 DecodingKey = [2]bool{true, false}
 
-var v1 Var = Var{typ.NewBoolType(), 0}
+var v1 Var = Var{typ.BoolType, 0}
 var v2 Var = Var{typ.NewIntType(8), 1}
 
 func mTestGarbledValue(t *testing.T) {
@@ -41,8 +42,8 @@ func mTestGarbledValue(t *testing.T) {
 func mTestEandD(t *testing.T) {
 	fmt.Println("\nStarting TestEandD")
 	r := k3
-	enc := NewEncodingSet(r)
-	dec := NewDecodingSet()
+	enc := NewEncodingSet(r, 2)
+	dec := NewDecodingSet(2)
 
 	enc.User[0] = append(enc.User[0], gv1)
 	enc.User[1] = append(enc.User[1], gv2)
@@ -114,7 +115,7 @@ func TestFile(t *testing.T) {
 	C.Funcs = append(C.Funcs, f)
 	C.Print("")
 
-	path := "testFile"
+	path := filepath.Join(t.TempDir(), "testFile")
 	C.SaveToFile(path)
 	Cbis := RetrieveCircuit(path)
 	Cbis.Print("")
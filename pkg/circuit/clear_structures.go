@@ -149,18 +149,78 @@ func (f *Function) PushFunctionCall(com Command, xor, nxor uint32) {
 }
 
 // Visit goes through all commands starting from a given function and transmits those
-// commands through a channel.
+// commands through a channel. A Cursor gives the same commands without the cost of
+// the synchronization of a channel for every command.
 func (f Function) Visit(chcom chan<- Command, funcs []*Function) {
-	for _, com := range f.Commands {
+	cur := f.Cursor(funcs)
+	for cur.Next() {
+		chcom <- cur.Command()
+	}
+}
+
+// A Cursor goes through the commands of a function in the order in which they are
+// executed, entering the functions called as many times as they are called. It keeps
+// the stack of the function calls being executed so that no memory is allocated
+// once the deepest call has been reached.
+type Cursor struct {
+	funcs []*Function
+	stack []frame
+	com   *Command
+}
+
+// A frame is the state of the execution of a function called
+type frame struct {
+	commands []Command
+	next     int    // index of the next command to execute
+	left     uint32 // number of executions of the function still to do after the current one
+}
+
+// Cursor returns a cursor placed before the first command of a function
+func (f *Function) Cursor(funcs []*Function) *Cursor {
+	cur := &Cursor{funcs: funcs, stack: make([]frame, 1, 8)}
+	cur.stack[0] = frame{commands: f.Commands}
+	return cur
+}
+
+// Cursor returns a cursor placed before the first command of a circuit
+func (C *Circuit) Cursor() *Cursor {
+	return C.Function.Cursor(C.Funcs)
+}
+
+// Next moves the cursor to the next command which is not a function call.
+// It returns false when all the commands have been gone through.
+func (cur *Cursor) Next() bool {
+	for len(cur.stack) > 0 {
+		top := &cur.stack[len(cur.stack)-1]
+		if top.next == len(top.commands) {
+			if top.left > 0 {
+				top.left--
+				top.next = 0
+			} else {
+				cur.stack = cur.stack[:len(cur.stack)-1]
+			}
+			continue
+		}
+		com := &top.commands[top.next]
+		top.next++
 		if com.Kind == FUNCTION_CALL {
-			funcs[com.X].Visit(chcom, funcs)
-			for i := uint32(1); i < uint32(com.Y); i++ {
-				funcs[com.X].Visit(chcom, funcs)
+			left := uint32(0)
+			if com.Y > 1 {
+				left = uint32(com.Y) - 1
 			}
-		} else {
-			chcom <- com
+			cur.stack = append(cur.stack, frame{commands: cur.funcs[com.X].Commands, left: left})
+			continue
 		}
+		cur.com = com
+		return true
 	}
+	cur.com = nil
+	return false
+}
+
+// Command returns the command the cursor is on
+func (cur *Cursor) Command() Command {
+	return *cur.com
 }
 
 /*         Methods and functions on Circuits        */
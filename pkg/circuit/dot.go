@@ -0,0 +1,261 @@
+package circuit
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	typ "ixxoprivacy/pkg/types"
+)
+
+// DotOptions gathers the parameters of the export of a circuit to the DOT
+// language of Graphviz.
+type DotOptions struct {
+	// Collapse draws a MASS_COPY or REPLICATE command as a single node instead
+	// of one node for each of the wires copied.
+	Collapse bool
+}
+
+// gateNames gives a readable name to the most common truth tables
+var gateNames = map[byte]string{
+	0: "ZERO", 1: "NOR", 3: "NOT a", 5: "NOT b", 6: "XOR", 7: "NAND",
+	8: "AND", 9: "XNOR", 10: "b", 12: "a", 14: "OR", 15: "ONE",
+}
+
+// GateLabel returns the label of a gate, made of its name when it has one and
+// of its truth table, whose bits are the outputs for (a, b) = 11, 10, 01 and 00.
+func GateLabel(table byte) string {
+	tt := fmt.Sprintf("%04b", table)
+	if name, ok := gateNames[table]; ok {
+		return name + "\\n" + tt
+	}
+	return tt
+}
+
+// dotGraph accumulates the nodes and edges of a function while it is visited.
+type dotGraph struct {
+	C        *Circuit
+	opts     DotOptions
+	usages   []Usage
+	nodes    []string            // nodes outside any cluster
+	clusters map[string][]string // nodes of each cluster
+	labels   map[string]string   // labels of the clusters
+	order    []string            // clusters in order of creation
+	edges    []string
+	producer map[typ.Num]int // node which produced the current value of each wire
+	count    int
+	inBits   []typ.Num // number of bits read from each party so far
+	outBits  []typ.Num // number of bits sent to each party so far
+}
+
+// WriteDot writes the main function of the circuit as a graph in the DOT
+// language of Graphviz.
+func (C Circuit) WriteDot(w io.Writer, opts DotOptions) error {
+	return C.writeDot(w, &C.Function, "main", opts)
+}
+
+// WriteFuncDot writes the function C.Funcs[i] of the circuit as a graph in the
+// DOT language of Graphviz. Wires read by the function before it writes them
+// are drawn as the inputs of the graph.
+func (C Circuit) WriteFuncDot(w io.Writer, i int, opts DotOptions) error {
+	if i < 0 || i >= len(C.Funcs) {
+		return fmt.Errorf("no function with index %d in the circuit", i)
+	}
+	return C.writeDot(w, C.Funcs[i], fmt.Sprintf("f%d", i), opts)
+}
+
+func (C Circuit) writeDot(w io.Writer, f *Function, name string, opts DotOptions) error {
+	g := &dotGraph{
+		C:        &C,
+		opts:     opts,
+		usages:   C.Usages(),
+		clusters: make(map[string][]string),
+		labels:   make(map[string]string),
+		producer: make(map[typ.Num]int),
+		inBits:   make([]typ.Num, C.Parties),
+		outBits:  make([]typ.Num, C.Parties),
+	}
+	for _, com := range f.Commands {
+		g.command(com)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", name)
+	b.WriteString("\trankdir=TB;\n\tnode [fontname=\"Helvetica\", fontsize=10];\n")
+	for _, cl := range g.order {
+		fmt.Fprintf(&b, "\tsubgraph %q {\n\t\tlabel=%q;\n\t\tstyle=rounded;\n", "cluster_"+cl, g.labels[cl])
+		for _, n := range g.clusters[cl] {
+			b.WriteString("\t\t" + n + ";\n")
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, n := range g.nodes {
+		b.WriteString("\t" + n + ";\n")
+	}
+	for _, e := range g.edges {
+		b.WriteString("\t" + e + ";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// newNode adds a node to the graph, in the cluster given if it is not empty,
+// and returns its identifier.
+func (g *dotGraph) newNode(cluster, label, attrs string) int {
+	id := g.count
+	g.count++
+	n := fmt.Sprintf("n%d [label=\"%s\"%s]", id, label, attrs)
+	if cluster == "" {
+		g.nodes = append(g.nodes, n)
+	} else {
+		g.clusters[cluster] = append(g.clusters[cluster], n)
+	}
+	return id
+}
+
+// cluster declares a cluster if it does not exist yet
+func (g *dotGraph) cluster(name, label string) string {
+	if _, ok := g.labels[name]; !ok {
+		g.labels[name] = label
+		g.order = append(g.order, name)
+	}
+	return name
+}
+
+// source returns the node which produced the current value of a wire,
+// creating a node for wires whose value comes from outside the function.
+func (g *dotGraph) source(w typ.Num) int {
+	if id, ok := g.producer[w]; ok {
+		return id
+	}
+	cl := g.cluster("free", "wires set by the caller")
+	id := g.newNode(cl, fmt.Sprintf("w%d", w), ", shape=plaintext")
+	g.producer[w] = id
+	return id
+}
+
+func (g *dotGraph) edge(from, to int, label string) {
+	if label == "" {
+		g.edges = append(g.edges, fmt.Sprintf("n%d -> n%d", from, to))
+	} else {
+		g.edges = append(g.edges, fmt.Sprintf("n%d -> n%d [label=%q, fontsize=8]", from, to, label))
+	}
+}
+
+func (g *dotGraph) command(com Command) {
+	switch com.Kind {
+	case INPUT, MASS_INPUT:
+		party := com.X
+		var in *Var
+		if int(party) < len(g.C.Inputs) {
+			in = g.C.Inputs[party]
+		}
+		cl := g.cluster(fmt.Sprintf("in_%d", party), fmt.Sprintf("inputs of party %d", party))
+		com.Writes(func(w typ.Num) {
+			label := fmt.Sprintf("in_%d#%d", party, g.inBits[party])
+			if in != nil && in.Type != nil && g.inBits[party] < in.Size() {
+				label = fmt.Sprintf("in_%d%s", party, in.BitPath(g.inBits[party]))
+			}
+			g.inBits[party]++
+			g.producer[w] = g.newNode(cl, label, ", shape=invtriangle")
+		})
+
+	case OUTPUT, MASS_OUTPUT:
+		party := com.To
+		var out *Var
+		if int(party) < len(g.C.Outputs) {
+			out = g.C.Outputs[party]
+		}
+		cl := g.cluster(fmt.Sprintf("out_%d", party), fmt.Sprintf("outputs to party %d", party))
+		com.Reads(func(w typ.Num) {
+			label := fmt.Sprintf("out_%d#%d", party, g.outBits[party])
+			if out != nil && out.Type != nil && g.outBits[party] < out.Size() {
+				label = fmt.Sprintf("out_%d%s", party, out.BitPath(g.outBits[party]))
+			}
+			g.outBits[party]++
+			g.edge(g.source(w), g.newNode(cl, label, ", shape=triangle"), "")
+		})
+
+	case COPY:
+		id := g.newNode("", "=", ", shape=point")
+		g.edge(g.source(com.X), id, "")
+		g.producer[com.To] = id
+
+	case MASS_COPY, REPLICATE:
+		if g.opts.Collapse {
+			label := fmt.Sprintf("MASS_COPY × %d", com.Y)
+			if com.Kind == REPLICATE {
+				label = fmt.Sprintf("REPLICATE × %d", com.Y)
+			}
+			id := g.newNode("", label, ", shape=cds")
+			seen := make(map[int]bool)
+			com.Reads(func(w typ.Num) {
+				if src := g.source(w); !seen[src] {
+					seen[src] = true
+					g.edge(src, id, "")
+				}
+			})
+			com.Writes(func(w typ.Num) {
+				g.producer[w] = id
+			})
+		} else {
+			for i := typ.Num(0); i < com.Y; i++ {
+				from := com.X + i
+				if com.Kind == REPLICATE {
+					from = com.X
+				}
+				id := g.newNode("", "=", ", shape=point")
+				g.edge(g.source(from), id, "")
+				g.producer[com.To+i] = id
+			}
+		}
+
+	case FUNCTION_CALL:
+		label := fmt.Sprintf("call f%d", com.X)
+		if com.Y > 1 {
+			label += fmt.Sprintf(" × %d", com.Y)
+		}
+		id := g.newNode("", label, ", shape=box3d")
+		u := g.usages[com.X]
+		seen := make(map[int]bool)
+		for _, w := range sortedWires(u.Reads) {
+			if src := g.source(w); !seen[src] {
+				seen[src] = true
+				g.edge(src, id, "")
+			}
+		}
+		for w := range u.Writes {
+			g.producer[w] = id
+		}
+
+	default:
+		if !com.IsGate() {
+			return
+		}
+		table := com.Gate()
+		attrs := ", shape=box"
+		if com.Kind == GATE_6 {
+			attrs = ", shape=ellipse"
+		}
+		id := g.newNode("", GateLabel(table), attrs)
+		// constant gates do not depend on their inputs
+		if com.Kind != GATE_0 && com.Kind != GATE_15 {
+			g.edge(g.source(com.X), id, "a")
+			g.edge(g.source(com.Y), id, "b")
+		}
+		g.producer[com.To] = id
+	}
+}
+
+// sortedWires returns the wires of a set in increasing order so that the
+// output does not depend on the order of iteration of maps
+func sortedWires(set map[typ.Num]bool) []typ.Num {
+	ws := make([]typ.Num, 0, len(set))
+	for w := range set {
+		ws = append(ws, w)
+	}
+	sort.Slice(ws, func(i, j int) bool { return ws[i] < ws[j] })
+	return ws
+}
@@ -0,0 +1,112 @@
+package circuit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	typ "ixxoprivacy/pkg/types"
+)
+
+// dotCircuit returns a circuit of two parties where party 0 gives an array
+// of two booleans and party 1 a boolean, whose AND and copies are the outputs
+// of party 1. The function f0 XORs the wires 3 and 4 into the wire 6.
+func dotCircuit() Circuit {
+	C := NewCircuit(8, 2)
+	C.Inputs[0] = &Var{typ.NewArrayType(2, typ.BoolType), 0}
+	C.Inputs[1] = &Var{typ.BoolType, 2}
+	ot := typ.NewObjType()
+	ot.Keys = []string{"and", "copies"}
+	ot.List = []*typ.Type{typ.BoolType, typ.NewArrayType(3, typ.BoolType)}
+	C.Outputs[1] = &Var{ot, 3}
+
+	f := NewFunctionPt()
+	f.PushNonFunctionCall(Command{GATE_6, 3, 4, 6})
+	C.Funcs = append(C.Funcs, f)
+
+	C.PushNonFunctionCall(Command{MASS_INPUT, 0, 2, 0})
+	C.PushNonFunctionCall(Command{INPUT, 1, 0, 2})
+	C.PushNonFunctionCall(Command{GATE_8, 0, 2, 3})
+	C.PushNonFunctionCall(Command{MASS_COPY, 0, 2, 4})
+	C.PushNonFunctionCall(Command{REPLICATE, 2, 2, 6})
+	C.PushFunctionCall(Command{FUNCTION_CALL, 0, 2, 0}, f.XORgates, f.NonXORgates)
+	C.PushNonFunctionCall(Command{MASS_OUTPUT, 3, 4, 1})
+	return C
+}
+
+func TestGateLabel(t *testing.T) {
+	fmt.Println("Starting TestGateLabel")
+	for table, want := range map[byte]string{8: `AND\n1000`, 6: `XOR\n0110`, 9: `XNOR\n1001`, 2: `0010`} {
+		if label := GateLabel(table); label != want {
+			t.Errorf("the gate %d is labelled %q instead of %q", table, label, want)
+		}
+	}
+}
+
+func TestWriteDot(t *testing.T) {
+	fmt.Println("Starting TestWriteDot")
+	C := dotCircuit()
+	var out bytes.Buffer
+	if err := C.WriteDot(&out, DotOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+	for _, want := range []string{
+		`digraph "main" {`,
+		`subgraph "cluster_in_0" {`, `label="inputs of party 0";`,
+		`subgraph "cluster_in_1" {`, `label="inputs of party 1";`,
+		`subgraph "cluster_out_1" {`, `label="outputs to party 1";`,
+		`[label="in_0[0]", shape=invtriangle]`, `[label="in_0[1]", shape=invtriangle]`, `[label="in_1", shape=invtriangle]`,
+		`[label="out_1.and", shape=triangle]`, `[label="out_1.copies[2]", shape=triangle]`,
+		`[label="AND\n1000", shape=box]`,
+		`[label="call f0 × 2", shape=box3d]`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("the graph does not contain %s:\n%s", want, dot)
+		}
+	}
+	if strings.Contains(dot, "cluster_out_0") || strings.Contains(dot, "cluster_free") {
+		t.Errorf("the graph has clusters without nodes:\n%s", dot)
+	}
+	// the copies are drawn one node a wire
+	if n := strings.Count(dot, `[label="=", shape=point]`); n != 4 {
+		t.Errorf("the 4 wires copied are drawn as %d nodes:\n%s", n, dot)
+	}
+
+	out.Reset()
+	if err := C.WriteDot(&out, DotOptions{Collapse: true}); err != nil {
+		t.Fatal(err)
+	}
+	dot = out.String()
+	if strings.Contains(dot, `shape=point`) {
+		t.Errorf("the copies are not collapsed:\n%s", dot)
+	}
+	for _, want := range []string{`[label="MASS_COPY × 2", shape=cds]`, `[label="REPLICATE × 2", shape=cds]`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("the graph does not contain %s:\n%s", want, dot)
+		}
+	}
+}
+
+func TestWriteFuncDot(t *testing.T) {
+	fmt.Println("Starting TestWriteFuncDot")
+	C := dotCircuit()
+	var out bytes.Buffer
+	if err := C.WriteFuncDot(&out, 0, DotOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+	for _, want := range []string{
+		`digraph "f0" {`, `subgraph "cluster_free" {`,
+		`[label="w3", shape=plaintext]`, `[label="w4", shape=plaintext]`,
+		`[label="XOR\n0110", shape=ellipse]`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("the graph does not contain %s:\n%s", want, dot)
+		}
+	}
+	if err := C.WriteFuncDot(&out, 1, DotOptions{}); err == nil {
+		t.Error("no error for a function which does not exist")
+	}
+}
@@ -3,6 +3,7 @@ package circuit
 import (
 	"encoding/gob"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"time"
@@ -102,15 +103,19 @@ func NewDecodingSet(parties uint8) DecodingSet {
 }
 
 // Encode uses a UserEncoder variable to encode one's own input values represented
-// by the slice in. The argument r is the offset used in Free-XOR.
+// by the slice in. The argument r is the offset used in Free-XOR. The keys
+// of the encoder are left as they are, so that they can still be read, by
+// a garbler for example, while the inputs are encoded.
 func (ue UserEncoder) Encode(r GarbledKey, in *UserInOut) UserEncoder {
 	rv := GarbledValue{true, r}
+	encoded := make(UserEncoder, len(ue))
+	copy(encoded, ue)
 	for i, x := range *in {
 		if x {
-			ue[i] = ue[i].XOR(rv)
+			encoded[i] = encoded[i].XOR(rv)
 		}
 	}
-	return ue
+	return encoded
 }
 
 // Decode uses a UserDecoder variable to return the clear output corresponding to
@@ -182,3 +187,40 @@ func RetrieveTableSet(path string) TableSet {
 	file.Close()
 	return TS
 }
+
+// WriteTable writes a garbled table to a stream of tables, every value of the
+// table being written as its permutation bit followed by its key. The tables
+// written one after the other can be read back with ReadTable.
+func WriteTable(w io.Writer, gt GarbledTable) error {
+	for _, gv := range gt {
+		p := byte(0)
+		if gv.P {
+			p = 1
+		}
+		if _, err := w.Write([]byte{p}); err != nil {
+			return err
+		}
+		if _, err := w.Write(gv.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadTable reads the next table of a stream written with WriteTable, the keys
+// being of n bytes. It returns io.EOF when the stream is over.
+func ReadTable(r io.Reader, n uint8) (GarbledTable, error) {
+	var gt GarbledTable
+	buf := make([]byte, 3*(int(n)+1))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return gt, fmt.Errorf("truncated table stream")
+		}
+		return gt, err
+	}
+	for i := range gt {
+		v := buf[i*(int(n)+1):]
+		gt[i] = GarbledValue{v[0] == 1, GarbledKey(v[1 : n+1])}
+	}
+	return gt, nil
+}
@@ -13,7 +13,10 @@ func Hash(data []byte) []byte {
 
 // hashGate produces the hash value used in case of a gate
 func HashGate(k1, k2 GarbledKey, index uint32, n uint8) GarbledValue {
-	data := append(k1, k2...)
+	// k1 is not appended to in place, its array may be shared with other
+	// wires and read at the same time by other gates
+	data := make([]byte, 0, len(k1)+len(k2)+4)
+	data = append(append(data, k1...), k2...)
 	ibytes := make([]byte, 4)
 	binary.LittleEndian.PutUint32(ibytes, index)
 	data = append(data, ibytes...)
@@ -24,7 +27,8 @@ func HashGate(k1, k2 GarbledKey, index uint32, n uint8) GarbledValue {
 // hashOut returns the boolean obtained as the first bit of a hash value
 func HashOut(k1 GarbledKey, index uint32) bool {
 	k2 := []byte("out")
-	data := append(k1, k2...)
+	data := make([]byte, 0, len(k1)+len(k2)+4)
+	data = append(append(data, k1...), k2...)
 	ibytes := make([]byte, 4)
 	binary.LittleEndian.PutUint32(ibytes, index)
 	data = append(data, ibytes...)
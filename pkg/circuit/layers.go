@@ -0,0 +1,172 @@
+package circuit
+
+import (
+	"sync"
+
+	typ "ixxoprivacy/pkg/types"
+)
+
+// A Step is a command working on a single wire: a COPY, an INPUT, an OUTPUT or
+// a gate. The commands of a circuit are turned into steps to be executed layer
+// by layer by the garbler and the evaluator.
+type Step struct {
+	Command
+	Index uint32 // gate index of a non-XOR gate, output index of an output
+	Arg   int    // index of the data of the step kept by the user of the steps
+}
+
+// AppendSteps appends to a list the steps a command which is not a function
+// call is made of. The non-XOR gates and the outputs are numbered from the
+// counters given, which are increased, as in a serial execution.
+func AppendSteps(steps []Step, com Command, gateIndex, outIndex *uint32) []Step {
+	switch com.Kind {
+	case INPUT, COPY:
+		steps = append(steps, Step{Command: com})
+	case MASS_INPUT:
+		for j := typ.Num(0); j < com.Y; j++ {
+			steps = append(steps, Step{Command: Command{INPUT, com.X, 0, com.To + j}})
+		}
+	case MASS_COPY:
+		for j := typ.Num(0); j < com.Y; j++ {
+			steps = append(steps, Step{Command: Command{COPY, com.X + j, 0, com.To + j}})
+		}
+	case REPLICATE:
+		for j := typ.Num(0); j < com.Y; j++ {
+			steps = append(steps, Step{Command: Command{COPY, com.X, 0, com.To + j}})
+		}
+	case OUTPUT:
+		steps = append(steps, Step{Command: com, Index: *outIndex})
+		*outIndex++
+	case MASS_OUTPUT:
+		for j := typ.Num(0); j < com.Y; j++ {
+			steps = append(steps, Step{Command: Command{OUTPUT, com.X + j, 0, com.To}, Index: *outIndex})
+			*outIndex++
+		}
+	default:
+		if com.IsGate() {
+			s := Step{Command: com}
+			if com.Kind != GATE_6 {
+				s.Index = *gateIndex
+				*gateIndex++
+			}
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+// A Layering splits lists of steps into layers which can be executed one after
+// the other, the steps of a layer neither reading nor writing a wire written by
+// another step of the layer so that they can be executed in any order.
+type Layering struct {
+	lastWrite []int32 // layer of the last step writing each wire
+	lastRead  []int32 // last layer reading each wire
+}
+
+// NewLayering returns a layering for the steps of a circuit of a given number of wires
+func NewLayering(wires typ.Num) *Layering {
+	return &Layering{
+		lastWrite: make([]int32, wires),
+		lastRead:  make([]int32, wires),
+	}
+}
+
+// Layers returns the indexes of the steps of a list grouped by layer. Every step
+// is put in the first layer coming after the layers of the steps writing the wires
+// it reads or writes and of the steps reading the wire it writes.
+func (ly *Layering) Layers(steps []Step) [][]int32 {
+	level := make([]int32, len(steps))
+	layers := int32(0)
+	for i, s := range steps {
+		l := int32(1)
+		s.Reads(func(w typ.Num) {
+			if ly.lastWrite[w] >= l {
+				l = ly.lastWrite[w] + 1
+			}
+		})
+		s.Writes(func(w typ.Num) {
+			if ly.lastWrite[w] >= l {
+				l = ly.lastWrite[w] + 1
+			}
+			if ly.lastRead[w] >= l {
+				l = ly.lastRead[w] + 1
+			}
+		})
+		s.Reads(func(w typ.Num) {
+			if ly.lastRead[w] < l {
+				ly.lastRead[w] = l
+			}
+		})
+		s.Writes(func(w typ.Num) {
+			ly.lastWrite[w] = l
+		})
+		level[i] = l
+		if l > layers {
+			layers = l
+		}
+	}
+
+	// the layering starts again from scratch for the next list
+	for _, s := range steps {
+		reset := func(w typ.Num) {
+			ly.lastWrite[w], ly.lastRead[w] = 0, 0
+		}
+		s.Reads(reset)
+		s.Writes(reset)
+	}
+
+	// steps sorted by layer
+	start := make([]int, layers+2)
+	for _, l := range level {
+		start[l+1]++
+	}
+	for l := 1; l < len(start); l++ {
+		start[l] += start[l-1]
+	}
+	order := make([]int32, len(steps))
+	pos := append([]int(nil), start...)
+	for i, l := range level {
+		order[pos[l]] = int32(i)
+		pos[l]++
+	}
+	result := make([][]int32, layers)
+	for l := range result {
+		result[l] = order[start[l+1]:start[l+2]]
+	}
+	return result
+}
+
+// minParallelLayer is the size of the parts of the layers given to the workers
+const minParallelLayer = 256
+
+// RunLayers calls a function on the steps of the layers, one layer after the
+// other. The large layers are split among a number of workers.
+func RunLayers(layers [][]int32, workers int, run func(i int32)) {
+	var wg sync.WaitGroup
+	for _, layer := range layers {
+		if workers < 2 || len(layer) < 2*minParallelLayer {
+			for _, i := range layer {
+				run(i)
+			}
+			continue
+		}
+		size := (len(layer) + workers - 1) / workers
+		if size < minParallelLayer {
+			size = minParallelLayer
+		}
+		for p := 0; p < len(layer); p += size {
+			end := p + size
+			if end > len(layer) {
+				end = len(layer)
+			}
+			wg.Add(1)
+			go func(part []int32) {
+				defer wg.Done()
+				for _, i := range part {
+					run(i)
+				}
+			}(layer[p:end])
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,146 @@
+package circuit
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+
+	typ "ixxoprivacy/pkg/types"
+)
+
+// FirstRelocatableWire is the first wire of an object which is moved when the
+// object is linked. Wires 0 and 1 hold false and true in every circuit
+// produced by the compiler, objects use them for their constants.
+const FirstRelocatableWire typ.Num = 2
+
+// An Object is a function compiled once and for all, which can be linked into
+// other circuits. Its interface is made of the wires receiving its parameters
+// and of the wires holding its return value, all its other wires being used
+// for its computations. No mass command of an object reads both constant and
+// relocatable wires.
+type Object struct {
+	Name   string
+	Params []*Var // wires receiving the arguments of the function
+	Return *Var   // wires holding the returned value, nil if the function returns nothing
+	// Funcs[0] is the function to call, the other ones are the functions it
+	// calls, FUNCTION_CALL commands using indexes in this list.
+	Funcs   []*Function
+	Wires   typ.Num // number of wires used by the object, constants included
+	IntSize typ.Num // $intsize of the program the object was compiled from
+}
+
+// A Library is a set of objects stored in a single file
+type Library struct {
+	Objects []*Object
+}
+
+// A Link describes an object once linked into a circuit
+type Link struct {
+	Function typ.Num // index of the function of the object to call in the circuit
+	Params   []*Var  // wires receiving the arguments in the circuit
+	Return   *Var    // wires holding the returned value in the circuit, or nil
+}
+
+// Link appends the functions of an object to the functions of the circuit,
+// moving its relocatable wires to a range of wires starting at base. The
+// range must not be used by the circuit, TotalWires is raised if needed.
+func (C *Circuit) Link(o *Object, base typ.Num) (Link, error) {
+	first := typ.Num(len(C.Funcs))
+	reloc := func(w typ.Num) typ.Num {
+		if w < FirstRelocatableWire {
+			return w
+		}
+		return w - FirstRelocatableWire + base
+	}
+	relocVar := func(v *Var) *Var {
+		if v == nil {
+			return nil
+		}
+		return &Var{v.Type, reloc(v.Wirebase)}
+	}
+
+	funcs := make([]*Function, len(o.Funcs))
+	for i, f := range o.Funcs {
+		nf := &Function{XORgates: f.XORgates, NonXORgates: f.NonXORgates, Commands: make([]Command, len(f.Commands))}
+		for j, com := range f.Commands {
+			switch com.Kind {
+			case FUNCTION_CALL:
+				if com.X >= typ.Num(len(o.Funcs)) {
+					return Link{}, fmt.Errorf("object %s calls unknown function %d", o.Name, com.X)
+				}
+				com.X += first
+			case COPY, MASS_COPY, REPLICATE:
+				if com.Kind == MASS_COPY && com.X < FirstRelocatableWire && com.X+com.Y > FirstRelocatableWire {
+					return Link{}, fmt.Errorf("object %s copies constant and relocatable wires at once", o.Name)
+				}
+				com.X, com.To = reloc(com.X), reloc(com.To)
+			case INPUT, MASS_INPUT, OUTPUT, MASS_OUTPUT:
+				return Link{}, fmt.Errorf("object %s has inputs or outputs", o.Name)
+			default:
+				if com.IsGate() {
+					com.X, com.Y, com.To = reloc(com.X), reloc(com.Y), reloc(com.To)
+				}
+			}
+			nf.Commands[j] = com
+		}
+		funcs[i] = nf
+	}
+	C.Funcs = append(C.Funcs, funcs...)
+	if end := base + o.Wires - FirstRelocatableWire; end > C.TotalWires {
+		C.TotalWires = end
+	}
+
+	l := Link{Function: first, Params: make([]*Var, len(o.Params)), Return: relocVar(o.Return)}
+	for i, p := range o.Params {
+		l.Params[i] = relocVar(p)
+	}
+	return l, nil
+}
+
+// Find returns the object of a library with a given name, or nil
+func (lib Library) Find(name string) *Object {
+	for _, o := range lib.Objects {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// SaveToFile saves a library into a file using gobs encoding
+func (lib *Library) SaveToFile(path string) {
+	outputFile, err := os.Create(path)
+	if err != nil {
+		fmt.Println("Error in SaveToFile: file creation failed")
+		fmt.Println(err)
+		os.Exit(64)
+	}
+	encoder := gob.NewEncoder(outputFile)
+	err = encoder.Encode(lib)
+	if err != nil {
+		fmt.Println("Error in SaveToFile: encoding failed")
+		fmt.Println(err)
+		os.Exit(64)
+	}
+	outputFile.Close()
+}
+
+// RetrieveLibrary is used to get a library from a file generated with method SaveToFile
+func RetrieveLibrary(path string) Library {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Println("Error: could not open library file")
+		fmt.Println(err)
+		os.Exit(64)
+	}
+	decoder := gob.NewDecoder(file)
+	var lib Library
+	err = decoder.Decode(&lib)
+	if err != nil {
+		fmt.Println("Error: could not decode library.")
+		fmt.Println(err)
+		os.Exit(64)
+	}
+	file.Close()
+	return lib
+}
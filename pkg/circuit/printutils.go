@@ -4,7 +4,7 @@ import "fmt"
 
 // Method to print a whole circuit
 func (C Circuit) Print(indent string) {
-	fmt.Println("\n", indent, "----- Printing circuit -----\n")
+	fmt.Printf("\n %s ----- Printing circuit -----\n\n", indent)
 	fmt.Println(indent, "Parties: ", C.Parties)
 	fmt.Println(indent, "IntSize: ", C.IntSize)
 	fmt.Println(indent, "TotalWires: ", C.TotalWires)
@@ -0,0 +1,239 @@
+package compiler
+
+import (
+	"sort"
+	"strings"
+
+	typ "ixxoprivacy/pkg/types"
+	vb "ixxoprivacy/pkg/variables"
+	wr "ixxoprivacy/pkg/wires"
+
+	"github.com/robertkrimen/otto/ast"
+	tk "github.com/robertkrimen/otto/token"
+)
+
+/*
+ * A function is compiled once, and its body reads and writes the global
+ * variables on their permanent wires whatever the caller. Around a call, the
+ * caller thus writes to their permanent wires the values of the globals the
+ * callee reads, and forgets what it knew of the globals the callee writes.
+ *
+ * In an if statement on a secret condition, the globals the callee writes are
+ * saved before the call and restored after it when the condition is false, so
+ * that the call has no effect unless the condition holds. The return value is
+ * assigned under the condition like any other value. The parameters and the
+ * locals of the callee need no such care since every call assigns them before
+ * they are read.
+ */
+
+// globalAccess are the global variables a function reads or writes, either
+// directly or through the functions it calls
+type globalAccess struct {
+	used    map[string]bool
+	written map[string]bool
+}
+
+// accesses caches the global variables accessed by the functions of the
+// program being compiled
+var accesses map[string]*globalAccess
+
+// functionGlobals returns the global variables accessed by a function
+func functionGlobals(name string) *globalAccess {
+	if ga, ok := accesses[name]; ok {
+		return ga
+	}
+	ga := &globalAccess{used: make(map[string]bool), written: make(map[string]bool)}
+	accesses[name] = ga
+	if truncates(name) {
+		// the calls cut off by the $maxdepth set it
+		ga.used[truncatedName], ga.written[truncatedName] = true, true
+	}
+	if fv, ok := context.FunctionContext[name].(*vb.FunctionVariable); ok && !fv.Extern {
+		ast.Walk(&globalsVisitor{ga, context.Funcs[name]}, fv.FunctionNode)
+	}
+	return ga
+}
+
+// globalsVisitor collects the global variables accessed by a function body
+type globalsVisitor struct {
+	*globalAccess
+	locals vb.FunctionContext
+}
+
+func (gv *globalsVisitor) Enter(n ast.Node) ast.Visitor {
+	switch e := n.(type) {
+	case *ast.Identifier:
+		if name := gv.global(e); name != "" {
+			gv.used[name] = true
+		}
+	case *ast.DotExpression:
+		// the identifier on the right is a key, not a variable
+		ast.Walk(gv, e.Left)
+		return nil
+	case *ast.AssignExpression:
+		gv.write(e.Left)
+	case *ast.UnaryExpression:
+		if e.Operator == tk.INCREMENT || e.Operator == tk.DECREMENT {
+			gv.write(e.Operand)
+		}
+	case *ast.CallExpression:
+		id, ok := e.Callee.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		switch id.Name {
+		case "SetWire", "CompareExchange", "CompareExchangeBy":
+			if len(e.ArgumentList) > 0 {
+				gv.write(e.ArgumentList[0])
+			}
+		default:
+			if _, ok := context.FunctionContext[id.Name].(*vb.FunctionVariable); ok {
+				callee := functionGlobals(id.Name)
+				for g := range callee.used {
+					gv.used[g] = true
+				}
+				for g := range callee.written {
+					gv.written[g] = true
+				}
+			}
+		}
+	}
+	return gv
+}
+
+func (gv *globalsVisitor) Exit(n ast.Node) {}
+
+// write records the global variable changed by an assignment to an
+// expression such as a.b[i], if any
+func (gv *globalsVisitor) write(n ast.Expression) {
+	for {
+		switch e := n.(type) {
+		case *ast.BracketExpression:
+			n = e.Left
+		case *ast.DotExpression:
+			n = e.Left
+		case *ast.Identifier:
+			if name := gv.global(e); name != "" {
+				gv.written[name] = true
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+// global returns the name of the global variable an identifier refers to,
+// or "" when it is a local variable, a function or a dollar variable
+func (gv *globalsVisitor) global(id *ast.Identifier) string {
+	if _, ok := gv.locals[id.Name]; ok || strings.HasPrefix(id.Name, "$") {
+		return ""
+	}
+	v, ok := context.FunctionContext[id.Name]
+	if !ok {
+		return ""
+	}
+	if _, ok := v.(*vb.FunctionVariable); ok {
+		return ""
+	}
+	return id.Name
+}
+
+// sortedNames returns the names of a set in a fixed order, so that the same
+// program always gives the same circuit
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flushWire writes the value of a wire on the wire itself, keeping what is
+// known of it
+func flushWire(w *wr.Wire) {
+	switch w.State {
+	case wr.ONE:
+		writer.AddCopy(w.Number, W_1.Number)
+	case wr.ZERO:
+		writer.AddCopy(w.Number, W_0.Number)
+	default:
+		makeWireContainValueNoONEZEROcopy(w)
+	}
+}
+
+// flushVar writes the values of the wires of a variable on its wires
+func flushVar(v vb.VarInterface) {
+	for i := typ.Num(0); i < v.Size(); i++ {
+		flushWire(v.GetWire(i))
+	}
+}
+
+// beforeCall prepares the global variables accessed by a function to be
+// called. In an if statement, it returns the saved values of the globals the
+// function writes.
+func beforeCall(name string, fc vb.FunctionContext) map[string][]*wr.Wire {
+	ga := functionGlobals(name)
+	for _, g := range sortedNames(ga.used) {
+		flushVar(context.FunctionContext[g])
+	}
+	var saved map[string][]*wr.Wire
+	if fc["-+IFCOND+-"] != nil {
+		saved = make(map[string][]*wr.Wire)
+	}
+	for _, g := range sortedNames(ga.written) {
+		v := context.FunctionContext[g]
+		ws := make([]*wr.Wire, v.Size())
+		for i := range ws {
+			w := v.GetWire(typ.Num(i))
+			if saved != nil {
+				ws[i] = pool.GetWire()
+				assignWire(ws[i], w)
+				ws[i].Locked = true
+			}
+			// the wires referencing w keep its value before the call
+			clearReffedWire(w)
+		}
+		if saved != nil {
+			saved[g] = ws
+		}
+	}
+	return saved
+}
+
+// afterCall updates the global variables written by a function after a call
+// to it, restoring their saved values when the condition of the if statement
+// of the call is false
+func afterCall(name string, fc vb.FunctionContext, saved map[string][]*wr.Wire) {
+	var skipped *wr.Wire
+	if saved != nil {
+		skipped = invertWire(fc["-+IFCOND+-"].GetWire(0))
+		skipped.Locked = true
+	}
+	for _, g := range sortedNames(functionGlobals(name).written) {
+		v := context.FunctionContext[g]
+		for i := typ.Num(0); i < v.Size(); i++ {
+			w := v.GetWire(i)
+			w.FreeRefs()
+			w.State = wr.UNKNOWN
+			if saved != nil {
+				assignWireCond(w, saved[g][i], skipped)
+				makeWireContainValueNoONEZEROcopy(w)
+				saved[g][i].Locked = false
+			}
+		}
+	}
+	if skipped != nil {
+		skipped.Locked = false
+	}
+	pool.FreeIfNoRefs()
+}
+
+// flushWrittenGlobals writes on their wires the values of the global
+// variables written by a function at the end of its body
+func flushWrittenGlobals(name string) {
+	for _, g := range sortedNames(functionGlobals(name).written) {
+		flushVar(context.FunctionContext[g])
+	}
+}
@@ -11,7 +11,7 @@ import (
 	wr "ixxoprivacy/pkg/wires"
 
 	"github.com/robertkrimen/otto/ast"
-	"github.com/robertkrimen/otto/parser"
+	"github.com/robertkrimen/otto/file"
 )
 
 var circuit circ.Circuit      // the circuit we work on
@@ -39,27 +39,33 @@ func SetParamsCG(bast, bcont, bdebug bool) {
 }
 
 // CircuitFromJS returns a boolean circuit from a JavaScript file whose path
-// is given in argument
+// is given in argument, and from the files it imports, see imports.go
 func CircuitFromJS(path string) (circ.Circuit, error) {
-	src, err := os.Open(path)
-	if err != nil {
+	if _, err := os.Stat(path); err != nil {
 		fmt.Println("Error : could not open file")
 		fmt.Println(err)
 		os.Exit(64)
 	}
-	program, err := parser.ParseFile(nil, "", src, 0)
-	src.Close()
+	program, files, err := parseProgram(path)
 	if err != nil {
-		fmt.Println("Error : could not parse file :\n")
+		fmt.Println("Error : could not parse file :")
 		fmt.Println(err)
 		os.Exit(64)
 	}
-	return CircuitFromAST(program)
+	return compileProgram(program, files)
 }
 
 // CircuitFromAST returns a boolean circuit from an abstract syntax tree
-// whith the format used in the otto package
+// whith the format used in the otto package. The source map of the circuit
+// only knows the file of the tree, see CircuitFromJS for the programs which
+// import other files.
 func CircuitFromAST(prog *ast.Program) (circ.Circuit, error) {
+	return compileProgram(prog, []*file.File{prog.File})
+}
+
+// compileProgram returns the circuit of a program whose source files are
+// given, the main file first
+func compileProgram(prog *ast.Program, files []*file.File) (circ.Circuit, error) {
 	if printAST {
 		typ.PrintAST(prog, false)
 	}
@@ -67,15 +73,24 @@ func CircuitFromAST(prog *ast.Program) (circ.Circuit, error) {
 		fmt.Println("Starting to initialize")
 	}
 
+	nextBaseWire = 0
 	circuit = circ.NewCircuit(findParameters(prog.DeclarationList))
 	writer = StartFuncWriter(&circuit.Function)
+	sourceFiles = files
+	currentSource = &FuncSource{Name: "main"}
+	currentSource.Start, currentSource.End = mainSpan(prog)
+	funcSources = map[*circ.Function]*FuncSource{&circuit.Function: currentSource}
+	probes = make(map[*circ.Function][]Probe)
+	lastBits = make(map[*circ.Function]map[string][]Bit)
+	accesses = make(map[string]*globalAccess)
 	makeONEandZERO()
 
-	context = vb.GenerateContext(prog, circuit.IntSize, W_0, W_1)
+	findRecursions(prog)
+	externs := externFunctions(prog)
+	context = vb.GenerateContext(prog, circuit.IntSize, W_0, W_1, externs)
 	if printCont {
 		context.Print("")
 	}
-	fNames := make([]string, 0) // fNames contains the names of the functions using the same index as the one in circuit
 
 	if debug {
 		fmt.Println("Starting with variable set up")
@@ -84,7 +99,7 @@ func CircuitFromAST(prog *ast.Program) (circ.Circuit, error) {
 	// We initialize permanent wires for all variables
 	for name, v := range context.FunctionContext {
 
-		if fv, ok := v.(*vb.FunctionVariable); !ok {
+		if _, ok := v.(*vb.FunctionVariable); !ok {
 			v.FillInWires(nil)
 			if !strings.HasPrefix(v.GetName(), "$") {
 				v.SetPerm()
@@ -100,38 +115,12 @@ func CircuitFromAST(prog *ast.Program) (circ.Circuit, error) {
 			} else if v.IsOutput() {
 				circuit.Outputs[getParty(name)] = vb.CircVar(v)
 			}
-
-		} else {
-			fv.FunctionNumber = typ.Num(len(circuit.Funcs))
-			circuit.Funcs = append(circuit.Funcs, circ.NewFunctionPt())
-			fNames = append(fNames, fv.GetName())
-
-			for _, v := range context.Funcs[fv.GetName()] {
-				v.FillInWires(nil)
-				if strings.HasPrefix(v.GetName(), "$") {
-					v.SetConst()
-				} else {
-					v.SetPerm()
-					nextBaseWire = v.AssignPermWires(nextBaseWire)
-				}
-			}
-
-			// setting arguments wires to unknown
-			for _, av := range fv.Argsv {
-				for i := typ.Num(0); i < av.Size(); i++ {
-					av.GetWire(i).State = wr.UNKNOWN
-				}
-			}
-			// setting return wires to unknown if they exist
-			if fv.Returnv != nil {
-				for i := typ.Num(0); i < fv.Returnv.Size(); i++ {
-					fv.Returnv.GetWire(i).State = wr.UNKNOWN
-				}
-			}
 		}
 	}
 
-	// TODO: sort functions
+	if err := linkExterns(prog, externs); err != nil {
+		return circ.Circuit{}, err
+	}
 	pool = wr.NewWirePool(nextBaseWire)
 
 	/*
@@ -147,36 +136,13 @@ func CircuitFromAST(prog *ast.Program) (circ.Circuit, error) {
 		}
 	}
 
+	// Output of the main body, the functions being compiled when they are
+	// first called, see instances.go
 	if debug {
-		fmt.Println("\nStarting with functions\n")
-	}
-
-	// We output the auxiliary functions
-	for i, f := range circuit.Funcs {
-		writer.ChangeFunction(f)
-		name := fNames[i]
-		fv := context.FunctionContext[name].(*vb.FunctionVariable)
-		fc := context.Funcs[name]
-
-		outFunctionLiteral(fv.FunctionNode, fc)
-
-		nextBaseWire = pool.NextNumber
-		pool = wr.NewWirePool(nextBaseWire)
-
-		for _, v := range context.FunctionContext {
-			if _, ok := v.(*vb.FunctionVariable); !ok && !strings.HasPrefix(v.GetName(), "$") {
-				for i := typ.Num(0); i < v.Size(); i++ {
-					v.GetWire(i).State = wr.UNKNOWN
-				}
-			}
-		}
-	}
-
-	// Output of the main body
-	if debug {
-		fmt.Println("\nStarting with main\n")
+		fmt.Println("\nStarting with main")
 	}
 	writer.ChangeFunction(&circuit.Function)
+	currentSource = funcSources[&circuit.Function]
 
 	mainNode := &ast.BlockStatement{List: prog.Body}
 	outStatementNode(mainNode, context.FunctionContext)
@@ -195,6 +161,7 @@ func CircuitFromAST(prog *ast.Program) (circ.Circuit, error) {
 	writer.AddPrev(nullComm)
 	nextBaseWire = pool.NextNumber
 	circuit.TotalWires = nextBaseWire
+	lastSourceMap = buildSourceMap()
 
 	return circuit, nil
 }
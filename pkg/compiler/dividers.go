@@ -0,0 +1,251 @@
+package compiler
+
+import (
+	"math/big"
+
+	wr "ixxoprivacy/pkg/wires"
+)
+
+/*
+ * A division or a modulus by an unknown integer is the restoring divider of
+ * outputDivideSigned and outputDivideUnsigned, which costs about 2n^2 gates
+ * for integers of n bits. A known divisor d is a multiplication by its
+ * reciprocal, as in Granlund and Montgomery: for the smallest p such that
+ * M = ceil(2^p / d) is close enough to 2^p / d, the quotient of x by d is the
+ * integer part of x * M / 2^p for every integer x of n bits. A signed
+ * quotient is rounded towards zero by adding 1 to the negative ones, and a
+ * negative divisor negates it. The modulus is x - q * d, as in JavaScript it
+ * has the sign of x. Unsigned divisions and moduli by a power of two are
+ * wiring only.
+ */
+
+// outputDivide computes the division or the modulus, when IsModDiv is true,
+// of leftv by rightv, producing gates when it is necessary.
+// Precondiction - all vectors are of proper size:
+// |leftv| == |rightv| == |destv|
+func outputDivide(leftv, rightv, destv wr.WireSet, signed, IsModDiv bool) {
+	if bits, ok := knownBits(rightv); ok && len(bits) >= len(destv) {
+		// the wires of a literal may be more than its type's
+		if d := bitsToInt(bits[:len(destv)], signed); d.Sign() != 0 {
+			assignWireSet(destv, divideByConstant(leftv, d, signed, IsModDiv))
+			pool.FreeSinglesIfNoRefs()
+			return
+		}
+	}
+	if signed {
+		outputDivideSigned(leftv, rightv, destv, IsModDiv)
+	} else {
+		outputDivideUnsigned(leftv, rightv, destv, IsModDiv)
+	}
+}
+
+// divideByConstant returns the quotient or the modulus of x by a known
+// divisor which is not 0
+func divideByConstant(x wr.WireSet, d *big.Int, signed, IsModDiv bool) wr.WireSet {
+	length := len(x)
+	a := new(big.Int).Abs(d)
+	k := a.BitLen() - 1
+	if IsModDiv && k == 0 {
+		return widen(nil, length)
+	}
+
+	var q wr.WireSet
+	switch {
+	case a.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(k))) != 0:
+		q = quotientByReciprocal(x, a, signed)
+	case !signed:
+		if IsModDiv {
+			return widen(x[:k], length)
+		}
+		q = widen(x[k:], length)
+	case k == 0:
+		q = widen(x, length)
+	default:
+		// a negative x is added 2^k - 1 so that the shift rounds it up
+		sign := x[length-1]
+		sum := sumWires(x, repeatWire(sign, k), length)
+		q = append(widen(sum[k:], length-k), repeatWire(sum[length-1], k)...)
+	}
+	if d.Sign() < 0 {
+		q = negateWires(q)
+	}
+	if !IsModDiv {
+		return q
+	}
+	return differenceWires(x, multiplyByConstant(q, intToBits(d, length)), length)
+}
+
+// quotientByReciprocal returns the quotient of x by a known positive divisor
+// which is not a power of two
+func quotientByReciprocal(x wr.WireSet, a *big.Int, signed bool) wr.WireSet {
+	length := len(x)
+	l := a.BitLen()
+	// x is at most 2^bound in absolute value, and the quotient holds in
+	// length - l + 1 bits, its sign included
+	bound := length
+	if signed {
+		bound--
+	}
+	quotientLength := length - l + 1
+
+	r := cheapestReciprocal(a, bound, quotientLength)
+	width := r.p + quotientLength
+	xw := widen(x, width)
+	if signed {
+		xw = append(widen(x, length), repeatWire(x[length-1], width-length)...)
+	}
+	var product wr.WireSet
+	if r.repeats == 0 {
+		product = multiplyByConstant(xw, intToBits(r.M, width))
+	} else {
+		y := multiplyByConstant(xw, intToBits(r.M, width))
+		product = addShifted(repeatBlocks(y, r.period, r.repeats), y, 0)
+	}
+	q := widen(product[r.p:], length)
+	if signed {
+		q = append(widen(product[r.p:], quotientLength), repeatWire(product[width-1], length-quotientLength)...)
+		q = sumWires(q, wr.WireSet{x[length-1]}, length)
+	}
+	return q
+}
+
+// reciprocal is a multiplier M such that x * M / 2^p is x divided by the
+// divisor, M being c * (1 + 2^T + ... + 2^((r-1)T)) + c when there are r
+// repeats of the block c of T bits
+type reciprocal struct {
+	M       *big.Int
+	p       int
+	period  int
+	repeats int
+	cost    int
+}
+
+// cheapestReciprocal returns the reciprocal of a divisor whose
+// multiplication costs the least gates, for integers of at most 2^bound in
+// absolute value.
+// With M = ceil(2^p / a), M * a - 2^p must be at most 2^(p - bound), which
+// is true for p = bound + l. When a is 2^s times an odd a' and 2^T - 1 is a
+// multiple of a', 1 / a' is the block c = (2^T - 1) / a' repeated, and M
+// can be c * (1 + 2^T + ... + 2^rT), for which M * a - 2^p is 2^s (2^T - 2)
+// with p = rT + s.
+func cheapestReciprocal(a *big.Int, bound, quotientLength int) reciprocal {
+	var best reciprocal
+	for p := bound; p <= bound+a.BitLen(); p++ {
+		power := new(big.Int).Lsh(big.NewInt(1), uint(p))
+		m := new(big.Int).Sub(power, big.NewInt(1))
+		m.Div(m, a).Add(m, big.NewInt(1))
+		e := new(big.Int).Mul(m, a)
+		e.Sub(e, power)
+		if e.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(p-bound))) > 0 {
+			continue
+		}
+		c := constantMultiplicationCost(intToBits(m, p+quotientLength))
+		if best.M == nil || c < best.cost {
+			best = reciprocal{M: m, p: p, cost: c}
+		}
+	}
+
+	s := int(a.TrailingZeroBits())
+	odd := new(big.Int).Rsh(a, uint(s))
+	period := 1
+	rest := big.NewInt(2)
+	for rest.Mod(rest, odd).Cmp(big.NewInt(1)) != 0 && period < bound {
+		rest.Lsh(rest, 1)
+		period++
+	}
+	if 2*period > bound {
+		return best
+	}
+	// 2^T - 2 is at most 2^(rT - bound) from rT - bound = T, or 1 when T is 2
+	excess := period
+	if period == 2 {
+		excess = 1
+	}
+	repeats := (bound + excess + period - 1) / period
+	block := new(big.Int).Lsh(big.NewInt(1), uint(period))
+	block.Sub(block, big.NewInt(1)).Div(block, odd)
+	p := repeats*period + s
+	width := p + quotientLength
+	c := constantMultiplicationCost(intToBits(block, width)) + repeatBlocksCost(width, period, repeats) + width - 1
+	if c < best.cost {
+		best = reciprocal{M: block, p: p, period: period, repeats: repeats, cost: c}
+	}
+	return best
+}
+
+// repeatBlocks returns y * (1 + 2^T + ... + 2^((r-1)T)) modulo 2^|y|, with
+// the sums of half the blocks doubled
+func repeatBlocks(y wr.WireSet, period, repeats int) wr.WireSet {
+	if repeats == 1 {
+		return y
+	}
+	half := repeatBlocks(y, period, repeats/2)
+	sum := addShifted(half, half, repeats/2*period)
+	if repeats%2 == 1 {
+		sum = addShifted(sum, y, (repeats-1)*period)
+	}
+	return sum
+}
+
+// repeatBlocksCost returns the number of AND gates of repeatBlocks
+func repeatBlocksCost(width, period, repeats int) int {
+	if repeats == 1 {
+		return 0
+	}
+	cost := repeatBlocksCost(width, period, repeats/2) + width - repeats/2*period - 1
+	if repeats%2 == 1 {
+		cost += width - (repeats-1)*period - 1
+	}
+	return cost
+}
+
+// constantMultiplicationCost returns the number of AND gates of the
+// multiplication by a known integer of the given bits, as in
+// multiplyByConstant but for the choice of the first digit
+func constantMultiplicationCost(bits []bool) int {
+	cost := 0
+	first := true
+	for i, d := range signedDigits(bits)[:len(bits)] {
+		if d != 0 && first {
+			first = false
+		} else if d != 0 {
+			cost += len(bits) - i - 1
+		}
+	}
+	return cost
+}
+
+// repeatWire returns a set of wires made of the same wire
+func repeatWire(w *wr.Wire, length int) wr.WireSet {
+	ws := wr.EmptyWireSet(0)
+	for len(ws) < length {
+		ws = append(ws, w)
+	}
+	return ws
+}
+
+// bitsToInt returns the integer of the given bits
+func bitsToInt(bits []bool, signed bool) *big.Int {
+	v := new(big.Int)
+	for i := len(bits) - 1; i >= 0; i-- {
+		v.Lsh(v, 1)
+		if bits[i] {
+			v.SetBit(v, 0, 1)
+		}
+	}
+	if signed && len(bits) > 0 && bits[len(bits)-1] {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(bits))))
+	}
+	return v
+}
+
+// intToBits returns the bits of an integer modulo 2^length
+func intToBits(v *big.Int, length int) []bool {
+	m := new(big.Int).Lsh(big.NewInt(1), uint(length))
+	m.Mod(v, m)
+	bits := make([]bool, length)
+	for i := range bits {
+		bits[i] = m.Bit(i) == 1
+	}
+	return bits
+}
@@ -0,0 +1,104 @@
+package compiler
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestDivideByConstant checks the quotients and the moduli by known divisors,
+// rounded towards zero as in JavaScript, including the ones which overflow
+func TestDivideByConstant(t *testing.T) {
+	fmt.Println("Starting TestDivideByConstant")
+	r := rand.New(rand.NewSource(1))
+	divisors := []int64{1, -1, 2, 3, -3, 5, 7, 10, -10, 16, -8, 100, 641, 1000, 65537}
+	for _, size := range []int{8, 16, 32, 45} {
+		var source strings.Builder
+		fmt.Fprintf(&source, "var $parties = 1\nvar $intsize = %d\nvar in_0 = 0\nvar out_0 = {", size)
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(size-1))
+		var used []int64
+		for _, d := range divisors {
+			if big.NewInt(d).CmpAbs(limit) < 0 {
+				used = append(used, d)
+				fmt.Fprintf(&source, "q%d: 0, r%d: 0, ", len(used), len(used))
+			}
+		}
+		source.WriteString("}\n")
+		for i, d := range used {
+			fmt.Fprintf(&source, "out_0.q%d = in_0 / %d\nout_0.r%d = in_0 %% %d\n", i+1, d, i+1, d)
+		}
+
+		for run := 0; run < 8; run++ {
+			x := randomInt(r, size)
+			switch run {
+			case 0:
+				x = new(big.Int).Neg(limit)
+			case 1:
+				x = new(big.Int).Sub(limit, big.NewInt(1))
+			case 2:
+				x = big.NewInt(-1)
+			}
+			want := make(map[string]*big.Int)
+			for i, d := range used {
+				q, m := new(big.Int).QuoRem(x, big.NewInt(d), new(big.Int))
+				want[fmt.Sprint("q", i+1)] = wrapInt(q, size)
+				want[fmt.Sprint("r", i+1)] = m
+			}
+			checkFields(t, fmt.Sprintf("%d bits, %v", size, x), runProgram(t, source.String(), x), want)
+		}
+	}
+}
+
+// TestIntToBits checks that the bits of an integer give it back
+func TestIntToBits(t *testing.T) {
+	fmt.Println("Starting TestIntToBits")
+	for _, v := range []int64{0, 1, -1, 5, -128, 127, 1000} {
+		bits := intToBits(big.NewInt(v), 16)
+		if got := bitsToInt(bits, true); got.Int64() != v {
+			t.Errorf("the bits of %d give %v", v, got)
+		}
+		if got := bitsToInt(bits, false); got.Int64() != v&0xffff {
+			t.Errorf("the bits of %d read as unsigned give %v", v, got)
+		}
+	}
+}
+
+// TestCheapestReciprocal checks that the quotients of every integer of
+// bound bits and a sign are the products by the reciprocals, periodic ones
+// included
+func TestCheapestReciprocal(t *testing.T) {
+	fmt.Println("Starting TestCheapestReciprocal")
+	periodic := 0
+	for bound := 6; bound <= 12; bound++ {
+		for a := int64(3); a < 200; a++ {
+			if a&(a-1) == 0 {
+				continue
+			}
+			r := cheapestReciprocal(big.NewInt(a), bound, bound+1)
+			m := r.M.Int64()
+			if r.repeats != 0 {
+				periodic++
+				block := m
+				for i := 1; i < r.repeats; i++ {
+					m = m<<uint(r.period) + block
+				}
+				m += block
+			}
+			// a negative quotient is rounded up as in quotientByReciprocal
+			for x := -int64(1) << uint(bound); x < 1<<uint(bound); x++ {
+				q := x * m >> uint(r.p)
+				if x < 0 {
+					q++
+				}
+				if q != x/a {
+					t.Fatalf("%d / %d is %d with the reciprocal %+v for %d bits", x, a, q, r, bound)
+				}
+			}
+		}
+	}
+	if periodic == 0 {
+		t.Error("no periodic reciprocal was chosen")
+	}
+}
@@ -65,6 +65,8 @@ func outExpressionNode(n ast.Expression, fc vb.FunctionContext) vb.VarInterface
 			return outShiftLeftNode(exp, fc)
 		case tk.SHIFT_RIGHT:
 			return outShiftRightNode(exp, fc)
+		case tk.UNSIGNED_SHIFT_RIGHT:
+			return outUnsignedShiftRightNode(exp, fc)
 		case tk.LOGICAL_AND:
 			return outLogicalANDNode(exp, fc)
 		case tk.LOGICAL_OR:
@@ -86,6 +88,10 @@ func outExpressionNode(n ast.Expression, fc vb.FunctionContext) vb.VarInterface
 			return outGetWireNode(exp.ArgumentList[0], exp.ArgumentList[1], fc)
 		case "SetWire":
 			return outSetWireNode(exp.ArgumentList[0], exp.ArgumentList[1], exp.ArgumentList[2], fc)
+		case "Sort", "SortBy":
+			return outSortNode(exp, fc)
+		case "CompareExchange", "CompareExchangeBy":
+			return outCompareExchangeNode(exp, fc)
 		default:
 			return outCallExpression(exp, fc)
 		}
@@ -127,7 +133,7 @@ func outExpressionNode(n ast.Expression, fc vb.FunctionContext) vb.VarInterface
 
 	case *ast.UnaryExpression:
 		switch exp.Operator {
-		case tk.NOT:
+		case tk.NOT, tk.BITWISE_NOT:
 			return outUnaryNOTNode(exp, fc)
 		case tk.MINUS:
 			return outUnaryMinusNode(exp, fc)
@@ -219,11 +225,7 @@ func outArithMultNode(n *ast.BinaryExpression, fc vb.FunctionContext) vb.VarInte
 	destv.FillInWires(&pool)
 
 	// outputting the circuit
-	if t.IsIntType() {
-		outputMultSigned(leftv.WSet(), rightv.WSet(), destv.Wires)
-	} else if t.IsUIntType() {
-		outputMultUnsigned(leftv.WSet(), rightv.WSet(), destv.Wires)
-	}
+	outputMult(leftv.WSet(), rightv.WSet(), destv.Wires, t.IsIntType())
 
 	cleanUpBinaryInt(leftv, rightv, destv)
 	return destv
@@ -243,11 +245,7 @@ func outArithModuloNode(n *ast.BinaryExpression, fc vb.FunctionContext) vb.VarIn
 	destv.FillInWires(&pool)
 
 	// outputting the circuit
-	if t.IsIntType() {
-		outputDivideSigned(leftv.WSet(), rightv.WSet(), destv.Wires, true)
-	} else if t.IsUIntType() {
-		outputDivideUnsigned(leftv.WSet(), rightv.WSet(), destv.Wires, true)
-	}
+	outputDivide(leftv.WSet(), rightv.WSet(), destv.Wires, t.IsIntType(), true)
 
 	cleanUpBinaryInt(leftv, rightv, destv)
 	return destv
@@ -267,11 +265,7 @@ func outArithDivNode(n *ast.BinaryExpression, fc vb.FunctionContext) vb.VarInter
 	destv.FillInWires(&pool)
 
 	// outputting the circuit
-	if t.IsIntType() {
-		outputDivideSigned(leftv.WSet(), rightv.WSet(), destv.Wires, false)
-	} else if t.IsUIntType() {
-		outputDivideUnsigned(leftv.WSet(), rightv.WSet(), destv.Wires, false)
-	}
+	outputDivide(leftv.WSet(), rightv.WSet(), destv.Wires, t.IsIntType(), false)
 
 	cleanUpBinaryInt(leftv, rightv, destv)
 	return destv
@@ -371,22 +365,14 @@ func outShiftLeftNode(n *ast.BinaryExpression, fc vb.FunctionContext) vb.VarInte
 	leftv := outExpressionNode(n.Left, fc).(vb.IntVariable)
 	rightv := outExpressionNode(n.Right, fc).(vb.IntVariable)
 
-	if leftv.IsExt() {
+	if leftv.IsExt() && rightv.IsExt() {
 		return vb.SimpleExtInt(leftv.Val() << uint(rightv.Val()))
 	}
 	destv := vb.NewIntVariable(leftv.GetType(), "<<OP")
 	destv.FillInWires(&pool)
-	lsize := leftv.Size()
-	shift := typ.Num(rightv.Val())
 
 	// shifting
-	for i := typ.Num(0); i < shift && i < lsize; i++ {
-		assignWire(destv.Wires[i], W_0)
-	}
-	for i := typ.Num(0); i+shift < lsize; i++ {
-		assignWire(destv.Wires[i+shift], leftv.GetWire(i))
-		makeWireContainValueNoONEZEROcopy(destv.Wires[i+shift])
-	}
+	outputShift(leftv.WSet()[:leftv.Size()], rightv.WSet(), destv.Wires, true, W_0)
 
 	cleanUpAny(leftv, rightv, destv)
 	return destv
@@ -398,28 +384,20 @@ func outRotateLeftNode(left, right ast.Expression, fc vb.FunctionContext) vb.Var
 		fmt.Println("\tStarting outRotateLeftNode")
 	}
 	// preparation
-	leftv := outExpressionNode(left, fc).(*vb.RegularInt)
+	leftv := outExpressionNode(left, fc).(vb.IntVariable)
 	rightv := outExpressionNode(right, fc).(vb.IntVariable)
 	destv := vb.NewIntVariable(leftv.GetType(), "<<>OP")
 	destv.FillInWires(&pool)
 
 	// rotating
-	lsize := int(leftv.Size())
-	if leftv.IsConst() {
-		for i, w := range leftv.Wires {
-			assignWire(destv.Wires[(i+rightv.Val())%lsize], w)
-		}
-	} else {
-		for i, w := range leftv.Wires {
-			assignWire(destv.Wires[(i+rightv.Val())%lsize], w)
-			makeWireContainValueNoONEZEROcopy(destv.Wires[(i+rightv.Val())%lsize])
-		}
-	}
+	outputRotate(leftv.WSet()[:leftv.Size()], rightv.WSet(), destv.Wires)
+
 	cleanUpAny(leftv, rightv, destv)
 	return destv
 }
 
-// outShiftRightNode is used for the output in case of a ">>" operator
+// outShiftRightNode is used for the output in case of a ">>" operator, which
+// fills a signed integer with its sign
 func outShiftRightNode(n *ast.BinaryExpression, fc vb.FunctionContext) vb.VarInterface {
 	if debug {
 		fmt.Println("\tStarting outShiftRightNode")
@@ -428,24 +406,41 @@ func outShiftRightNode(n *ast.BinaryExpression, fc vb.FunctionContext) vb.VarInt
 	leftv := outExpressionNode(n.Left, fc).(vb.IntVariable)
 	rightv := outExpressionNode(n.Right, fc).(vb.IntVariable)
 
-	if leftv.IsExt() {
+	if leftv.IsExt() && rightv.IsExt() {
 		return vb.SimpleExtInt(leftv.Val() >> uint(rightv.Val()))
 	}
-	leftv = leftv.(*vb.RegularInt)
 	destv := vb.NewIntVariable(leftv.GetType(), ">>OP")
 	destv.FillInWires(&pool)
-	lsize := leftv.Size()
-	shift := typ.Num(rightv.Val())
+	fill := W_0
+	if leftv.GetType().IsIntType() {
+		fill = leftv.GetWire(leftv.Size() - 1)
+	}
 
 	// shifting
-	i := typ.Num(0)
-	for ; i < lsize-shift; i++ {
-		assignWire(destv.Wires[i], leftv.GetWire(i+shift))
-		makeWireContainValue(destv.Wires[i])
+	outputShift(leftv.WSet()[:leftv.Size()], rightv.WSet(), destv.Wires, false, fill)
+
+	cleanUpAny(leftv, rightv, destv)
+	return destv
+}
+
+// outUnsignedShiftRightNode is used for the output in case of a ">>>" operator
+func outUnsignedShiftRightNode(n *ast.BinaryExpression, fc vb.FunctionContext) vb.VarInterface {
+	if debug {
+		fmt.Println("\tStarting outUnsignedShiftRightNode")
 	}
-	for ; i < lsize; i++ {
-		assignWire(destv.Wires[i], W_0)
+	// preparation
+	leftv := outExpressionNode(n.Left, fc).(vb.IntVariable)
+	rightv := outExpressionNode(n.Right, fc).(vb.IntVariable)
+
+	if leftv.IsExt() && rightv.IsExt() {
+		bits := uint64(leftv.Val()) & (1<<uint(leftv.Size()) - 1)
+		return vb.SimpleExtInt(int(bits >> uint(rightv.Val())))
 	}
+	destv := vb.NewIntVariable(leftv.GetType(), ">>>OP")
+	destv.FillInWires(&pool)
+
+	// shifting
+	outputShift(leftv.WSet()[:leftv.Size()], rightv.WSet(), destv.Wires, false, W_0)
 
 	cleanUpAny(leftv, rightv, destv)
 	return destv
@@ -645,7 +640,7 @@ func outConditionalNotEqualNode(n *ast.BinaryExpression, fc vb.FunctionContext)
 /*             Unary operators               */
 /*********************************************/
 
-// outUnaryNOTNode is used for the output in case of a "!" operator
+// outUnaryNOTNode is used for the output in case of a "!" or a "~" operator
 func outUnaryNOTNode(n *ast.UnaryExpression, fc vb.FunctionContext) vb.VarInterface {
 	if debug {
 		fmt.Println("\tStarting outUnaryNOTNode")
@@ -942,62 +937,39 @@ func outCallExpression(n *ast.CallExpression, fc vb.FunctionContext) vb.VarInter
 		fmt.Println("Callee should be identifier, exiting now.")
 		os.Exit(64)
 	}
-	funcvar := context.FunctionContext[id.Name].(*vb.FunctionVariable)
-
-	//|algorithm:
-	//--|copy parameters to function paramter slots
-	//--|call function
-	//--|copy returnv to CORV v and return
-
-	// copy param
-	for i, arg := range n.ArgumentList {
-		argv := outExpressionNode(arg, fc)
-		paramv := funcvar.Argsv[i]
-		larg := argv.Size()
-		lparam := paramv.Size()
-
-		if argv.IsPerm() {
-			writer.AddMassCopy(vb.Wirebase(paramv), vb.Wirebase(argv), typ.Num(larg))
-		} else {
-			for j := typ.Num(0); j < larg; j++ {
-				w := paramv.GetWire(j)
-				assignWire(w, argv.GetWire(j))
-				makeWireContainValue(w)
-			}
-			if unlockVar(argv) {
-				pool.FreeIfNoRefs()
-			}
-		}
-		if larg < lparam {
-			writer.AddReplicate(W_0.Number, paramv.GetWire(larg).Number, typ.Num(lparam-larg))
-		}
+	retv, executed := callFunction(id.Name, n.ArgumentList, fc)
+	if !executed {
+		return retv
 	}
 
-	writer.AddFunctionCall(funcvar.FunctionNumber)
-
 	//put results intocorv
-	if funcvar.Returnv != nil {
+	if retv != nil {
 		// get or create variable and add to scope
 		var rvar vb.VarInterface
 		counter := 0
 
 		for true {
-			rvar = fc[string(counter)+"-+r+"+id.Name]
+			// the instances of a function may return different types
+			name := fmt.Sprint(counter) + "-+r+" + id.Name + retv.GetType().Name()
+			rvar = fc[name]
 
 			if rvar == nil {
 				//create
-				name := string(counter) + "-+r+" + id.Name
-				rvar = vb.VarFromType(funcvar.Returnv.GetType(), name)
+				rvar = vb.VarFromType(retv.GetType(), name)
 				rvar.FillInWires(&pool)
 				rvar.Lock()
 				fc[name] = rvar
 				break
 			} else if !rvar.GetWire(0).Locked {
+				// the wires of the previous result may have been freed and
+				// given to another variable since
+				rvar.FillInWires(&pool)
+				rvar.Lock()
 				break
 			}
 			counter++
 		}
-		messyAssignAndCopy(funcvar.Returnv, rvar)
+		messyAssignAndCopy(retv, rvar)
 		return rvar
 	}
 	return nil
@@ -1024,7 +996,17 @@ func outVariableExpression(n *ast.VariableExpression, fc vb.FunctionContext) vb.
 				return v
 			}
 		}
-		messyAssignAndCopy(rv, v)
+		if ifvar := fc["-+IFCOND+-"]; ifvar != nil && rv.Size() == v.Size() {
+			// as an assignment, a declaration in an if statement is conditional
+			cond := ifvar.GetWire(0)
+			for i := typ.Num(0); i < v.Size(); i++ {
+				w := v.GetWire(i)
+				assignWireCond(w, rv.GetWire(i), cond)
+				makeWireContainValueNoONEZEROcopy(w)
+			}
+		} else {
+			messyAssignAndCopy(rv, v)
+		}
 		rv.Unlock()
 	} else {
 		fmt.Println("Error in outVariableExpression: unknown variable", n.Name)
@@ -1045,58 +1027,119 @@ func outIdentifier(n *ast.Identifier, fc vb.FunctionContext) vb.VarInterface {
 	return nil
 }
 
-// outCallAndAssign is used when we call a function and directly assign the result
-// Warning: this is not in Frigate, it might not work in several cases, in particular when used in conditions
-func outCallAndAssign(n *ast.AssignExpression, fc vb.FunctionContext) vb.VarInterface {
-	callExp := n.Right.(*ast.CallExpression)
-	if debug {
-		fmt.Println("\tStarting outCallAndAssign with callee", callExp.Callee.(*ast.Identifier).Name)
+// callFunction outputs a call to a user function, which is compiled for the
+// types and the known values of its arguments, and returns the variable of the
+// result. When the call is deeper than the $maxdepth of its recursion, it is
+// not executed, the result is a locked variable of value 0 and the global
+// variable truncated is set.
+func callFunction(name string, arguments []ast.Expression, fc vb.FunctionContext) (vb.VarInterface, bool) {
+	args := make([]vb.VarInterface, len(arguments))
+	for i, arg := range arguments {
+		argv := outExpressionNode(arg, fc)
+		if argv.IsPerm() && hasSideEffects(arguments[i+1:]) {
+			// the next arguments may change the variable given
+			tmp := vb.VarFromType(argv.GetType(), "ARG")
+			tmp.FillInWires(&pool)
+			for j := typ.Num(0); j < argv.Size(); j++ {
+				assignWire(tmp.GetWire(j), argv.GetWire(j))
+			}
+			tmp.Lock()
+			argv = tmp
+		}
+		args[i] = argv
 	}
-	id, ok := callExp.Callee.(*ast.Identifier)
-	if !ok {
-		fmt.Println("Callee should be identifier, exiting now.")
-		os.Exit(64)
+	inst := calledFunction(name, args)
+	if inst == nil {
+		markTruncated(fc)
+		for _, argv := range args {
+			unlockVar(argv)
+		}
+		pool.FreeIfNoRefs()
+		return zeroResult(name, args), false
 	}
-	funcvar := context.FunctionContext[id.Name].(*vb.FunctionVariable)
-
-	//|algorithm:
-	//--|copy parameters to function paramter slots
-	//--|call function
-	//--|copy returnv to CORV v and return
-
-	for i, arg := range callExp.ArgumentList {
-		argv := outExpressionNode(arg, fc)
-		paramv := funcvar.Argsv[i]
-		larg := argv.Size()
-		lparam := paramv.Size()
 
+	// copy param
+	for i, argv := range args {
+		paramv := inst.fv.Argsv[i]
+		if inst.given[i] {
+			// the instance is compiled with the value of the argument
+			unlockVar(argv)
+			continue
+		}
 		if argv.IsPerm() {
-			writer.AddMassCopy(vb.Wirebase(paramv), vb.Wirebase(argv), typ.Num(larg))
+			flushVar(argv)
+			writer.AddMassCopy(vb.Wirebase(paramv), vb.Wirebase(argv), argv.Size())
 		} else {
-			for j := typ.Num(0); j < larg; j++ {
+			for j := typ.Num(0); j < argv.Size(); j++ {
 				w := paramv.GetWire(j)
 				assignWire(w, argv.GetWire(j))
 				makeWireContainValue(w)
 			}
-			if unlockVar(argv) {
-				pool.FreeIfNoRefs()
-			}
+			unlockVar(argv)
 		}
-		if larg < lparam {
-			writer.AddReplicate(W_0.Number, paramv.GetWire(larg).Number, typ.Num(lparam-larg))
+	}
+	pool.FreeIfNoRefs()
+
+	saved := beforeCall(name, fc)
+	writer.AddFunctionCall(inst.fv.FunctionNumber)
+	afterCall(name, fc, saved)
+	return inst.fv.Returnv, true
+}
+
+// sideEffectVisitor finds the expressions which change variables
+type sideEffectVisitor struct {
+	found bool
+}
+
+func (sv *sideEffectVisitor) Enter(n ast.Node) ast.Visitor {
+	switch e := n.(type) {
+	case *ast.AssignExpression, *ast.CallExpression:
+		sv.found = true
+	case *ast.UnaryExpression:
+		if e.Operator == tk.INCREMENT || e.Operator == tk.DECREMENT {
+			sv.found = true
 		}
 	}
+	if sv.found {
+		return nil
+	}
+	return sv
+}
+
+func (sv *sideEffectVisitor) Exit(n ast.Node) {}
+
+// hasSideEffects tells if the evaluation of some expressions may change
+// variables
+func hasSideEffects(exps []ast.Expression) bool {
+	sv := &sideEffectVisitor{}
+	for _, exp := range exps {
+		ast.Walk(sv, exp)
+	}
+	return sv.found
+}
 
-	writer.AddFunctionCall(funcvar.FunctionNumber)
+// outCallAndAssign is used when we call a function and directly assign the result.
+// In an if statement the result is assigned only when the condition holds.
+func outCallAndAssign(n *ast.AssignExpression, fc vb.FunctionContext) vb.VarInterface {
+	callExp := n.Right.(*ast.CallExpression)
+	if debug {
+		fmt.Println("\tStarting outCallAndAssign with callee", callExp.Callee.(*ast.Identifier).Name)
+	}
+	id, ok := callExp.Callee.(*ast.Identifier)
+	if !ok {
+		fmt.Println("Callee should be identifier, exiting now.")
+		os.Exit(64)
+	}
+	retv, executed := callFunction(id.Name, callExp.ArgumentList, fc)
 
 	leftv := outExpressionNode(n.Left, fc)
 
-	if funcvar.Returnv != nil {
+	if retv != nil {
 		ifvar := fc["-+IFCOND+-"]
 		if ifvar == nil {
 			for i := typ.Num(0); i < leftv.Size(); i++ {
 				w1 := leftv.GetWire(i)
-				w2 := funcvar.Returnv.GetWire(i)
+				w2 := retv.GetWire(i)
 
 				if w1.Refs() > 0 && !(w2.Other == w1 && w1.Refs() == 1) {
 					clearReffedWire(w1)
@@ -1109,7 +1152,7 @@ func outCallAndAssign(n *ast.AssignExpression, fc vb.FunctionContext) vb.VarInte
 
 			for i := typ.Num(0); i < leftv.Size(); i++ {
 				w1 := leftv.GetWire(i)
-				w2 := funcvar.Returnv.GetWire(i)
+				w2 := retv.GetWire(i)
 				if w1.Refs() > 0 {
 					clearReffedWire(w1)
 				}
@@ -1118,6 +1161,10 @@ func outCallAndAssign(n *ast.AssignExpression, fc vb.FunctionContext) vb.VarInte
 			}
 		}
 	}
+	if !executed {
+		unlockVar(retv)
+		pool.FreeIfNoRefs()
+	}
 	return leftv
 }
 
@@ -0,0 +1,295 @@
+package compiler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+/*
+ * A program can be split across several files: a file imports the functions
+ * of another one with a directive on its own line, among the comments which
+ * start the file, such as
+ *
+ *	// @import "lib/math.js"
+ *	// @import "../common/matrix.js" as mat
+ *
+ * the path being relative to the directory of the importing file, and ".js"
+ * being added to a path without extension. The functions of an imported file
+ * are called through its namespace, the name of the file without extension
+ * unless another one is given after "as":
+ *
+ *	var m = math.max(a, b)
+ *
+ * An imported file only declares functions, and may import other files. Its
+ * functions are added to the program under the name namespace.function, so
+ * that they do not clash with the ones of the other files. A file imported by
+ * several files is added once, and a file importing itself, even through
+ * other files, is an error.
+ */
+
+var importDirective = regexp.MustCompile(`^\s*//\s*@import\s+"([^"]*)"(?:\s+as\s+(\S+))?\s*$`)
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// module is a file of a program
+type module struct {
+	path       string
+	prog       *ast.Program
+	prefix     string             // prefix of the names of its functions, empty for the main file
+	funcs      map[string]bool    // the functions it declares
+	namespaces map[string]*module // the files it imports, by namespace
+}
+
+// importer loads the files of a program
+type importer struct {
+	fs       *file.FileSet
+	modules  map[string]*module // the files loaded, by absolute path
+	loading  []string           // the files being loaded, the innermost last
+	prefixes map[string]bool
+	order    []*module // the files loaded, each one after the files it imports
+}
+
+// ParseProgram parses a JavaScript file and the files it imports, and returns
+// a single program declaring the functions of all of them
+func ParseProgram(path string) (*ast.Program, error) {
+	prog, _, err := parseProgram(path)
+	return prog, err
+}
+
+// parseProgram returns the program of ParseProgram along with its source
+// files, the main file first
+func parseProgram(path string) (*ast.Program, []*file.File, error) {
+	im := &importer{fs: new(file.FileSet), modules: make(map[string]*module), prefixes: make(map[string]bool)}
+	main, err := im.load(path, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range im.order {
+		r := &renamer{m: m}
+		for _, st := range m.prog.Body {
+			ast.Walk(r, st)
+		}
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+	}
+	if len(im.order) == 1 {
+		return main.prog, []*file.File{main.prog.File}, nil
+	}
+
+	files := []*file.File{main.prog.File}
+	for _, m := range im.order {
+		if m == main {
+			continue
+		}
+		for _, dec := range m.prog.DeclarationList {
+			f := dec.(*ast.FunctionDeclaration).Function
+			f.Name.Name = m.prefix + "." + f.Name.Name
+		}
+		main.prog.DeclarationList = append(main.prog.DeclarationList, m.prog.DeclarationList...)
+		main.prog.Body = append(main.prog.Body, m.prog.Body...)
+		files = append(files, m.prog.File)
+	}
+	return main.prog, files, nil
+}
+
+// load parses a file and the files it imports
+func (im *importer) load(path string, main bool) (*module, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range im.loading {
+		if p == abs {
+			cycle := append(append([]string{}, im.loading[i:]...), abs)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if m, ok := im.modules[abs]; ok {
+		return m, nil
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := parser.ParseFile(im.fs, path, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	m := &module{path: path, prog: prog, funcs: make(map[string]bool), namespaces: make(map[string]*module)}
+	if !main {
+		m.prefix = im.newPrefix(path)
+	}
+	im.modules[abs] = m
+
+	vars := make(map[string]bool)
+	for _, dec := range prog.DeclarationList {
+		switch d := dec.(type) {
+		case *ast.FunctionDeclaration:
+			if m.funcs[d.Function.Name.Name] {
+				return nil, m.errorAt(d.Function.Idx0(), "the function "+d.Function.Name.Name+" is declared twice")
+			}
+			m.funcs[d.Function.Name.Name] = true
+		case *ast.VariableDeclaration:
+			for _, v := range d.List {
+				vars[v.Name] = true
+			}
+		}
+	}
+	if !main {
+		for _, st := range prog.Body {
+			switch st.(type) {
+			case *ast.FunctionStatement, *ast.EmptyStatement:
+			default:
+				return nil, m.errorAt(st.Idx0(), "an imported file can only declare functions")
+			}
+		}
+	}
+
+	im.loading = append(im.loading, abs)
+	imported := make(map[*module]string)
+	for i, line := range leadingComments(string(src)) {
+		match := importDirective.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		at := fmt.Sprintf("%s:%d: ", path, i+1)
+		target := filepath.Join(filepath.Dir(path), match[1])
+		if filepath.Ext(target) == "" {
+			target += ".js"
+		}
+		ns := match[2]
+		if ns == "" {
+			ns = strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+		}
+		if !identifier.MatchString(ns) {
+			return nil, fmt.Errorf("%sthe namespace %s of %s is not an identifier, name it with as", at, ns, match[1])
+		}
+		if _, ok := m.namespaces[ns]; ok {
+			return nil, fmt.Errorf("%sthe namespace %s is given to two imports", at, ns)
+		}
+		if m.funcs[ns] || vars[ns] {
+			return nil, fmt.Errorf("%sthe namespace %s is also the name of a variable or function", at, ns)
+		}
+		if _, err := os.Stat(target); err != nil {
+			return nil, fmt.Errorf("%s%v", at, err)
+		}
+		dep, err := im.load(target, false)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := imported[dep]; ok {
+			return nil, fmt.Errorf("%s%s is already imported as %s", at, match[1], other)
+		}
+		imported[dep] = ns
+		m.namespaces[ns] = dep
+	}
+	im.loading = im.loading[:len(im.loading)-1]
+	im.order = append(im.order, m)
+	return m, nil
+}
+
+// leadingComments returns the line comments and the empty lines which start
+// a source, up to its first line of code, indexed by line. The lines of the
+// block comments among them are left empty, so that they hold no directive.
+func leadingComments(src string) []string {
+	lines := make([]string, 0)
+	inBlock := false
+	for _, line := range strings.Split(src, "\n") {
+		rest := strings.TrimSpace(line)
+		if !inBlock && strings.HasPrefix(rest, "/*") {
+			inBlock, rest = true, rest[2:]
+		}
+		if inBlock {
+			end := strings.Index(rest, "*/")
+			if end < 0 {
+				lines = append(lines, "")
+				continue
+			}
+			inBlock = false
+			rest = strings.TrimSpace(rest[end+2:])
+			line = rest
+		}
+		if rest != "" && !strings.HasPrefix(rest, "//") {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// newPrefix returns the prefix of the functions of an imported file, named
+// after the file
+func (im *importer) newPrefix(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	prefix := base
+	for i := 2; im.prefixes[prefix]; i++ {
+		prefix = fmt.Sprintf("%s_%d", base, i)
+	}
+	im.prefixes[prefix] = true
+	return prefix
+}
+
+func (m *module) errorAt(idx file.Idx, msg string) error {
+	if pos := m.prog.File.Position(idx); pos != nil {
+		return fmt.Errorf("%s:%d: %s", m.path, pos.Line, msg)
+	}
+	return fmt.Errorf("%s: %s", m.path, msg)
+}
+
+// renamer gives to the functions called in a file their name in the program
+type renamer struct {
+	m   *module
+	err error
+}
+
+func (r *renamer) Enter(n ast.Node) ast.Visitor {
+	ce, ok := n.(*ast.CallExpression)
+	if !ok {
+		return r
+	}
+	switch callee := ce.Callee.(type) {
+	case *ast.DotExpression:
+		ns, ok := callee.Left.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		if dep, ok := r.m.namespaces[ns.Name]; ok {
+			name := callee.Identifier.Name
+			if !dep.funcs[name] && r.err == nil {
+				r.err = r.m.errorAt(ns.Idx, "no function "+name+" in "+dep.path+", imported as "+ns.Name)
+			}
+			ce.Callee = &ast.Identifier{Idx: ns.Idx, Name: dep.prefix + "." + name}
+		}
+	case *ast.Identifier:
+		if r.m.prefix != "" && r.m.funcs[callee.Name] {
+			callee.Name = r.m.prefix + "." + callee.Name
+		}
+	}
+	return r
+}
+func (r *renamer) Exit(n ast.Node) {}
+
+// mainSpan returns the span of the main body of a program, which ends with
+// the functions of the files it imports
+func mainSpan(prog *ast.Program) (file.Idx, file.Idx) {
+	var start, end file.Idx
+	for _, st := range prog.Body {
+		if int(st.Idx0()) >= prog.File.Base()+len(prog.File.Source()) {
+			break
+		}
+		if start == 0 {
+			start = st.Idx0()
+		}
+		end = st.Idx1()
+	}
+	return start, end
+}
@@ -0,0 +1,135 @@
+package compiler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// TestImportDirectives checks that the directives are only read in the line
+// comments which start a file, and that the circuit knows the files imported
+func TestImportDirectives(t *testing.T) {
+	fmt.Println("Starting TestImportDirectives")
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib.js": "function twice(x) {\n\treturn x + x\n}\n",
+		"main.js": `/* the directives of block comments are not read
+// @import "missing.js"
+*/
+// @import "lib.js" as l
+
+var $parties = 1
+var $intsize = 8
+var in_0 = 0
+var out_0 = 0
+// @import "missing.js"
+out_0 = l.twice(in_0)
+`,
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prog, err := ParseProgram(filepath.Join(dir, "main.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, dec := range prog.DeclarationList {
+		if d, ok := dec.(*ast.FunctionDeclaration); ok {
+			names = append(names, d.Function.Name.Name)
+		}
+	}
+	if len(names) != 1 || names[0] != "lib.twice" {
+		t.Errorf("the program declares the functions %v instead of lib.twice", names)
+	}
+
+	if _, err := CircuitFromJS(filepath.Join(dir, "main.js")); err != nil {
+		t.Fatal(err)
+	}
+	if sm := GetSourceMap(); len(sm.Files) != 2 {
+		t.Errorf("the source map has %d files instead of 2", len(sm.Files))
+	}
+}
+
+func TestLeadingComments(t *testing.T) {
+	fmt.Println("Starting TestLeadingComments")
+	lines := leadingComments("// a\n\n/* b\nc */ // d\n  // e\nvar x = 0\n// f\n")
+	want := []string{"// a", "", "", "// d", "  // e"}
+	if fmt.Sprintf("%q", lines) != fmt.Sprintf("%q", want) {
+		t.Errorf("the leading comments are %q instead of %q", lines, want)
+	}
+}
+
+// TestImportErrors checks the errors of the imports, the programs being
+// written in the files of main.js and of the files it imports
+func TestImportErrors(t *testing.T) {
+	fmt.Println("Starting TestImportErrors")
+	header := "var $parties = 1\nvar in_0 = 0\nvar out_0 = 0\n"
+	lib := "function f(x) {\n\treturn x\n}\n"
+	cases := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{"cycle", map[string]string{
+			"main.js": "// @import \"a.js\"\n" + header,
+			"a.js":    "// @import \"b.js\"\n" + lib,
+			"b.js":    "// @import \"a.js\"\n" + lib,
+		}, "b.js -> "},
+		{"self import", map[string]string{
+			"main.js": "// @import \"main.js\" as m\n" + header,
+		}, "main.js -> "},
+		{"same namespace", map[string]string{
+			"main.js": "// @import \"a.js\" as l\n// @import \"b.js\" as l\n" + header,
+			"a.js":    lib,
+			"b.js":    lib,
+		}, "main.js:2: the namespace l is given to two imports"},
+		{"namespace of a variable", map[string]string{
+			"main.js": "// @import \"a.js\" as in_0\n" + header,
+			"a.js":    lib,
+		}, "main.js:1: the namespace in_0 is also the name of a variable or function"},
+		{"namespace of a function", map[string]string{
+			"main.js": "// @import \"a.js\"\n" + header + "function a() {\n\treturn 0\n}\n",
+			"a.js":    lib,
+		}, "main.js:1: the namespace a is also the name of a variable or function"},
+		{"function declared twice", map[string]string{
+			"main.js": "// @import \"a.js\"\n" + header,
+			"a.js":    lib + "\n" + lib,
+		}, "a.js:5: the function f is declared twice"},
+		{"same file twice", map[string]string{
+			"main.js": "// @import \"a.js\"\n// @import \"./a.js\" as b\n" + header,
+			"a.js":    lib,
+		}, "main.js:2: ./a.js is already imported as a"},
+		{"statement in an imported file", map[string]string{
+			"main.js": "// @import \"a.js\"\n" + header,
+			"a.js":    lib + "var x = 0\n",
+		}, "a.js:4: an imported file can only declare functions"},
+		{"missing file", map[string]string{
+			"main.js": "// @import \"missing.js\"\n" + header,
+		}, "main.js:1: "},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, src := range c.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := ParseProgram(filepath.Join(dir, "main.js"))
+			if err == nil {
+				t.Fatalf("no error instead of %q", c.err)
+			}
+			if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("the error is %q instead of %q", err, c.err)
+			}
+		})
+	}
+}
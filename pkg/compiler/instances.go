@@ -0,0 +1,421 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+	vb "ixxoprivacy/pkg/variables"
+	wr "ixxoprivacy/pkg/wires"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+/*
+ * A function is compiled once per tuple of types of its arguments and of
+ * values of its arguments known at compile time, that is the dollar variables,
+ * the number literals and the booleans true and false. Each of these instances
+ * is a function of the circuit, compiled the first time it is called, in the
+ * middle of the compilation of its caller. The parameters given a known value
+ * are not copied by the callers: the instance starts with their wires in a
+ * known state, so that the branches which depend on them are not compiled.
+ * The instance called with the types of the arguments of the first call of
+ * the program has the name of the function, the other ones are named after
+ * their arguments, as in
+ *
+ *	first(a [3]int16)
+ *	pow(x int16, $e=3)
+ *
+ * This unrolls the recursions whose depth is bounded by a dollar parameter:
+ * the recursive calls are made with other values of the parameter, until a
+ * condition on it, known at compile time, stops the recursion. A recursion
+ * calling a function with the same values again would never stop, which is
+ * reported as an error.
+ *
+ * A recursive function without dollar parameters declares instead the
+ * maximum number of nested calls of the recursion with
+ *
+ *	var $maxdepth = 8
+ *
+ * and the function is compiled once per depth. The calls deeper than the
+ * bound are not executed and return 0. The program must then declare the
+ * global boolean
+ *
+ *	var truncated = false
+ *
+ * which these calls set to true, under the conditions of the if statements
+ * they are in, so that the program can output whether its result is the one
+ * of the full recursion. Without it, reaching the bound is an error.
+ */
+
+// maxNesting is the number of nested instances from which a recursion is
+// considered not to stop
+const maxNesting = 1000
+
+// truncatedName is the name of the global variable set by the calls deeper
+// than a $maxdepth
+const truncatedName = "truncated"
+
+// instance is a compiled copy of a function
+type instance struct {
+	name  string // name of the function in the program
+	fv    *vb.FunctionVariable
+	given []bool // the parameters whose values are given by the instance
+	depth int    // depth in the recursion, for the functions with a $maxdepth
+	done  bool   // the compilation of the instance is over
+}
+
+var cycles map[string]string // the name of the recursion cycle of each recursive function
+var bounds map[string]int    // the $maxdepth of each recursion cycle
+var instances map[string]*instance
+var compiling []*instance // the instances being compiled, the innermost last
+
+// findRecursions finds the recursive functions of a program and checks that
+// their recursions are bounded. It only reads the declarations, so that a
+// recursion without bound is reported before the types are inferred.
+func findRecursions(prog *ast.Program) {
+	cycles = make(map[string]string)
+	bounds = make(map[string]int)
+	instances = make(map[string]*instance)
+	compiling = nil
+
+	funcs := make(map[string]*ast.FunctionLiteral)
+	names := make([]string, 0)
+	for _, dec := range prog.DeclarationList {
+		if d, ok := dec.(*ast.FunctionDeclaration); ok {
+			funcs[d.Function.Name.Name] = d.Function
+			names = append(names, d.Function.Name.Name)
+		}
+	}
+	sort.Strings(names)
+
+	callees := make(map[string][]string)
+	for _, name := range names {
+		cv := make(calleeVisitor)
+		ast.Walk(cv, funcs[name])
+		for callee := range cv {
+			if _, ok := funcs[callee]; ok {
+				callees[name] = append(callees[name], callee)
+			}
+		}
+	}
+	reach := func(from string) map[string]bool {
+		seen := make(map[string]bool)
+		var visit func(name string)
+		visit = func(name string) {
+			for _, callee := range callees[name] {
+				if !seen[callee] {
+					seen[callee] = true
+					visit(callee)
+				}
+			}
+		}
+		visit(from)
+		return seen
+	}
+
+	for _, name := range names {
+		from := reach(name)
+		if !from[name] {
+			continue
+		}
+		// the cycle is named after the first of its functions
+		cycles[name] = name
+		for callee := range from {
+			if callee < cycles[name] && reach(callee)[name] {
+				cycles[name] = callee
+			}
+		}
+		if d := maxDepth(funcs[name]); d > bounds[cycles[name]] {
+			bounds[cycles[name]] = d
+		}
+	}
+	for _, name := range names {
+		if cycle, ok := cycles[name]; ok && bounds[cycle] == 0 && !hasDollarParams(funcs[name]) {
+			fmt.Println("Error: the depth of the recursive function", name, "cannot be bounded, give it a dollar parameter or declare var $maxdepth = n in it")
+			os.Exit(64)
+		}
+	}
+}
+
+// hasDollarParams tells if a function has dollar parameters
+func hasDollarParams(f *ast.FunctionLiteral) bool {
+	for _, param := range f.ParameterList.List {
+		if strings.HasPrefix(param.Name, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// maxDepth returns the value of the variable $maxdepth declared in a
+// function, or 0
+func maxDepth(f *ast.FunctionLiteral) int {
+	for _, dec := range f.DeclarationList {
+		d, ok := dec.(*ast.VariableDeclaration)
+		if !ok {
+			continue
+		}
+		for _, v := range d.List {
+			if v.Name != "$maxdepth" {
+				continue
+			}
+			if num, ok := v.Initializer.(*ast.NumberLiteral); ok {
+				if val, ok := num.Value.(int64); ok && val > 0 {
+					return int(val)
+				}
+			}
+			fmt.Println("Error: $maxdepth must be declared as a positive number in", f.Name.Name)
+			os.Exit(64)
+		}
+	}
+	return 0
+}
+
+// knownValue returns the value of an argument known at compile time, if any
+func knownValue(argv vb.VarInterface) (string, bool) {
+	switch argv {
+	case vb.TrueV:
+		return "true", true
+	case vb.FalseV:
+		return "false", true
+	}
+	if ev, ok := argv.(*vb.ExtInt); ok {
+		return fmt.Sprint(ev.Val()), true
+	}
+	return "", false
+}
+
+// calledFunction returns the instance of a function called with the given
+// arguments, compiling it when needed. It returns nil when the call is deeper
+// than the $maxdepth of its recursion, and is not executed.
+func calledFunction(name string, args []vb.VarInterface) *instance {
+	fv := context.FunctionContext[name].(*vb.FunctionVariable)
+	if len(args) != len(fv.Argsv) {
+		fmt.Println("Error in call to", name, ": it is given", len(args), "arguments instead of", len(fv.Argsv))
+		os.Exit(64)
+	}
+	if fv.Extern {
+		return &instance{name: name, fv: fv, given: make([]bool, len(args)), done: true}
+	}
+	values := make([]string, len(args))
+	params := make([]*typ.Type, len(args))
+	given := make([]bool, len(args))
+	generic := true
+	for i, param := range fv.FunctionNode.ParameterList.List {
+		params[i] = args[i].GetType()
+		val, known := knownValue(args[i])
+		if strings.HasPrefix(param.Name, "$") {
+			if _, ok := args[i].(*vb.ExtInt); !ok {
+				fmt.Println("Error in call to", name, ": the argument given to the parameter", param.Name, "must be known at compile time")
+				os.Exit(64)
+			}
+		}
+		if known {
+			values[i] = param.Name + "=" + val
+			given[i] = true
+			generic = false
+		} else {
+			values[i] = param.Name + " " + params[i].Name()
+			generic = generic && params[i].Equals(fv.Argsv[i].GetType())
+		}
+	}
+	depth := 0
+	if bound := bounds[cycles[name]]; bound > 0 {
+		if len(compiling) > 0 {
+			if caller := compiling[len(compiling)-1]; cycles[caller.name] == cycles[name] {
+				depth = caller.depth + 1
+			}
+		}
+		if depth >= bound {
+			if !truncates(name) {
+				fmt.Println("Error: the calls of", name, "nested deeper than the $maxdepth of", bound, "are not executed, declare var", truncatedName, "= false to know when they are needed")
+				os.Exit(64)
+			}
+			return nil
+		}
+		values = append(values, fmt.Sprintf("depth=%d", depth))
+		generic = false
+	}
+
+	key := name
+	if !generic {
+		key += "(" + strings.Join(values, ", ") + ")"
+	}
+	if inst, ok := instances[key]; ok {
+		if !inst.done {
+			fmt.Println("Error: the recursion of", name, "does not stop, it calls", key, "again")
+			os.Exit(64)
+		}
+		return inst
+	}
+	if len(compiling) >= maxNesting {
+		fmt.Println("Error: the recursion of", name, "cannot be proven to stop, it goes deeper than", maxNesting, "calls")
+		os.Exit(64)
+	}
+	inst := &instance{name: name, fv: fv, given: given, depth: depth}
+	if !generic {
+		inst.fv = context.NewInstance(name, key, params)
+	}
+	instances[key] = inst
+	compileInstance(inst, key, args)
+	return inst
+}
+
+// compileInstance compiles an instance of a function in the middle of the
+// compilation of its caller, which resumes afterwards as it was
+func compileInstance(inst *instance, key string, args []vb.VarInterface) {
+	fc := context.Funcs[key]
+	names := make([]string, 0, len(fc))
+	for k := range fc {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	next := pool.NextNumber
+	for _, k := range names {
+		v := fc[k]
+		if strings.HasPrefix(k, "$") {
+			if _, ok := v.(*vb.ExtInt); !ok {
+				v.FillInWires(nil)
+			}
+			v.SetConst()
+			continue
+		}
+		v.FillInWires(nil)
+		v.SetPerm()
+		next = v.AssignPermWires(next)
+		for i := typ.Num(0); i < v.Size(); i++ {
+			v.GetWire(i).State = wr.UNKNOWN
+		}
+	}
+	for i, paramv := range inst.fv.Argsv {
+		if !inst.given[i] {
+			continue
+		}
+		if ev, ok := paramv.(*vb.ExtInt); ok {
+			ev.ChangeValue(args[i].(*vb.ExtInt).Val())
+			continue
+		}
+		for j := typ.Num(0); j < paramv.Size(); j++ {
+			paramv.GetWire(j).State = args[i].GetWire(j).State
+		}
+	}
+
+	f := circ.NewFunctionPt()
+	inst.fv.FunctionNumber = typ.Num(len(circuit.Funcs))
+	circuit.Funcs = append(circuit.Funcs, f)
+	node := inst.fv.FunctionNode
+	funcSources[f] = &FuncSource{Name: key, Start: node.Idx0(), End: node.Idx1()}
+
+	// the state of the caller
+	caller, callerPool, callerSource := writer.GetFunction(), pool, currentSource
+	pos := writer.SetPosition(node.Idx0())
+	globals := forgetGlobals()
+
+	writer.ChangeFunction(f)
+	pool = wr.NewWirePool(next)
+	currentSource = funcSources[f]
+	compiling = append(compiling, inst)
+	outFunctionLiteral(node, fc)
+	flushWrittenGlobals(inst.name)
+	compiling = compiling[:len(compiling)-1]
+	inst.done = true
+
+	next = pool.NextNumber
+	writer.ChangeFunction(caller)
+	pool = callerPool
+	pool.NextNumber = next
+	currentSource = callerSource
+	writer.SetPosition(pos)
+	globals.restore()
+}
+
+// savedWire is what the compiler knows of a wire at some point
+type savedWire struct {
+	w     *wr.Wire
+	state wr.WireState
+	other *wr.Wire
+	refs  []*wr.Wire
+}
+
+// globalsState is what the compiler knows of the wires of the global variables
+type globalsState []savedWire
+
+// forgetGlobals returns what the compiler knows of the global variables,
+// which become unknown as at the beginning of a function
+func forgetGlobals() globalsState {
+	gs := make(globalsState, 0)
+	for _, v := range context.FunctionContext {
+		// the main body also keeps its constants and temporary results there
+		if !v.IsPerm() {
+			continue
+		}
+		for i := typ.Num(0); i < v.Size(); i++ {
+			w := v.GetWire(i)
+			gs = append(gs, savedWire{w, w.State, w.Other, w.RefsToMe})
+			w.State, w.Other, w.RefsToMe = wr.UNKNOWN, nil, nil
+		}
+	}
+	return gs
+}
+
+// restore gives back to the wires of the global variables their state
+func (gs globalsState) restore() {
+	for _, s := range gs {
+		s.w.State, s.w.Other, s.w.RefsToMe = s.state, s.other, s.refs
+	}
+}
+
+// truncates tells if the calls of a function deeper than the $maxdepth of
+// its recursion set the global variable truncated
+func truncates(name string) bool {
+	if bounds[cycles[name]] == 0 {
+		return false
+	}
+	v, ok := context.FunctionContext[truncatedName]
+	if !ok {
+		return false
+	}
+	if _, ok := v.(*vb.FunctionVariable); ok || !v.GetType().IsBoolType() {
+		fmt.Println("Error: the variable", truncatedName, "set by the calls deeper than a $maxdepth must be a boolean")
+		os.Exit(64)
+	}
+	return true
+}
+
+// markTruncated sets the global variable truncated in place of a call deeper
+// than the $maxdepth of its recursion
+func markTruncated(fc vb.FunctionContext) {
+	w := context.FunctionContext[truncatedName].GetWire(0)
+	if ifvar := fc["-+IFCOND+-"]; ifvar != nil {
+		assignWireCond(w, vb.TrueV.GetWire(0), ifvar.GetWire(0))
+		makeWireContainValueNoONEZEROcopy(w)
+	} else {
+		assignWire(w, vb.TrueV.GetWire(0))
+		makeWireContainValue(w)
+	}
+	pool.FreeIfNoRefs()
+}
+
+// zeroResult returns the result of a call deeper than the $maxdepth of its
+// recursion, which is not executed and returns 0
+func zeroResult(name string, args []vb.VarInterface) vb.VarInterface {
+	params := make([]*typ.Type, len(args))
+	for i, argv := range args {
+		params[i] = argv.GetType()
+	}
+	t := context.CallType(name, params)
+	if t == nil || t.IsVoid() {
+		return nil
+	}
+	v := vb.VarFromType(t, "SKIPPED")
+	v.FillInWires(&pool)
+	for i := typ.Num(0); i < v.Size(); i++ {
+		v.GetWire(i).State = wr.ZERO
+	}
+	v.Lock()
+	return v
+}
@@ -0,0 +1,94 @@
+package compiler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRecursionErrors compiles the programs of Tests/recursion_errors, whose
+// first line gives the error expected, in a process of their own since the
+// compiler exits on errors
+func TestRecursionErrors(t *testing.T) {
+	if path := os.Getenv("COMPILE_PROGRAM"); path != "" {
+		CircuitFromJS(path)
+		os.Exit(0)
+	}
+	fmt.Println("Starting TestRecursionErrors")
+	paths, err := filepath.Glob("../../Tests/recursion_errors/*.js")
+	if err != nil || len(paths) == 0 {
+		t.Fatal("no program in Tests/recursion_errors", err)
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first := strings.SplitN(string(src), "\n", 2)[0]
+			if !strings.HasPrefix(first, "// error: ") {
+				t.Fatal("the program does not start with // error: ")
+			}
+			want := strings.TrimPrefix(first, "// error: ")
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestRecursionErrors$")
+			cmd.Env = append(os.Environ(), "COMPILE_PROGRAM="+path)
+			out, err := cmd.CombinedOutput()
+			if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 64 {
+				t.Errorf("the compilation ended with %v instead of the exit status 64", err)
+			}
+			if !strings.Contains(string(out), want) {
+				t.Errorf("the compiler printed\n%s\ninstead of %q", out, want)
+			}
+		})
+	}
+}
+
+// TestTruncated checks that the calls deeper than a $maxdepth set truncated,
+// in the if statements they are in only
+func TestTruncated(t *testing.T) {
+	fmt.Println("Starting TestTruncated")
+	source := `var $parties = 2
+var $intsize = 8
+var in_0 = 0
+var in_1 = 0
+var out_0 = {n: 0, truncated: false}
+var truncated = false
+
+function count(x) {
+	var $maxdepth = 4
+	var n = 0
+	if (x != 0) {
+		n = 1 + count(x >>> 1)
+	}
+	return n
+}
+
+if (in_1 != 0) {
+	out_0.n = count(in_0)
+}
+out_0.truncated = truncated
+`
+	for _, x := range []int64{0, 1, 7, 8, 15, -56} {
+		for _, called := range []int64{0, 1} {
+			length := bits.Len8(uint8(x))
+			n, truncated := 0, false
+			if called != 0 {
+				n, truncated = length, length >= 4
+				if truncated {
+					n = 4
+				}
+			}
+			got := runProgram(t, source, big.NewInt(x), big.NewInt(called))
+			if got["n"] != fmt.Sprint(n) || got["truncated"] != fmt.Sprint(truncated) {
+				t.Errorf("count(%d) called %d times gives %v instead of n %d and truncated %v", x, called, got, n, truncated)
+			}
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/stdlib"
+	typ "ixxoprivacy/pkg/types"
+	vb "ixxoprivacy/pkg/variables"
+	wr "ixxoprivacy/pkg/wires"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+var libraries []circ.Library // libraries whose functions can be called by the programs compiled
+
+// AddLibrary makes the functions of a library callable from the programs
+// compiled afterwards. Functions declared in a program hide the functions
+// of the libraries with the same name.
+func AddLibrary(lib circ.Library) {
+	libraries = append(libraries, lib)
+}
+
+// ClearLibraries forgets the libraries added with AddLibrary
+func ClearLibraries() {
+	libraries = nil
+}
+
+// findObject returns the object of a function of the libraries, or of the
+// standard library built for the integers of the circuit, or nil
+func findObject(name string) *circ.Object {
+	for _, lib := range libraries {
+		if o := lib.Find(name); o != nil {
+			return o
+		}
+	}
+	o, err := stdlib.Object(name, circuit.IntSize)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(64)
+	}
+	return o
+}
+
+// calleeVisitor collects the names of the functions called in a program
+type calleeVisitor map[string]bool
+
+func (cv calleeVisitor) Enter(n ast.Node) ast.Visitor {
+	if ce, ok := n.(*ast.CallExpression); ok {
+		if id, ok := ce.Callee.(*ast.Identifier); ok {
+			cv[id.Name] = true
+		}
+	}
+	return cv
+}
+func (cv calleeVisitor) Exit(n ast.Node) {}
+
+// externFunctions returns the variables of the functions of the libraries
+// called by a program, sorted by name
+func externFunctions(prog *ast.Program) []*vb.FunctionVariable {
+	cv := make(calleeVisitor)
+	ast.Walk(cv, prog)
+	for _, dec := range prog.DeclarationList {
+		if d, ok := dec.(*ast.FunctionDeclaration); ok {
+			delete(cv, d.Function.Name.Name)
+		}
+	}
+	names := make([]string, 0)
+	for name := range cv {
+		if findObject(name) != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	externs := make([]*vb.FunctionVariable, 0)
+	for _, name := range names {
+		o := findObject(name)
+		params := make([]*typ.Type, len(o.Params))
+		for i, p := range o.Params {
+			params[i] = p.Type
+		}
+		rt := vb.GetVoidType()
+		if o.Return != nil {
+			rt = o.Return.Type
+		}
+		externs = append(externs, vb.NewExternFunctionVariable(name, params, rt))
+	}
+	return externs
+}
+
+// externCallVisitor checks the types of the arguments of the calls to the
+// functions of the libraries against the types of their parameters
+type externCallVisitor struct {
+	objects map[string]*circ.Object
+	caller  vb.FunctionContext
+	err     error
+}
+
+func (ev *externCallVisitor) Enter(n ast.Node) ast.Visitor {
+	if ev.err != nil {
+		return nil
+	}
+	switch e := n.(type) {
+	case *ast.FunctionLiteral:
+		// the arguments are typed in the context of the function calling
+		inner := &externCallVisitor{objects: ev.objects, caller: context.Funcs[e.Name.Name]}
+		if inner.caller != nil {
+			ast.Walk(inner, e.Body)
+		}
+		ev.err = inner.err
+		return nil
+	case *ast.CallExpression:
+		id, ok := e.Callee.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		o, ok := ev.objects[id.Name]
+		if !ok {
+			break
+		}
+		if len(e.ArgumentList) != len(o.Params) {
+			ev.err = fmt.Errorf("%s is called with %d arguments but its object takes %d", id.Name, len(e.ArgumentList), len(o.Params))
+			return nil
+		}
+		for i, arg := range e.ArgumentList {
+			t := ev.caller.GetNodeType(arg)
+			if t != nil && !t.Equals(o.Params[i].Type) {
+				ev.err = fmt.Errorf("the argument %d of a call to %s is of type %s but its object takes %s", i+1, id.Name, t.Name(), o.Params[i].Type.Name())
+				return nil
+			}
+		}
+	}
+	return ev
+}
+func (ev *externCallVisitor) Exit(n ast.Node) {}
+
+// linkExterns links the objects of the library functions called by the
+// program into the circuit, and binds the parameters and return values of
+// their variables to the wires of the objects. The objects must be compiled
+// for the $intsize of the program, and take the types of the arguments the
+// program gives them.
+func linkExterns(prog *ast.Program, externs []*vb.FunctionVariable) error {
+	objects := make(map[string]*circ.Object)
+	for _, fv := range externs {
+		o := findObject(fv.GetName())
+		if o.IntSize != circuit.IntSize {
+			return fmt.Errorf("the object of %s is compiled for a $intsize of %d instead of %d", o.Name, o.IntSize, circuit.IntSize)
+		}
+		objects[fv.GetName()] = o
+	}
+	ev := &externCallVisitor{objects: objects, caller: context.FunctionContext}
+	ast.Walk(ev, prog)
+	if ev.err != nil {
+		return ev.err
+	}
+
+	for _, fv := range externs {
+		o := objects[fv.GetName()]
+		first := len(circuit.Funcs)
+		l, err := circuit.Link(o, nextBaseWire)
+		if err != nil {
+			return err
+		}
+		nextBaseWire += o.Wires - circ.FirstRelocatableWire
+		for _, f := range circuit.Funcs[first:] {
+			funcSources[f] = &FuncSource{Name: o.Name}
+		}
+
+		fv.FunctionNumber = l.Function
+		bind := func(v vb.VarInterface, cv *circ.Var) {
+			v.FillInWires(nil)
+			v.SetPerm()
+			v.AssignPermWires(cv.Wirebase)
+			for i := typ.Num(0); i < v.Size(); i++ {
+				v.GetWire(i).State = wr.UNKNOWN
+			}
+		}
+		for i, av := range fv.Argsv {
+			bind(av, l.Params[i])
+		}
+		if fv.Returnv != nil {
+			bind(fv.Returnv, l.Return)
+		}
+	}
+	return nil
+}
+
+// ObjectsFromJS compiles a JavaScript file and returns the library made of
+// the functions of the given names. The types of the parameters of the
+// functions are given by their calls in the program, as usual.
+// The functions must not use global variables.
+func ObjectsFromJS(path string, names ...string) (circ.Library, error) {
+	C, err := CircuitFromJS(path)
+	if err != nil {
+		return circ.Library{}, err
+	}
+	lib := circ.Library{Objects: make([]*circ.Object, 0)}
+	usages := C.Usages()
+	for _, name := range names {
+		fv, ok := context.FunctionContext[name].(*vb.FunctionVariable)
+		if !ok || fv.Extern {
+			return lib, fmt.Errorf("no function %s in %s", name, path)
+		}
+		o, err := newObject(C, usages, fv)
+		if err != nil {
+			return lib, err
+		}
+		lib.Objects = append(lib.Objects, o)
+	}
+	return lib, nil
+}
+
+// newObject extracts the object of a function from a compiled circuit
+func newObject(C circ.Circuit, usages []circ.Usage, fv *vb.FunctionVariable) (*circ.Object, error) {
+	o := &circ.Object{Name: fv.GetName(), Params: make([]*circ.Var, 0), IntSize: C.IntSize}
+
+	// the wires of the interface come first
+	renum := map[typ.Num]typ.Num{W_0.Number: 0, W_1.Number: 1}
+	next := circ.FirstRelocatableWire
+	number := func(w typ.Num) typ.Num {
+		if n, ok := renum[w]; ok {
+			return n
+		}
+		renum[w] = next
+		next++
+		return renum[w]
+	}
+	interfaceVar := func(v vb.VarInterface) *circ.Var {
+		cv := &circ.Var{Type: v.GetType(), Wirebase: next}
+		for i := typ.Num(0); i < v.Size(); i++ {
+			number(v.GetWire(i).Number)
+		}
+		return cv
+	}
+	for _, av := range fv.Argsv {
+		o.Params = append(o.Params, interfaceVar(av))
+	}
+	for w := range usages[fv.FunctionNumber].Reads {
+		if _, ok := renum[w]; !ok {
+			return nil, fmt.Errorf("function %s reads wire %d which is not one of its parameters", o.Name, w)
+		}
+	}
+	if fv.Returnv != nil {
+		o.Return = interfaceVar(fv.Returnv)
+	}
+
+	// the functions called, in the order in which they are found
+	index := map[typ.Num]typ.Num{fv.FunctionNumber: 0}
+	order := []typ.Num{fv.FunctionNumber}
+	for i := 0; i < len(order); i++ {
+		for _, com := range C.Funcs[order[i]].Commands {
+			if _, ok := index[com.X]; com.Kind == circ.FUNCTION_CALL && !ok {
+				index[com.X] = typ.Num(len(order))
+				order = append(order, com.X)
+			}
+		}
+	}
+
+	o.Funcs = make([]*circ.Function, len(order))
+	var build func(i typ.Num) *circ.Function
+	build = func(i typ.Num) *circ.Function {
+		if o.Funcs[i] != nil {
+			return o.Funcs[i]
+		}
+		f := circ.NewFunctionPt()
+		o.Funcs[i] = f
+		for _, com := range C.Funcs[order[i]].Commands {
+			switch com.Kind {
+			case circ.FUNCTION_CALL:
+				called := build(index[com.X])
+				f.PushFunctionCall(circ.Command{Kind: circ.FUNCTION_CALL, X: index[com.X], Y: com.Y}, called.XORgates, called.NonXORgates)
+			case circ.MASS_COPY, circ.REPLICATE:
+				for j := typ.Num(0); j < com.Y; j++ {
+					from := com.X
+					if com.Kind == circ.MASS_COPY {
+						from += j
+					}
+					pushCopy(f, number(from), number(com.To+j))
+				}
+			case circ.COPY:
+				pushCopy(f, number(com.X), number(com.To))
+			default:
+				if com.IsGate() {
+					com.X, com.Y, com.To = number(com.X), number(com.Y), number(com.To)
+				}
+				f.PushNonFunctionCall(com)
+			}
+		}
+		return f
+	}
+	build(0)
+	o.Wires = next
+	return o, nil
+}
+
+// pushCopy adds a copy to a function of an object, merging it with the
+// previous command when possible without mixing constant and relocatable wires
+func pushCopy(f *circ.Function, from, to typ.Num) {
+	if n := len(f.Commands); n > 0 {
+		prev := &f.Commands[n-1]
+		switch {
+		case prev.Kind == circ.COPY && prev.X == from && prev.To+1 == to:
+			prev.Kind, prev.Y = circ.REPLICATE, 2
+			return
+		case prev.Kind == circ.REPLICATE && prev.X == from && prev.To+prev.Y == to:
+			prev.Y++
+			return
+		case from >= circ.FirstRelocatableWire && prev.Kind == circ.COPY && prev.X >= circ.FirstRelocatableWire &&
+			prev.X+1 == from && prev.To+1 == to:
+			prev.Kind, prev.Y = circ.MASS_COPY, 2
+			return
+		case from >= circ.FirstRelocatableWire && prev.Kind == circ.MASS_COPY && prev.X >= circ.FirstRelocatableWire &&
+			prev.X+prev.Y == from && prev.To+prev.Y == to:
+			prev.Y++
+			return
+		}
+	}
+	f.PushNonFunctionCall(circ.Command{Kind: circ.COPY, X: from, To: to})
+}
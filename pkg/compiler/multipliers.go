@@ -0,0 +1,306 @@
+package compiler
+
+import (
+	wr "ixxoprivacy/pkg/wires"
+)
+
+/*
+ * A product of integers of n bits is computed modulo 2^n, which is the same
+ * for signed and unsigned integers. Below karatsubaThreshold bits, it is the
+ * schoolbook multiplier of outputMultSigned and outputMultUnsigned, which
+ * costs about n^2 AND gates. Above, the low halves are multiplied with
+ * Karatsuba's algorithm and the cross products modulo 2^(n/2) recursively.
+ * A multiplication by a known value is a sum of shifted copies of the other
+ * operand, the value being recoded in canonical signed digits so that there
+ * is at most one nonzero digit out of two: a digit at the position i costs an
+ * addition or a subtraction of n - i - 1 AND gates, but for the first one.
+ */
+
+// karatsubaThreshold is the number of bits from which a product of unknown
+// integers uses Karatsuba's algorithm, and fullKaratsubaThreshold the number
+// of bits of the operands from which it recurses into the full products.
+//
+// Karatsuba's algorithm saves AND gates but costs wires: a level of the
+// recursion holds its three products and their sums at the same time, where
+// the schoolbook multiplier only holds a row and the sum of the rows above.
+// The product of 256 bits integers takes 42002 AND gates and 2185 wires
+// instead of 65285 and 1795, the one of 1024 bits integers 427904 AND gates
+// and 11541 wires instead of 1047557 and 7171. The wires are a bound on the
+// memory of the garbler and the evaluator, the gates their time and the
+// size of the garbled circuit, which is why the gates are chosen.
+const (
+	karatsubaThreshold     = 32
+	fullKaratsubaThreshold = 16
+)
+
+// outputMult computes the product of leftv and rightv modulo 2^|destv|,
+// producing gates when it is necessary.
+// Precondiction - all vectors are of proper size:
+// |leftv| == |rightv| == |destv|
+func outputMult(leftv, rightv, destv wr.WireSet, signed bool) {
+	length := len(destv)
+	var product wr.WireSet
+
+	if c, ok := knownBits(rightv); ok {
+		product = multiplyByConstant(leftv, c)
+	} else if c, ok := knownBits(leftv); ok {
+		product = multiplyByConstant(rightv, c)
+	} else if length >= karatsubaThreshold {
+		product = multiplyLow(leftv, rightv, length)
+	} else if signed {
+		outputMultSigned(leftv, rightv, destv)
+		return
+	} else {
+		outputMultUnsigned(leftv, rightv, destv)
+		return
+	}
+
+	assignWireSet(destv, product)
+	pool.FreeSinglesIfNoRefs()
+}
+
+// knownBits returns the bits of a set of wires when they are all known
+func knownBits(ws wr.WireSet) ([]bool, bool) {
+	bits := make([]bool, len(ws))
+	for i, w := range ws {
+		if w.State != wr.ZERO && w.State != wr.ONE {
+			return nil, false
+		}
+		bits[i] = w.State == wr.ONE
+	}
+	return bits, true
+}
+
+// signedDigits returns the non-adjacent form of an integer given by its bits:
+// digits of -1, 0 or 1, of which no two consecutive ones are nonzero, and
+// the sum of digits[i] * 2^i being the integer. There is one more digit than
+// bits.
+func signedDigits(bits []bool) []int {
+	bit := func(i int) int {
+		if i < len(bits) && bits[i] {
+			return 1
+		}
+		return 0
+	}
+	digits := make([]int, len(bits)+1)
+	carry := 0
+	for i := range digits {
+		switch bit(i) + carry {
+		case 1:
+			// an odd value is rounded to a multiple of 4
+			if bit(i+1) == 1 {
+				digits[i], carry = -1, 1
+			} else {
+				digits[i], carry = 1, 0
+			}
+		case 2:
+			carry = 1
+		default:
+			carry = 0
+		}
+	}
+	return digits
+}
+
+// multiplyByConstant returns the product of x and the known integer of the
+// given bits modulo 2^|x|
+func multiplyByConstant(x wr.WireSet, bits []bool) wr.WireSet {
+	length := len(x)
+	digits := signedDigits(bits)[:length]
+
+	// the product starts from a shift of x by the lowest positive digit, but
+	// subtracting x from the zeros below it costs two gates a bit: when the
+	// lowest digit is negative and farther from it than the top, the product
+	// starts from a shift of -x by the lowest digit
+	lowest, positive := -1, -1
+	for i, d := range digits {
+		if d != 0 && lowest < 0 {
+			lowest = i
+		}
+		if d == 1 && positive < 0 {
+			positive = i
+		}
+	}
+	product := widen(nil, length)
+	if lowest >= 0 && digits[lowest] == -1 && (positive < 0 || 2*positive > length+lowest) {
+		product = append(widen(nil, lowest), negateWires(x[:length-lowest])...)
+		digits[lowest] = 0
+	} else if positive >= 0 {
+		product = append(widen(nil, positive), x[:length-positive]...)
+		digits[positive] = 0
+	}
+	for i, d := range digits {
+		if d == 0 {
+			continue
+		}
+		if d == 1 {
+			product = addShifted(product, x, i)
+		} else {
+			high := differenceWires(product[i:], x[:length-i], length-i)
+			product = append(product[:i:i], high...)
+		}
+	}
+	return product
+}
+
+// multiplyLow returns the product of a and b modulo 2^length
+func multiplyLow(a, b wr.WireSet, length int) wr.WireSet {
+	a, b = widen(a, length), widen(b, length)
+
+	if length < karatsubaThreshold {
+		product := andWires(a, b[0])
+		for i := 1; i < length; i++ {
+			row := andWires(a[:length-i], b[i])
+			high := sumWires(product[i:], row, length-i)
+			freeWires(row)
+			freeWires(product[i:])
+			product = append(product[:i:i], high...)
+		}
+		return product
+	}
+
+	// a0 * b0 + (a0 * b1 + a1 * b0) * 2^h, a0 and b0 being the h low bits,
+	// as 2h is not less than the length
+	h := (length + 1) / 2
+	cross1 := multiplyLow(a[:h], b[h:], length-h)
+	cross2 := multiplyLow(a[h:], b[:h], length-h)
+	cross := sumWires(cross1, cross2, length-h)
+	freeWires(cross1)
+	freeWires(cross2)
+	low := multiplyFull(a[:h], b[:h])
+	high := sumWires(low[h:], cross, length-h)
+	freeWires(cross)
+	freeWires(low[h:])
+	return append(low[:h:h], high...)
+}
+
+// multiplyFull returns the product of a and b, of |a| + |b| bits
+func multiplyFull(a, b wr.WireSet) wr.WireSet {
+	length := len(a)
+	if len(b) > length {
+		length = len(b)
+	}
+	a, b = widen(a, length), widen(b, length)
+
+	if length < fullKaratsubaThreshold {
+		// product holds the bits from the position i of the sum of the rows
+		// up to the row i
+		product := andWires(a, b[0])
+		result := wr.EmptyWireSet(0)
+		for i := 1; i < length; i++ {
+			result = append(result, product[0])
+			row := andWires(a, b[i])
+			next := sumWires(product[1:], row, length+1)
+			freeWires(row)
+			freeWires(product[1:])
+			product = next
+		}
+		return widen(append(result, product...), 2*length)
+	}
+
+	// with a = a0 + a1 * 2^h and b = b0 + b1 * 2^h, the product is
+	// z0 + z1 * 2^h + z2 * 2^2h where z1 = (a0 + a1) * (b0 + b1) - z0 - z2
+	h := (length + 1) / 2
+	// the intermediate results are freed as soon as they are consumed, so
+	// that the wires of the recursive products are reused
+	sa := sumWires(a[:h], a[h:], h+1)
+	sb := sumWires(b[:h], b[h:], h+1)
+	p := multiplyFull(sa, sb)
+	freeWires(sa)
+	freeWires(sb)
+	z0 := multiplyFull(a[:h], b[:h])
+	t := differenceWires(p, z0, 2*h+1)
+	freeWires(p)
+	z2 := multiplyFull(a[h:], b[h:])
+	z1 := differenceWires(t, z2, 2*h+1)
+	freeWires(t)
+	high := sumWires(append(append(wr.EmptyWireSet(0), z0[h:]...), z2...), z1, 2*length-h)
+	for _, ws := range []wr.WireSet{z1, z0[h:], z2} {
+		freeWires(ws)
+	}
+	return append(z0[:h:h], high...)
+}
+
+// widen returns a copy of a set of wires truncated or extended with zeros to
+// the given length
+func widen(ws wr.WireSet, length int) wr.WireSet {
+	if len(ws) > length {
+		ws = ws[:length]
+	}
+	w := make(wr.WireSet, len(ws), length)
+	copy(w, ws)
+	for len(w) < length {
+		w = append(w, W_0)
+	}
+	return w
+}
+
+// newWires returns the given number of wires of the pool
+func newWires(length int) wr.WireSet {
+	ws := wr.EmptyWireSet(0)
+	for len(ws) < length {
+		ws = append(ws, pool.GetWire())
+	}
+	return ws
+}
+
+// freeWires gives back to the pool the wires of an intermediate result, and
+// the wires they copy once nothing else refers to them, such as the carries
+// left by the additions
+func freeWires(ws wr.WireSet) {
+	for _, w := range ws {
+		for w != W_0 && w != W_1 {
+			other := w.Other
+			pool.FreeWire(w)
+			if other == nil || w.Other != nil {
+				break
+			}
+			w = other
+		}
+	}
+}
+
+// negateWires returns -x modulo 2^|x| in new wires: a bit of x is inverted
+// when there is a bit set below it
+func negateWires(x wr.WireSet) wr.WireSet {
+	dest := wr.WireSet{x[0]}
+	below := x[0]
+	for i := 1; i < len(x); i++ {
+		if i > 1 {
+			below = outputGate(14, below, x[i-1])
+		}
+		dest = append(dest, outputGate(6, x[i], below))
+	}
+	return dest
+}
+
+// andWires returns the AND of every wire of a set with another wire
+func andWires(ws wr.WireSet, w *wr.Wire) wr.WireSet {
+	dest := wr.EmptyWireSet(0)
+	for _, x := range ws {
+		dest = append(dest, outputGate(8, x, w))
+	}
+	return dest
+}
+
+// sumWires returns the sum of a and b modulo 2^length in new wires
+func sumWires(a, b wr.WireSet, length int) wr.WireSet {
+	dest := newWires(length)
+	outputAddition(widen(a, length), widen(b, length), dest)
+	return dest
+}
+
+// addShifted returns a + b * 2^shift modulo 2^|a|, the bits of a below the
+// shift being left as they are
+func addShifted(a, b wr.WireSet, shift int) wr.WireSet {
+	length := len(a)
+	high := sumWires(a[shift:], b, length-shift)
+	return append(a[:shift:shift], high...)
+}
+
+// differenceWires returns a - b modulo 2^length in new wires
+func differenceWires(a, b wr.WireSet, length int) wr.WireSet {
+	dest := newWires(length)
+	outputSubtract(widen(a, length), widen(b, length), dest)
+	return dest
+}
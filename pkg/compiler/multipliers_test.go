@@ -0,0 +1,120 @@
+package compiler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/interpreter"
+)
+
+// runProgram compiles a program and returns the fields of the output of the
+// party 0, printed in decimal, for the inputs given to each party
+func runProgram(t *testing.T, source string, inputs ...*big.Int) map[string]string {
+	path := filepath.Join(t.TempDir(), "program.js")
+	if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	C, err := CircuitFromJS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uios := make([]*circ.UserInOut, len(inputs))
+	for party, v := range inputs {
+		if uios[party], err = interpreter.EncodeInput(v.String(), C.Inputs[party].Type); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outputs := interpreter.Interprete(C, uios)
+	fields := interpreter.GetGoValue(outputs[0], C.Outputs[0].Type).(map[string]interface{})
+	printed := make(map[string]string)
+	for key, v := range fields {
+		printed[key] = fmt.Sprint(v)
+	}
+	return printed
+}
+
+// wrapInt returns v modulo 2^size as a signed integer of size bits
+func wrapInt(v *big.Int, size int) *big.Int {
+	m := new(big.Int).Lsh(big.NewInt(1), uint(size))
+	w := new(big.Int).Mod(v, m)
+	if w.Bit(size-1) == 1 {
+		w.Sub(w, m)
+	}
+	return w
+}
+
+// randomInt returns a random signed integer of size bits
+func randomInt(r *rand.Rand, size int) *big.Int {
+	return wrapInt(new(big.Int).Rand(r, new(big.Int).Lsh(big.NewInt(1), uint(size))), size)
+}
+
+// checkFields reports the fields of a result which are not the ones expected
+func checkFields(t *testing.T, context string, got map[string]string, want map[string]*big.Int) {
+	for key, v := range want {
+		if got[key] != v.String() {
+			t.Errorf("%s: %s is %s instead of %v", context, key, got[key], v)
+		}
+	}
+}
+
+// TestMultiply checks the products of unknown integers, with the schoolbook
+// multiplier and Karatsuba's algorithm, and by known integers
+func TestMultiply(t *testing.T) {
+	fmt.Println("Starting TestMultiply")
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{8, 31, 45, 64, 100} {
+		source := fmt.Sprintf(`var $parties = 2
+var $intsize = %d
+var in_0 = 0
+var in_1 = 0
+var out_0 = {product: 0, square: 0, by5: 0, byMinus7: 0, by1030: 0}
+out_0.product = in_0 * in_1
+out_0.square = in_0 * in_0
+out_0.by5 = in_0 * 5
+out_0.byMinus7 = -7 * in_1
+out_0.by1030 = in_1 * 1030
+`, size)
+		for run := 0; run < 5; run++ {
+			a, b := randomInt(r, size), randomInt(r, size)
+			if run == 0 {
+				a, b = wrapInt(big.NewInt(-1), size), wrapInt(big.NewInt(-1), size)
+			}
+			got := runProgram(t, source, a, b)
+			checkFields(t, fmt.Sprintf("%d bits, %v and %v", size, a, b), got, map[string]*big.Int{
+				"product":  wrapInt(new(big.Int).Mul(a, b), size),
+				"square":   wrapInt(new(big.Int).Mul(a, a), size),
+				"by5":      wrapInt(new(big.Int).Mul(a, big.NewInt(5)), size),
+				"byMinus7": wrapInt(new(big.Int).Mul(b, big.NewInt(-7)), size),
+				"by1030":   wrapInt(new(big.Int).Mul(b, big.NewInt(1030)), size),
+			})
+		}
+	}
+}
+
+// TestSignedDigits checks that the recoding has the value of the bits and no
+// two adjacent nonzero digits
+func TestSignedDigits(t *testing.T) {
+	fmt.Println("Starting TestSignedDigits")
+	for v := 0; v < 1<<10; v++ {
+		bits := make([]bool, 10)
+		for i := range bits {
+			bits[i] = v>>uint(i)&1 == 1
+		}
+		digits := signedDigits(bits)
+		sum := 0
+		for i, d := range digits {
+			sum += d << uint(i)
+			if d != 0 && i > 0 && digits[i-1] != 0 {
+				t.Errorf("%d has adjacent nonzero digits %v", v, digits)
+			}
+		}
+		if sum != v {
+			t.Errorf("the digits %v of %d sum to %d", digits, v, sum)
+		}
+	}
+}
@@ -18,7 +18,7 @@ func outputEquals(leftv, rightv wr.WireSet) *wr.Wire {
 		t = invertWireNoInvertOutput(wl)
 		currentxor = outputGateNoInvertOutput(6, t, rightv[i])
 
-		if i > 1 {
+		if i > 0 {
 			outputGateToDest(8, currentxor, outputwire, outputwire)
 		} else if i == 0 {
 			outputwire = currentxor
@@ -93,12 +93,14 @@ func outputSubtract(leftv, rightv, destv wr.WireSet) {
 		na := pool.GetWire()
 
 		for i := 0; i < length; i++ {
+			pool.FreeWire(na)
 			na = invertWireNoInvertOutput(leftv[i])
 			xorab = clearWireForReuse(xorab)
 			outputGateToDest(6, rightv[i], na, xorab)
 
 			t := invertWireNoInvertOutput(xorab)
 			outputGateNoInvertOutputToDest(6, t, carry, destv[i])
+			pool.FreeWire(t)
 
 			if i < length-1 {
 				xorac = clearWireForReuse(xorac)
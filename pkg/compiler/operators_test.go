@@ -0,0 +1,27 @@
+package compiler
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// TestEquals checks that == compares every bit of its operands
+func TestEquals(t *testing.T) {
+	fmt.Println("Starting TestEquals")
+	source := `var $parties = 2
+var $intsize = 8
+var in_0 = 0
+var in_1 = 0
+var out_0 = {eq: false, ne: false}
+out_0.eq = in_0 == in_1
+out_0.ne = in_0 != in_1
+`
+	for _, in := range [][2]int64{{1, 3}, {3, 1}, {0, 2}, {5, 5}, {4, 6}, {-1, 127}, {0, 0}} {
+		eq := in[0] == in[1]
+		got := runProgram(t, source, big.NewInt(in[0]), big.NewInt(in[1]))
+		if got["eq"] != fmt.Sprint(eq) || got["ne"] != fmt.Sprint(!eq) {
+			t.Errorf("%d == %d gives %v", in[0], in[1], got)
+		}
+	}
+}
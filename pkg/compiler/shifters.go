@@ -0,0 +1,108 @@
+package compiler
+
+import (
+	"math/big"
+	"math/bits"
+
+	wr "ixxoprivacy/pkg/wires"
+)
+
+/*
+ * A shift or a rotation by a known amount is wiring only. By a secret amount,
+ * it is a barrel shifter: the stage j shifts by 2^j the result of the
+ * previous stages when the bit j of the amount is set, with a multiplexer of
+ * one AND gate a bit, so that there are log n stages for integers of n bits.
+ * The shifts fill the integer with zeros, or with its sign for the right
+ * shift of a signed integer, and an amount which is negative or not less than
+ * n fills it entirely. A rotation is by the amount, its bits read as an
+ * unsigned integer, modulo n.
+ */
+
+// outputShift computes leftv shifted by rightv, to the left when left is true
+// and to the right otherwise, filled with the wire fill, producing gates when
+// it is necessary.
+// Precondiction - |leftv| == |destv|
+func outputShift(leftv, rightv, destv wr.WireSet, left bool, fill *wr.Wire) {
+	assignWireSet(destv, shiftWires(leftv, rightv, left, fill))
+	pool.FreeSinglesIfNoRefs()
+}
+
+// outputRotate computes leftv rotated to the left by rightv, producing gates
+// when it is necessary.
+// Precondiction - |leftv| == |destv|
+func outputRotate(leftv, rightv, destv wr.WireSet) {
+	assignWireSet(destv, rotateWires(leftv, rightv))
+	pool.FreeSinglesIfNoRefs()
+}
+
+// shiftWires returns x shifted by the integer of the wires of amount
+func shiftWires(x, amount wr.WireSet, left bool, fill *wr.Wire) wr.WireSet {
+	length := len(x)
+	if b, ok := knownBits(amount); ok {
+		v := bitsToInt(b, true)
+		if v.Sign() < 0 || !v.IsInt64() || v.Int64() >= int64(length) {
+			return repeatWire(fill, length)
+		}
+		return shiftByConstant(x, int(v.Int64()), left, fill)
+	}
+
+	// the stages shift by less than the length, the higher bits and the sign
+	// fill the result entirely
+	stages := bits.Len(uint(length - 1))
+	result := x
+	overflow := W_0
+	for j, s := range amount {
+		if j < stages && j < len(amount)-1 {
+			result = muxWires(s, result, shiftByConstant(result, 1<<uint(j), left, fill))
+		} else {
+			overflow = outputGate(14, overflow, s)
+		}
+	}
+	return muxWires(overflow, result, repeatWire(fill, length))
+}
+
+// rotateWires returns x rotated to the left by the integer of the wires of
+// amount modulo |x|
+func rotateWires(x, amount wr.WireSet) wr.WireSet {
+	length := len(x)
+	amount = widen(amount, length)
+	if b, ok := knownBits(amount); ok {
+		v := bitsToInt(b, false)
+		return rotateByConstant(x, int(v.Mod(v, big.NewInt(int64(length))).Int64()))
+	}
+
+	stages := bits.Len(uint(length - 1))
+	if length&(length-1) != 0 {
+		amount = divideByConstant(amount, big.NewInt(int64(length)), false, true)
+	}
+	result := x
+	for j := 0; j < stages; j++ {
+		result = muxWires(amount[j], result, rotateByConstant(result, 1<<uint(j)))
+	}
+	return result
+}
+
+// shiftByConstant returns x shifted by a known amount less than |x|
+func shiftByConstant(x wr.WireSet, shift int, left bool, fill *wr.Wire) wr.WireSet {
+	length := len(x)
+	if left {
+		return append(repeatWire(fill, shift), x[:length-shift]...)
+	}
+	return append(widen(x[shift:], length-shift), repeatWire(fill, shift)...)
+}
+
+// rotateByConstant returns x rotated to the left by a known amount less than
+// |x|
+func rotateByConstant(x wr.WireSet, shift int) wr.WireSet {
+	length := len(x)
+	return append(widen(x[length-shift:], shift), x[:length-shift]...)
+}
+
+// muxWires returns b when s is set and a otherwise, as a XOR (s AND (a XOR b))
+func muxWires(s *wr.Wire, a, b wr.WireSet) wr.WireSet {
+	dest := wr.EmptyWireSet(0)
+	for i := range a {
+		dest = append(dest, outputGate(6, a[i], outputGate(8, s, outputGate(6, a[i], b[i]))))
+	}
+	return dest
+}
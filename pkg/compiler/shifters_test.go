@@ -0,0 +1,56 @@
+package compiler
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// shifted returns x shifted by amount on size bits as the programs do: a
+// shift out of range fills the integer, and a rotation is by the amount read
+// as unsigned modulo size
+func shifted(x *big.Int, amount int64, size int) map[string]*big.Int {
+	m := new(big.Int).Lsh(big.NewInt(1), uint(size))
+	bits := new(big.Int).Mod(x, m)
+	want := map[string]*big.Int{"left": big.NewInt(0), "signed": new(big.Int).Rsh(x, uint(size)), "unsigned": big.NewInt(0)}
+	if amount >= 0 && amount < int64(size) {
+		want["left"] = wrapInt(new(big.Int).Lsh(x, uint(amount)), size)
+		want["signed"] = new(big.Int).Rsh(x, uint(amount))
+		want["unsigned"] = wrapInt(new(big.Int).Rsh(bits, uint(amount)), size)
+	}
+	r := uint(new(big.Int).Mod(wrapInt(big.NewInt(amount), size), m).Int64() % int64(size))
+	rotated := new(big.Int).Lsh(bits, r)
+	want["rotated"] = wrapInt(rotated.Or(rotated, new(big.Int).Rsh(bits, uint(size)-r)), size)
+	return want
+}
+
+// TestShift checks the barrel shifters with secret amounts, in and out of
+// range, and the shifts by known amounts
+func TestShift(t *testing.T) {
+	fmt.Println("Starting TestShift")
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{8, 13, 32} {
+		source := fmt.Sprintf(`var $parties = 2
+var $intsize = %d
+var in_0 = 0
+var in_1 = 0
+var out_0 = {left: 0, signed: 0, unsigned: 0, rotated: 0, known: 0}
+out_0.left = in_0 << in_1
+out_0.signed = in_0 >> in_1
+out_0.unsigned = in_0 >>> in_1
+out_0.rotated = RotateLeft(in_0, in_1)
+out_0.known = (in_0 >> 3) ^ RotateLeft(in_0, 5)
+`, size)
+		// 3 << stages sets two bits above the ones of the stages
+		stages := bits.Len(uint(size - 1))
+		for _, amount := range []int64{0, 1, 3, 7, int64(size) - 1, int64(size), int64(size) + 3, 3 << uint(stages), -1, -int64(size)} {
+			x := randomInt(r, size)
+			want := shifted(x, amount, size)
+			known := shifted(x, 5, size)["rotated"]
+			want["known"] = known.Xor(known, shifted(x, 3, size)["signed"])
+			checkFields(t, fmt.Sprintf("%d bits, %v by %d", size, x, amount), runProgram(t, source, x, big.NewInt(amount)), want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+
+	typ "ixxoprivacy/pkg/types"
+	vb "ixxoprivacy/pkg/variables"
+	wr "ixxoprivacy/pkg/wires"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+/*
+ * The sorting functions compile to Batcher's odd-even merge sort network: a
+ * fixed sequence of compare-exchanges, which does not depend on the values
+ * sorted. A compare-exchange of the items i and j costs a comparison of their
+ * keys and one AND gate per wire of the items to swap them conditionally.
+ * Sorting networks are not stable: the order of items with equal keys is
+ * given by the network.
+ */
+
+// SortingNetwork returns the comparators of the odd-even merge sort of n
+// items, in the order they are applied. A comparator (i, j), with i < j,
+// exchanges the items i and j when the item i is the greater. The network of
+// n items is the network of the next power of two without the comparators
+// touching the items after n.
+func SortingNetwork(n int) [][2]int {
+	net := make([][2]int, 0)
+	for p := 1; p < n; p <<= 1 {
+		for k := p; k >= 1; k >>= 1 {
+			for j := k % p; j+k < n; j += 2 * k {
+				for i := 0; i < k && i+j+k < n; i++ {
+					if (i+j)/(2*p) == (i+j+k)/(2*p) {
+						net = append(net, [2]int{i + j, i + j + k})
+					}
+				}
+			}
+		}
+	}
+	return net
+}
+
+// outSortNode is used in case of call to built-in functions Sort and SortBy
+func outSortNode(n *ast.CallExpression, fc vb.FunctionContext) vb.VarInterface {
+	if debug {
+		fmt.Println("\tStarting outSortNode")
+	}
+	key := sortingKey(n)
+	arrv := outExpressionNode(n.ArgumentList[0], fc).(*vb.ArrayVariable)
+
+	// the items are sorted in a copy of the array
+	destv := vb.NewArrayVariable(arrv.GetType(), "SORTOP")
+	destv.FillInWires(&pool)
+	lockVar(destv)
+	for i := typ.Num(0); i < destv.Size(); i++ {
+		w := destv.GetWire(i)
+		assignWire(w, arrv.GetWire(i))
+		makeWireContainValue(w)
+	}
+	unlockVar(arrv)
+	pool.FreeIfNoRefs()
+
+	for _, c := range SortingNetwork(len(destv.Av)) {
+		compareExchange(destv, c[0], c[1], key, nil)
+	}
+	return destv
+}
+
+// outCompareExchangeNode is used in case of call to built-in functions
+// CompareExchange and CompareExchangeBy, which change the array in place
+func outCompareExchangeNode(n *ast.CallExpression, fc vb.FunctionContext) vb.VarInterface {
+	if debug {
+		fmt.Println("\tStarting outCompareExchangeNode")
+	}
+	key := sortingKey(n)
+	arrv := outExpressionNode(n.ArgumentList[0], fc).(*vb.ArrayVariable)
+	ind := make([]int, 2)
+	for k, arg := range n.ArgumentList[1:3] {
+		indv := outExpressionNode(arg, fc).(vb.IntVariable)
+		if !indv.IsExt() {
+			fmt.Println("Error in outCompareExchangeNode: the indexes must be dollar variables or numbers")
+			os.Exit(64)
+		}
+		if indv.Val() < 0 || indv.Val() >= len(arrv.Av) {
+			fmt.Println("Array index out of range for CompareExchange. Length is ", len(arrv.Av), " and received index: ", indv.Val())
+			os.Exit(64)
+		}
+		ind[k] = indv.Val()
+	}
+	for _, v := range []vb.VarInterface{arrv.Av[ind[0]], arrv.Av[ind[1]]} {
+		if _, ok := v.(*vb.ExtInt); ok {
+			fmt.Println("Error in outCompareExchangeNode: cannot change wire of ext integer.")
+			os.Exit(64)
+		}
+	}
+
+	// in an if statement the items are exchanged only when the condition holds
+	var cond *wr.Wire
+	if ifvar := fc["-+IFCOND+-"]; ifvar != nil {
+		cond = ifvar.GetWire(0)
+	}
+	if ind[0] != ind[1] {
+		compareExchange(arrv, ind[0], ind[1], key, cond)
+	}
+	return nil
+}
+
+// sortingKey returns the key by which a sorting function sorts, "" when the
+// items are integers
+func sortingKey(n *ast.CallExpression) string {
+	key, err := vb.SortingKeyArg(n)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(64)
+	}
+	return key
+}
+
+// compareExchange exchanges in place the items i and j of an array when the
+// key of the item i is greater than the key of the item j, and cond is nil or true
+func compareExchange(arrv *vb.ArrayVariable, i, j int, key string, cond *wr.Wire) {
+	a, b := arrv.Av[i], arrv.Av[j]
+	swap := outputLessThan(keyWires(b, key), keyWires(a, key))
+	if cond != nil {
+		swap = outputGate(8, swap, cond)
+	}
+	// a ^= d and b ^= d, where d is a ^ b when the items are exchanged and 0 otherwise
+	for k := typ.Num(0); k < a.Size(); k++ {
+		wa, wb := a.GetWire(k), b.GetWire(k)
+		d := outputGate(8, outputGate(6, wa, wb), swap)
+		xorInPlace(wa, d)
+		xorInPlace(wb, d)
+	}
+	pool.FreeIfNoRefs()
+}
+
+// keyWires returns the wires of the key of an item as a new set, extended
+// with a zero when the key is unsigned so that it is compared as a signed integer
+func keyWires(item vb.VarInterface, key string) wr.WireSet {
+	if key != "" {
+		item = item.(*vb.ObjectVariable).Map[key]
+	}
+	ws := make(wr.WireSet, item.Size(), item.Size()+2)
+	for i := range ws {
+		ws[i] = item.GetWire(typ.Num(i))
+	}
+	if item.GetType().IsUIntType() {
+		ws = append(ws, W_0)
+	}
+	return ws
+}
+
+// xorInPlace sets the value of a wire to its XOR with another wire, the
+// wires referencing the first one keeping its previous value
+func xorInPlace(w, d *wr.Wire) {
+	if w.Refs() > 0 {
+		clearReffedWire(w)
+	}
+	makeWireContainValueNoONEZEROcopy(w)
+	outputGateToDest(6, w, d, w)
+	makeWireContainValueNoONEZEROcopy(w)
+}
@@ -0,0 +1,34 @@
+package compiler
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestSortingNetwork checks the networks with the 0-1 principle: a network
+// sorts every sequence if it sorts every sequence of zeros and ones.
+func TestSortingNetwork(t *testing.T) {
+	fmt.Println("Starting TestSortingNetwork")
+	for n := 0; n <= 14; n++ {
+		net := SortingNetwork(n)
+		for bits := 0; bits < 1<<uint(n); bits++ {
+			seq := make([]int, n)
+			for i := range seq {
+				seq[i] = bits >> uint(i) & 1
+			}
+			for _, c := range net {
+				if c[0] >= c[1] || c[1] >= n {
+					t.Fatalf("invalid comparator %v in the network of %d items", c, n)
+				}
+				if seq[c[0]] > seq[c[1]] {
+					seq[c[0]], seq[c[1]] = seq[c[1]], seq[c[0]]
+				}
+			}
+			for i := 1; i < n; i++ {
+				if seq[i-1] > seq[i] {
+					t.Fatalf("the network of %d items does not sort %b", n, bits)
+				}
+			}
+		}
+	}
+}
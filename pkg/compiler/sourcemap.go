@@ -0,0 +1,207 @@
+package compiler
+
+import (
+	"sort"
+	"strings"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+	vb "ixxoprivacy/pkg/variables"
+	wr "ixxoprivacy/pkg/wires"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+)
+
+// A FuncSource describes where the commands of a circuit function come from.
+// Positions is aligned with the Commands of the function, a null position
+// meaning that the command was generated by the compiler itself (constants,
+// inputs and outputs).
+type FuncSource struct {
+	Name       string     // name of the JavaScript function, "main" for the main body
+	Loop       bool       // true when the function is the body of a for loop compiled as a procedure
+	Start, End file.Idx   // span of the corresponding node in the source
+	Positions  []file.Idx // position of the node which produced each command
+	Probes     []Probe    // probes of the statements, recorded when RecordProbes is on
+}
+
+// A Probe tells, at the start of a statement, where the values of the
+// variables the statement can see are held.
+type Probe struct {
+	Pos     file.Idx
+	Command int // index in the commands of the function of the first command of the statement
+	Vars    []VarProbe
+}
+
+// A VarProbe gives the bits of a variable at the start of a statement
+type VarProbe struct {
+	Name string
+	Type *typ.Type
+	Bits []Bit
+}
+
+// A Bit is a constant when State is wr.ZERO or wr.ONE, the value of Wire when
+// State is wr.UNKNOWN and the negation of this value when it is wr.UNKNOWN_INVERT.
+type Bit struct {
+	State wr.WireState
+	Wire  typ.Num
+}
+
+// A SourceMap relates every command of a compiled circuit to the JavaScript
+// source, using the same indexes as the circuit for its functions.
+type SourceMap struct {
+	Files []*file.File
+	Main  FuncSource
+	Funcs []FuncSource
+}
+
+var funcSources map[*circ.Function]*FuncSource // sources of the functions being compiled
+var currentSource *FuncSource                  // source of the function being compiled
+var sourceFiles []*file.File                   // files the AST being compiled comes from
+var lastSourceMap SourceMap
+
+var recordProbes bool                            // true when probes are recorded
+var probes map[*circ.Function][]Probe            // probes of the functions being compiled
+var lastBits map[*circ.Function]map[string][]Bit // bits of the variables in the last probe of each function
+
+// RecordProbes sets whether the compiler records in the source map a probe of
+// the variables at the start of every statement, for debuggers. The commands
+// of a statement are then never merged with the ones of the previous statement.
+func RecordProbes(record bool) {
+	recordProbes = record
+}
+
+// recordProbe records the probe of a statement starting at a position
+func recordProbe(pos file.Idx, fc vb.FunctionContext) {
+	// the statement starts with a new command
+	writer.AddPrev(nullComm)
+	f := writer.GetFunction()
+	p := Probe{Pos: pos, Command: len(f.Commands)}
+
+	vars := make(map[string]vb.VarInterface)
+	for name, v := range context.FunctionContext {
+		vars[name] = v
+	}
+	for name, v := range fc {
+		vars[name] = v
+	}
+	names := make([]string, 0, len(vars))
+	for name, v := range vars {
+		// the variables of the compiler, such as the return value, are not named like JavaScript ones
+		if !v.IsFunction() && !strings.ContainsAny(name, "+-@") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if lastBits[f] == nil {
+		lastBits[f] = make(map[string][]Bit)
+	}
+	for _, name := range names {
+		v := vars[name]
+		bits := make([]Bit, v.Size())
+		for i := range bits {
+			w := v.GetWire(typ.Num(i))
+			if w == nil {
+				bits = nil
+				break
+			}
+			bits[i] = probeBit(w)
+		}
+		if bits == nil {
+			continue
+		}
+		// the bits of the variables which did not change are shared with the previous probe
+		if last := lastBits[f][name]; equalBits(last, bits) {
+			bits = last
+		} else {
+			lastBits[f][name] = bits
+		}
+		p.Vars = append(p.Vars, VarProbe{Name: name, Type: v.GetType(), Bits: bits})
+	}
+	probes[f] = append(probes[f], p)
+}
+
+// probeBit returns where the value of a wire is
+func probeBit(w *wr.Wire) Bit {
+	switch w.State {
+	case wr.UNKNOWN_OTHER_WIRE:
+		return Bit{State: wr.UNKNOWN, Wire: w.Other.Number}
+	case wr.UNKNOWN_INVERT_OTHER_WIRE:
+		return Bit{State: wr.UNKNOWN_INVERT, Wire: w.Other.Number}
+	}
+	return Bit{State: w.State, Wire: w.Number}
+}
+
+func equalBits(a, b []Bit) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// statementPosition returns the position of a statement. The parser does not
+// record the position of the for keyword, a for statement is at its first
+// clause.
+func statementPosition(n ast.Statement) file.Idx {
+	if st, ok := n.(*ast.ForStatement); ok && st.For == 0 {
+		for _, e := range []ast.Expression{st.Initializer, st.Test, st.Update} {
+			if e != nil {
+				return e.Idx0()
+			}
+		}
+		return st.Body.Idx0()
+	}
+	return n.Idx0()
+}
+
+// GetSourceMap returns the source map of the last circuit compiled
+func GetSourceMap() SourceMap {
+	return lastSourceMap
+}
+
+// Position converts a position of the source map into a file, line and column
+func (sm SourceMap) Position(idx file.Idx) *file.Position {
+	for _, f := range sm.Files {
+		if int(idx) >= f.Base() && int(idx) < f.Base()+len(f.Source()) {
+			return f.Position(idx)
+		}
+	}
+	return nil
+}
+
+// File returns the source file containing a given position, or nil
+func (sm SourceMap) File(idx file.Idx) *file.File {
+	for _, f := range sm.Files {
+		if int(idx) >= f.Base() && int(idx) < f.Base()+len(f.Source()) {
+			return f
+		}
+	}
+	return nil
+}
+
+// buildSourceMap gathers the positions recorded by the writer once the
+// whole circuit has been written.
+func buildSourceMap() SourceMap {
+	sm := SourceMap{Files: sourceFiles, Funcs: make([]FuncSource, len(circuit.Funcs))}
+	sm.Main = *funcSources[&circuit.Function]
+	sm.Main.Positions = writer.Positions(&circuit.Function)
+	sm.Main.Probes = probes[&circuit.Function]
+	for i, f := range circuit.Funcs {
+		if fs, ok := funcSources[f]; ok {
+			sm.Funcs[i] = *fs
+		}
+		sm.Funcs[i].Positions = writer.Positions(f)
+		sm.Funcs[i].Probes = probes[f]
+		if sm.Funcs[i].Positions == nil {
+			// linked functions are not written by the compiler
+			sm.Funcs[i].Positions = make([]file.Idx, len(f.Commands))
+		}
+	}
+	return sm
+}
@@ -3,6 +3,7 @@ package compiler
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	circ "ixxoprivacy/pkg/circuit"
 	typ "ixxoprivacy/pkg/types"
@@ -19,6 +20,13 @@ func outStatementNode(n ast.Statement, fc vb.FunctionContext) {
 	if debug {
 		fmt.Println("Starting outStatementNode")
 	}
+	if _, ok := n.(*ast.BlockStatement); !ok {
+		prevPos := writer.SetPosition(statementPosition(n))
+		defer writer.SetPosition(prevPos)
+		if recordProbes {
+			recordProbe(statementPosition(n), fc)
+		}
+	}
 	switch st := n.(type) {
 	case *ast.BlockStatement:
 		for _, val := range st.List {
@@ -130,11 +138,7 @@ func outReturnNode(n *ast.ReturnStatement, fc vb.FunctionContext) {
 			case tk.MULTIPLY:
 
 				t, leftv, rightv := auxIntegersOperands(exp, fc)
-				if t.IsIntType() {
-					outputMultSigned(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires)
-				} else if t.IsUIntType() {
-					outputMultUnsigned(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires)
-				}
+				outputMult(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires, t.IsIntType())
 				leftv.Unlock()
 				rightv.Unlock()
 				for _, w := range returnv.(*vb.RegularInt).Wires {
@@ -145,11 +149,7 @@ func outReturnNode(n *ast.ReturnStatement, fc vb.FunctionContext) {
 			case tk.SLASH:
 
 				t, leftv, rightv := auxIntegersOperands(exp, fc)
-				if t.IsIntType() {
-					outputDivideSigned(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires, false)
-				} else if t.IsUIntType() {
-					outputDivideUnsigned(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires, false)
-				}
+				outputDivide(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires, t.IsIntType(), false)
 				leftv.Unlock()
 				rightv.Unlock()
 				for _, w := range returnv.(*vb.RegularInt).Wires {
@@ -160,11 +160,7 @@ func outReturnNode(n *ast.ReturnStatement, fc vb.FunctionContext) {
 			case tk.REMAINDER:
 
 				t, leftv, rightv := auxIntegersOperands(exp, fc)
-				if t.IsIntType() {
-					outputDivideSigned(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires, true)
-				} else if t.IsUIntType() {
-					outputDivideUnsigned(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires, true)
-				}
+				outputDivide(leftv.WSet(), rightv.WSet(), returnv.(*vb.RegularInt).Wires, t.IsIntType(), true)
 				leftv.Unlock()
 				rightv.Unlock()
 				for _, w := range returnv.(*vb.RegularInt).Wires {
@@ -303,10 +299,15 @@ func outForNode(n *ast.ForStatement, fc vb.FunctionContext) {
 	isproc := isProc(n)
 	var itr uint32
 	var upperFunc *circ.Function
+	var written []vb.VarInterface
 	if isproc {
 		if debug {
 			fmt.Println("Proc found")
 		}
+		// the body is compiled once for all the iterations, so it must not
+		// rely on what is known of the variables it writes
+		written = writtenVariables(n.Body, fc)
+		flushWritten(written)
 		upperFunc = writer.GetFunction()
 		writer.ChangeFunction(circ.NewFunctionPt())
 	}
@@ -315,6 +316,10 @@ func outForNode(n *ast.ForStatement, fc vb.FunctionContext) {
 		if !isproc || itr == 0 {
 			outStatementNode(n.Body, fc)
 		}
+		if isproc && itr == 0 {
+			// the next iteration reads them on their wires
+			flushWritten(written)
+		}
 		itr++
 		outExpressionNode(n.Update, fc)
 		condv = outExpressionNode(n.Test, fc)
@@ -328,6 +333,7 @@ func outForNode(n *ast.ForStatement, fc vb.FunctionContext) {
 	unlockVar(condv)
 	if isproc {
 		procID := len(circuit.Funcs)
+		funcSources[writer.GetFunction()] = &FuncSource{Name: currentSource.Name, Loop: true, Start: statementPosition(n), End: n.Idx1()}
 		circuit.Funcs = append(circuit.Funcs, writer.GetFunction())
 		writer.ChangeFunction(upperFunc)
 		writer.AddProcCall(typ.Num(procID), typ.Num(itr))
@@ -335,9 +341,105 @@ func outForNode(n *ast.ForStatement, fc vb.FunctionContext) {
 	pool.FreeIfNoRefs()
 }
 
+// writtenVisitor collects the variables assigned by a loop body, and the
+// global variables written by the functions it calls
+type writtenVisitor struct {
+	locals  map[string]bool
+	globals map[string]bool
+}
+
+func (wv *writtenVisitor) Enter(n ast.Node) ast.Visitor {
+	switch e := n.(type) {
+	case *ast.AssignExpression:
+		wv.write(e.Left)
+	case *ast.UnaryExpression:
+		if e.Operator == tk.INCREMENT || e.Operator == tk.DECREMENT {
+			wv.write(e.Operand)
+		}
+	case *ast.CallExpression:
+		id, ok := e.Callee.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		switch id.Name {
+		case "SetWire", "CompareExchange", "CompareExchangeBy":
+			if len(e.ArgumentList) > 0 {
+				wv.write(e.ArgumentList[0])
+			}
+		default:
+			if _, ok := context.FunctionContext[id.Name].(*vb.FunctionVariable); ok {
+				for g := range functionGlobals(id.Name).written {
+					wv.globals[g] = true
+				}
+			}
+		}
+	}
+	return wv
+}
+
+func (wv *writtenVisitor) Exit(n ast.Node) {}
+
+// write records the variable changed by an assignment to an expression such
+// as a.b[i]
+func (wv *writtenVisitor) write(n ast.Expression) {
+	for {
+		switch e := n.(type) {
+		case *ast.BracketExpression:
+			n = e.Left
+		case *ast.DotExpression:
+			n = e.Left
+		case *ast.Identifier:
+			wv.locals[e.Name] = true
+			return
+		default:
+			return
+		}
+	}
+}
+
+// writtenVariables returns the variables a loop body may change
+func writtenVariables(body ast.Statement, fc vb.FunctionContext) []vb.VarInterface {
+	wv := &writtenVisitor{make(map[string]bool), make(map[string]bool)}
+	ast.Walk(wv, body)
+	vars := make([]vb.VarInterface, 0)
+	for _, name := range sortedNames(wv.locals) {
+		if strings.HasPrefix(name, "$") {
+			continue
+		}
+		if v, ok := fc[name]; ok {
+			vars = append(vars, v)
+		} else if v, ok := context.FunctionContext[name]; ok {
+			vars = append(vars, v)
+		}
+	}
+	for _, name := range sortedNames(wv.globals) {
+		vars = append(vars, context.FunctionContext[name])
+	}
+	return vars
+}
+
+// flushWritten writes the values of variables on their wires and forgets
+// them, the wires referencing these keeping their current values
+func flushWritten(vars []vb.VarInterface) {
+	for _, v := range vars {
+		if _, ok := v.(*vb.FunctionVariable); ok {
+			continue
+		}
+		for i := typ.Num(0); i < v.Size(); i++ {
+			w := v.GetWire(i)
+			clearReffedWire(w)
+			flushWire(w)
+			w.State = wr.UNKNOWN
+		}
+	}
+	pool.FreeIfNoRefs()
+}
+
 // isProc will assess if a given for statement qualifies as a procedure, which
 // means that the operations performed are the same at every iteration.
-// The condition tested is that the iterative index does not appear in the body.
+// The conditions tested are that the iterative index does not appear in the
+// body and that the body assigns no dollar variable, whose values are known
+// at compile time.
 // But other conditions may have to be verified as well.
 type updateVisitor []string
 
@@ -375,5 +477,13 @@ func isProc(n *ast.ForStatement) bool {
 	var result procVisitor = procVisitor{&ids, true}
 	ast.Walk(&result, n.Body)
 
+	wv := &writtenVisitor{make(map[string]bool), make(map[string]bool)}
+	ast.Walk(wv, n.Body)
+	for name := range wv.locals {
+		if strings.HasPrefix(name, "$") {
+			return false
+		}
+	}
+
 	return result.Proc
 }
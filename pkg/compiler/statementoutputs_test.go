@@ -0,0 +1,64 @@
+package compiler
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// TestProcedures checks the loops compiled once for all their iterations,
+// whose bodies start from what the previous iteration left
+func TestProcedures(t *testing.T) {
+	fmt.Println("Starting TestProcedures")
+	source := `var $parties = 2
+var $intsize = 32
+var in_0 = 0
+var in_1 = 0
+var out_0 = {pow: 0, sum: 0, fib: 0}
+
+// a constant before the loop, and a call under a secret condition
+var x = in_0
+var e = in_1
+var r = 1
+for (var $i = 0; $i < 4; $i++) {
+	if (GetWire(e, 0)) {
+		r = modMul(r, x)
+	}
+	e = e >> 1
+	x = modMul(x, x)
+}
+
+var s = 0
+var u = in_0
+for (var $i = 0; $i < 3; $i++) {
+	s = s + u
+	u = u + 1
+}
+
+// values moved from a variable to another
+var a = 0
+var b = in_1
+for (var $i = 0; $i < 10; $i++) {
+	var c = a + b
+	a = b
+	b = c
+}
+
+out_0.pow = r
+out_0.sum = s
+out_0.fib = a
+
+function modMul(x, y) {
+	return (x * y) % 251
+}
+`
+	for _, in := range [][2]int64{{0, 0}, {3, 14}, {7, 5}, {250, 15}, {12, 9}} {
+		pow := new(big.Int).Exp(big.NewInt(in[0]), big.NewInt(in[1]), big.NewInt(251))
+		sum := 3*in[0] + 3
+		fib := 55 * in[1]
+		got := runProgram(t, source, big.NewInt(in[0]), big.NewInt(in[1]))
+		if got["pow"] != pow.String() || got["sum"] != fmt.Sprint(sum) || got["fib"] != fmt.Sprint(fib) {
+			t.Errorf("the inputs %d and %d give %v instead of pow %v, sum %d and fib %d", in[0], in[1], got, pow, sum, fib)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	typ "ixxoprivacy/pkg/types"
 	vb "ixxoprivacy/pkg/variables"
 	wr "ixxoprivacy/pkg/wires"
+	"os"
 	"strconv"
 	"strings"
 
@@ -42,6 +43,7 @@ func makeONEandZERO() {
 // findParameters analyses the AST to find :
 // - the number of parties
 // - the bit size to use for integers
+// Both must be declared as positive numbers.
 func findParameters(decList []ast.Declaration) (intsize typ.Num, pnumb uint8) {
 	for _, dec := range decList {
 		// If it is a variable
@@ -49,25 +51,38 @@ func findParameters(decList []ast.Declaration) (intsize typ.Num, pnumb uint8) {
 			for _, v := range vdec.List {
 
 				if v.Name == "$intsize" {
-					vinit, ok := v.Initializer.(*ast.NumberLiteral)
-					if !ok {
-						fmt.Println("$intsize is no number")
-					}
-					intsize = typ.Num(vinit.Value.(int64))
+					intsize = typ.Num(positiveParameter(v))
 
 				} else if v.Name == "$parties" {
-					vinit, ok := v.Initializer.(*ast.NumberLiteral)
-					if !ok {
-						fmt.Println("$parties is no number")
-					}
-					pnumb = uint8(vinit.Value.(int64))
+					pnumb = uint8(positiveParameter(v))
 				}
 			}
 		}
 	}
+	if intsize == 0 {
+		fmt.Println("Error: the program must declare the number of bits of its integers with var $intsize = n")
+		os.Exit(64)
+	}
+	if pnumb == 0 {
+		fmt.Println("Error: the program must declare its number of parties with var $parties = n")
+		os.Exit(64)
+	}
 	return intsize, pnumb
 }
 
+// positiveParameter returns the value of the declaration of $intsize or
+// $parties, which must be a positive number
+func positiveParameter(v *ast.VariableExpression) int64 {
+	if vinit, ok := v.Initializer.(*ast.NumberLiteral); ok {
+		if val, ok := vinit.Value.(int64); ok && val > 0 {
+			return val
+		}
+	}
+	fmt.Println("Error:", v.Name, "must be declared as a positive number")
+	os.Exit(64)
+	return 0
+}
+
 // unlockVar will unlock the set of wires related to a given variable.
 // It is useful when this is not a user-defined variable (tested in the if condition).
 func unlockVar(v vb.VarInterface) bool {
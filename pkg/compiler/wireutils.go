@@ -249,6 +249,19 @@ func assignWire(w1 *wr.Wire, w2 *wr.Wire) {
 	}
 }
 
+// assignWireSet assigns a result to destv when the result can share wires
+// with the operands, which can be destv itself
+func assignWireSet(destv, ws wr.WireSet) {
+	copies := wr.EmptyWireSet(typ.Num(len(ws)))
+	for i, w := range ws {
+		copies[i] = pool.GetWire()
+		assignWire(copies[i], w)
+	}
+	for i, w := range copies {
+		assignWire(destv[i], w)
+	}
+}
+
 // assignWireCond assigns w2 to w1 if w3 is true
 func assignWireCond(w1 *wr.Wire, w2 *wr.Wire, w3 *wr.Wire) {
 	if w1 == w2 {
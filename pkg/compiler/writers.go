@@ -5,26 +5,33 @@ import (
 
 	circ "ixxoprivacy/pkg/circuit"
 	typ "ixxoprivacy/pkg/types"
+
+	"github.com/robertkrimen/otto/file"
 )
 
 // A FuncWriter variable is an object which progressively writes new gates into a function
 // of the circuit. Its main interest over the solution which would be to simply write gates
 // as they come is that it will simplify consecutive gates when it can, thus reducing the
 // size of the circuit.
+// Alongside the commands, it records in a side table the position of the AST node
+// which produced each of them, so that gates can be traced back to the source.
 type FuncWriter struct {
-	f    *circ.Function
-	prev circ.Command
+	f         *circ.Function
+	prev      circ.Command
+	pos       file.Idx                      // position of the node being compiled
+	prevPos   file.Idx                      // position of the node which produced prev
+	positions map[*circ.Function][]file.Idx // aligned with the Commands of each function
 }
 
 // The variable nullComm is used to initialize FuncWriter's
-var nullComm circ.Command = circ.Command{circ.EMPTY_COMMAND, 0, 0, 0}
+var nullComm circ.Command = circ.Command{Kind: circ.EMPTY_COMMAND}
 
 // StartFuncWriter creates a new FuncWriter variable corresponding to a given circuit function
 func StartFuncWriter(f *circ.Function) FuncWriter {
 	if debug {
 		fmt.Println("Starting function writer")
 	}
-	return FuncWriter{f, nullComm}
+	return FuncWriter{f: f, prev: nullComm, positions: make(map[*circ.Function][]file.Idx)}
 }
 
 // AddPrev pushes the last added command to the circuit function and replace the prev field with
@@ -37,8 +44,23 @@ func (fw *FuncWriter) AddPrev(newComm circ.Command) {
 		} else {
 			fw.f.PushNonFunctionCall(fw.prev)
 		}
+		fw.positions[fw.f] = append(fw.positions[fw.f], fw.prevPos)
 	}
 	fw.prev = newComm
+	fw.prevPos = fw.pos
+}
+
+// SetPosition sets the position of the AST node from which the next commands
+// originate, and returns the previous one so that it can be restored.
+func (fw *FuncWriter) SetPosition(pos file.Idx) file.Idx {
+	prevPos := fw.pos
+	fw.pos = pos
+	return prevPos
+}
+
+// Positions returns the source positions recorded for the commands of a function
+func (fw FuncWriter) Positions(f *circ.Function) []file.Idx {
+	return fw.positions[f]
 }
 
 // ChangeFunction is used to keep the same FuncWriter object but change the underlying function
@@ -58,7 +80,7 @@ func (fw FuncWriter) GetFunction() *circ.Function {
 // AddGate adds a gate to the circuit whose input wires are given by x and y,
 // the destination wire is d and the operator is table.
 func (fw *FuncWriter) AddGate(table uint8, d, x, y typ.Num) {
-	fw.AddPrev(circ.Command{circ.CommandType(circ.GATE_0 + table), x, y, d})
+	fw.AddPrev(circ.Command{Kind: circ.CommandType(circ.GATE_0 + table), X: x, Y: y, To: d})
 	if debug {
 		fmt.Printf("Gate: %d(%d, %d) -> %d\n", table, x, y, d)
 	}
@@ -75,7 +97,7 @@ func (fw *FuncWriter) AddCopy(to, from typ.Num) {
 	} else if fw.prev.Kind == circ.REPLICATE && fw.prev.X == from && fw.prev.To+fw.prev.Y == to {
 		fw.prev.Y++
 	} else {
-		fw.AddPrev(circ.Command{circ.COPY, from, 0, to})
+		fw.AddPrev(circ.Command{Kind: circ.COPY, X: from, To: to})
 	}
 	if debug {
 		fmt.Printf("Copy: %d -> %d\n", from, to)
@@ -91,7 +113,7 @@ func (fw *FuncWriter) AddMassCopy(to, from, len typ.Num) {
 	} else if fw.prev.Kind == circ.MASS_COPY && fw.prev.X+fw.prev.Y == from && fw.prev.To+fw.prev.Y == to {
 		fw.prev.Y += len
 	} else {
-		fw.AddPrev(circ.Command{circ.MASS_COPY, from, len, to})
+		fw.AddPrev(circ.Command{Kind: circ.MASS_COPY, X: from, Y: len, To: to})
 	}
 	if debug {
 		fmt.Printf("Mass Copy: (%d, %d) -> (%d, %d)\n", from, from+len, to, to+len)
@@ -100,7 +122,7 @@ func (fw *FuncWriter) AddMassCopy(to, from, len typ.Num) {
 
 // AddFunctionCall adds to the circuit a function call command.
 func (fw *FuncWriter) AddFunctionCall(fid typ.Num) {
-	fw.AddPrev(circ.Command{circ.FUNCTION_CALL, fid, 0, 0})
+	fw.AddPrev(circ.Command{Kind: circ.FUNCTION_CALL, X: fid})
 	if debug {
 		fmt.Printf("Call: %d\n", fid)
 	}
@@ -110,7 +132,7 @@ func (fw *FuncWriter) AddFunctionCall(fid typ.Num) {
 // of this procedure is contained in the function referenced and argument len is the number
 // of iterations of this procedure.
 func (fw *FuncWriter) AddProcCall(fid, itr typ.Num) {
-	fw.AddPrev(circ.Command{circ.FUNCTION_CALL, fid, itr, 0})
+	fw.AddPrev(circ.Command{Kind: circ.FUNCTION_CALL, X: fid, Y: itr})
 	if debug {
 		fmt.Printf("Procedure × %d\n", itr)
 	}
@@ -125,7 +147,7 @@ func (fw *FuncWriter) AddIn(wire, party typ.Num) {
 	} else if fw.prev.Kind == circ.MASS_INPUT && fw.prev.X == party && fw.prev.To+fw.prev.Y == wire {
 		fw.prev.Y++
 	} else {
-		fw.AddPrev(circ.Command{circ.INPUT, party, 0, wire})
+		fw.AddPrev(circ.Command{Kind: circ.INPUT, X: party, To: wire})
 	}
 	if debug {
 		fmt.Printf("Input: %d from %d\n", wire, party)
@@ -141,7 +163,7 @@ func (fw *FuncWriter) AddMassIn(wire, len, party typ.Num) {
 	} else if fw.prev.Kind == circ.MASS_INPUT && fw.prev.X == party && fw.prev.To+fw.prev.Y == wire {
 		fw.prev.Y += len
 	} else {
-		fw.AddPrev(circ.Command{circ.MASS_INPUT, party, len, wire})
+		fw.AddPrev(circ.Command{Kind: circ.MASS_INPUT, X: party, Y: len, To: wire})
 	}
 	if debug {
 		fmt.Printf("Mass Input: (%d, %d) from %d\n", wire, wire+len, party)
@@ -157,7 +179,7 @@ func (fw *FuncWriter) AddOut(wire, party typ.Num) {
 	} else if fw.prev.Kind == circ.MASS_OUTPUT && fw.prev.X+fw.prev.Y == wire && fw.prev.To == party {
 		fw.prev.Y++
 	} else {
-		fw.AddPrev(circ.Command{circ.OUTPUT, wire, 0, party})
+		fw.AddPrev(circ.Command{Kind: circ.OUTPUT, X: wire, To: party})
 	}
 	if debug {
 		fmt.Printf("Input: %d to %d\n", wire, party)
@@ -173,7 +195,7 @@ func (fw *FuncWriter) AddMassOut(wire, len, party typ.Num) {
 	} else if fw.prev.Kind == circ.MASS_OUTPUT && fw.prev.X+fw.prev.Y == wire && fw.prev.To == party {
 		fw.prev.Y += len
 	} else {
-		fw.AddPrev(circ.Command{circ.MASS_OUTPUT, wire, len, party})
+		fw.AddPrev(circ.Command{Kind: circ.MASS_OUTPUT, X: wire, Y: len, To: party})
 	}
 	if debug {
 		fmt.Printf("Mass Output: (%d, %d) to %d\n", wire, wire+len, party)
@@ -189,7 +211,7 @@ func (fw *FuncWriter) AddReplicate(to, from, len typ.Num) {
 	} else if fw.prev.Kind == circ.REPLICATE && fw.prev.X == from && fw.prev.To+fw.prev.Y == to {
 		fw.prev.Y += len
 	} else {
-		fw.AddPrev(circ.Command{circ.REPLICATE, from, len, to})
+		fw.AddPrev(circ.Command{Kind: circ.REPLICATE, X: from, Y: len, To: to})
 	}
 	if debug {
 		fmt.Printf("Replicate: %d -> (%d, %d)\n", from, to, to+len)
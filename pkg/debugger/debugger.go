@@ -0,0 +1,262 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+	ip "ixxoprivacy/pkg/interpreter"
+	wr "ixxoprivacy/pkg/wires"
+
+	"github.com/robertkrimen/otto/file"
+)
+
+/*
+ * This package runs a circuit compiled from a JavaScript program statement by
+ * statement. The program is compiled with probes: at the start of every
+ * statement, the compiler records in the source map which wires hold the bits
+ * of each variable the statement can see. The debugger runs the commands of
+ * the circuit with the interpreter up to the next probe, and decodes the
+ * variables from the wires using their types.
+ */
+
+// A frame is the state of the execution of a function of the circuit
+type frame struct {
+	source   *compiler.FuncSource
+	commands []circ.Command
+	next     int    // index of the next command to execute
+	left     uint32 // number of executions of the function still to do after the current one
+	probe    int    // index of the next probe of the source
+}
+
+// A Debugger runs a circuit statement by statement
+type Debugger struct {
+	C      circ.Circuit
+	Source compiler.SourceMap
+
+	m     *ip.Machine
+	stack []frame
+	probe *compiler.Probe // probe of the statement the debugger is stopped at, nil at the end
+	entry bool            // true when the statement is the first one of its function
+
+	lines map[int]bool    // lines with a breakpoint
+	funcs map[string]bool // functions with a breakpoint
+}
+
+// New compiles a program with probes and returns a debugger stopped at its
+// first statement, with the inputs of all the parties.
+func New(fileName string, inputs []*circ.UserInOut) (*Debugger, error) {
+	C, err := compile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return newDebugger(C, inputs)
+}
+
+// NewFromFiles is New with the inputs read from JSON files
+func NewFromFiles(fileName string, inputFiles []string) (*Debugger, error) {
+	C, err := compile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputFiles) != int(C.Parties) {
+		return nil, fmt.Errorf("%d input files given to a circuit of %d parties", len(inputFiles), C.Parties)
+	}
+	return newDebugger(C, ip.GetAllInputs(C.Inputs, inputFiles))
+}
+
+func compile(fileName string) (circ.Circuit, error) {
+	compiler.RecordProbes(true)
+	defer compiler.RecordProbes(false)
+	return compiler.CircuitFromJS(fileName)
+}
+
+func newDebugger(C circ.Circuit, inputs []*circ.UserInOut) (*Debugger, error) {
+	if len(inputs) != int(C.Parties) {
+		return nil, fmt.Errorf("%d inputs given to a circuit of %d parties", len(inputs), C.Parties)
+	}
+	d := &Debugger{
+		C:      C,
+		Source: compiler.GetSourceMap(),
+		m:      ip.NewMachine(inputs),
+		lines:  make(map[int]bool),
+		funcs:  make(map[string]bool),
+	}
+	d.stack = []frame{{source: &d.Source.Main, commands: C.Function.Commands}}
+	d.next()
+	return d, nil
+}
+
+// next runs the commands up to the next probe, and stops before the commands
+// of its statement. It returns false when the whole circuit has been run.
+func (d *Debugger) next() bool {
+	for len(d.stack) > 0 {
+		top := &d.stack[len(d.stack)-1]
+		if top.probe < len(top.source.Probes) && top.source.Probes[top.probe].Command <= top.next {
+			d.probe = &top.source.Probes[top.probe]
+			d.entry = top.probe == 0
+			top.probe++
+			return true
+		}
+		if top.next == len(top.commands) {
+			if top.left > 0 {
+				top.left--
+				top.next, top.probe = 0, 0
+			} else {
+				d.stack = d.stack[:len(d.stack)-1]
+			}
+			continue
+		}
+		com := top.commands[top.next]
+		top.next++
+		if com.Kind == circ.FUNCTION_CALL {
+			d.call(com)
+			continue
+		}
+		d.m.Exec(com)
+	}
+	d.probe = nil
+	d.entry = false
+	return false
+}
+
+// call pushes the frame of a function called
+func (d *Debugger) call(com circ.Command) {
+	f := frame{commands: d.C.Funcs[com.X].Commands, source: &compiler.FuncSource{}}
+	if int(com.X) < len(d.Source.Funcs) {
+		f.source = &d.Source.Funcs[com.X]
+	}
+	if com.Y > 1 {
+		f.left = uint32(com.Y) - 1
+	}
+	d.stack = append(d.stack, f)
+}
+
+// Done tells whether the whole circuit has been run
+func (d *Debugger) Done() bool {
+	return d.probe == nil
+}
+
+// Step runs the statement the debugger is stopped at, along with the
+// functions it calls, up to the start of the next statement. It returns false
+// when the whole circuit has been run.
+func (d *Debugger) Step() bool {
+	if d.probe == nil {
+		return false
+	}
+	return d.next()
+}
+
+// Continue runs the circuit up to the next breakpoint. It returns false when
+// the whole circuit has been run.
+func (d *Debugger) Continue() bool {
+	for d.Step() {
+		if d.atBreakpoint() {
+			return true
+		}
+	}
+	return false
+}
+
+// atBreakpoint tells whether the statement the debugger is stopped at has a breakpoint
+func (d *Debugger) atBreakpoint() bool {
+	if pos := d.Position(); pos != nil && d.lines[pos.Line] {
+		return true
+	}
+	src := d.stack[len(d.stack)-1].source
+	return d.entry && !src.Loop && d.funcs[src.Name]
+}
+
+// BreakLine sets a breakpoint on the statements starting on a line
+func (d *Debugger) BreakLine(line int) {
+	d.lines[line] = true
+}
+
+// BreakFunc sets a breakpoint on the entry of a JavaScript function
+func (d *Debugger) BreakFunc(name string) error {
+	for _, fs := range d.Source.Funcs {
+		if fs.Name == name && !fs.Loop {
+			d.funcs[name] = true
+			return nil
+		}
+	}
+	return fmt.Errorf("no function %s in the circuit", name)
+}
+
+// ClearBreakpoints removes all the breakpoints
+func (d *Debugger) ClearBreakpoints() {
+	d.lines = make(map[int]bool)
+	d.funcs = make(map[string]bool)
+}
+
+// Position returns the position of the statement the debugger is stopped at, nil at the end
+func (d *Debugger) Position() *file.Position {
+	if d.probe == nil {
+		return nil
+	}
+	return d.Source.Position(d.probe.Pos)
+}
+
+// Function returns the name of the function the debugger is stopped in
+func (d *Debugger) Function() string {
+	if len(d.stack) == 0 {
+		return ""
+	}
+	return d.stack[len(d.stack)-1].source.Name
+}
+
+// Where returns the names of the functions being run, the innermost last
+func (d *Debugger) Where() []string {
+	names := make([]string, len(d.stack))
+	for i, f := range d.stack {
+		names[i] = f.source.Name
+	}
+	return names
+}
+
+// Variables returns the names of the variables the statement the debugger is stopped at can see
+func (d *Debugger) Variables() []string {
+	if d.probe == nil {
+		return nil
+	}
+	names := make([]string, len(d.probe.Vars))
+	for i, v := range d.probe.Vars {
+		names[i] = v.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Value returns the value of a variable at the start of the statement the
+// debugger is stopped at, as it would be decoded from JSON.
+func (d *Debugger) Value(name string) (interface{}, error) {
+	if d.probe == nil {
+		return nil, fmt.Errorf("the program has ended")
+	}
+	for _, v := range d.probe.Vars {
+		if v.Name != name {
+			continue
+		}
+		bits := circ.NewUIO()
+		for _, b := range v.Bits {
+			switch b.State {
+			case wr.ZERO:
+				bits.Add(false)
+			case wr.ONE:
+				bits.Add(true)
+			case wr.UNKNOWN_INVERT:
+				bits.Add(!d.m.Wire(b.Wire))
+			default:
+				bits.Add(d.m.Wire(b.Wire))
+			}
+		}
+		return ip.GetGoValue(bits, v.Type), nil
+	}
+	return nil, fmt.Errorf("no variable %s here", name)
+}
+
+// Outputs returns the outputs written so far, nil for the parties without output
+func (d *Debugger) Outputs() []*circ.UserInOut {
+	return d.m.Outputs()
+}
@@ -0,0 +1,87 @@
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ixxoprivacy/pkg/compiler"
+	ip "ixxoprivacy/pkg/interpreter"
+)
+
+const testsDir = "../../Tests/"
+
+func newTestDebugger(t *testing.T) *Debugger {
+	d, err := NewFromFiles(testsDir+"test1_pgcd.js", []string{testsDir + "entry1-0.json", testsDir + "entry1-1.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestDebugger(t *testing.T) {
+	fmt.Println("Starting TestDebugger")
+	d := newTestDebugger(t)
+	if err := d.BreakFunc("aux"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.BreakFunc("nothing"); err == nil {
+		t.Error("a breakpoint was set on a function which does not exist")
+	}
+
+	// the first call of aux is aux(102, 120)
+	if !d.Continue() || d.Function() != "aux" {
+		t.Fatalf("stopped in %q instead of aux", d.Function())
+	}
+	for name, want := range map[string]int64{"a": 102, "b": 120, "in_0": 102, "in_1": 120} {
+		if v, err := d.Value(name); err != nil || v != want {
+			t.Errorf("%s is %v (%v) instead of %d", name, v, err, want)
+		}
+	}
+	if where := d.Where(); len(where) != 3 || where[2] != "aux" {
+		t.Errorf("the functions being run are %v", where)
+	}
+
+	// the statements of aux are run one at a time
+	line := d.Position().Line
+	d.Step()
+	if d.Position().Line != line+1 {
+		t.Errorf("stepped from line %d to line %d", line, d.Position().Line)
+	}
+	if v, _ := d.Value("result"); v != int64(18) {
+		t.Errorf("result is %v instead of 18", v)
+	}
+
+	// aux is called 10 times
+	calls := 1
+	for d.Continue() {
+		calls++
+	}
+	if calls != 10 {
+		t.Errorf("stopped %d times in aux instead of 10", calls)
+	}
+
+	// the outputs are the ones of the circuit compiled without probes
+	C, err := compiler.CircuitFromJS(testsDir + "test1_pgcd.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := ip.GetAllInputs(C.Inputs, []string{testsDir + "entry1-0.json", testsDir + "entry1-1.json"})
+	want := ip.Interprete(C, inputs)
+	if got := d.Outputs(); got[0] == nil || !got[0].Equals(want[0]) {
+		t.Errorf("the output is %v instead of %v", got[0], want[0])
+	}
+}
+
+func TestRepl(t *testing.T) {
+	fmt.Println("Starting TestRepl")
+	d := newTestDebugger(t)
+	var out bytes.Buffer
+	d.Run(strings.NewReader("break 28\ncontinue\nprint a\ndelete\ncontinue\n"), &out)
+	for _, s := range []string{"test1_pgcd.js:28 in aux: result = a", "a = 102", "out_0 = 6"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("%q not found in the output of the debugger:\n%s", s, out.String())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	ip "ixxoprivacy/pkg/interpreter"
+)
+
+const replHelp = `Commands:
+  break <line>|<function>  stop at the statements of a line or at the entry of a function
+  delete                   remove all the breakpoints
+  step, s                  run the current statement
+  continue, c              run up to the next breakpoint
+  print, p <variable>      print the value of a variable
+  vars                     list the variables of the current statement
+  where                    print the functions being run
+  quit, q                  stop debugging`
+
+// Run reads debugger commands from in and writes their results to out, until
+// the end of the input or the quit command. The outputs of the circuit are
+// written once it has been run entirely.
+func (d *Debugger) Run(in io.Reader, out io.Writer) {
+	d.printPosition(out)
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(out, "(debug) ")
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && d.command(fields[0], fields[1:], out) {
+			return
+		}
+		fmt.Fprint(out, "(debug) ")
+	}
+}
+
+// command runs a debugger command and tells whether debugging is over
+func (d *Debugger) command(cmd string, args []string, out io.Writer) bool {
+	switch cmd {
+	case "break", "b":
+		if len(args) != 1 {
+			fmt.Fprintln(out, "Usage: break <line>|<function>")
+		} else if line, err := strconv.Atoi(args[0]); err == nil {
+			d.BreakLine(line)
+		} else if err := d.BreakFunc(args[0]); err != nil {
+			fmt.Fprintln(out, err)
+		}
+
+	case "delete":
+		d.ClearBreakpoints()
+
+	case "step", "s", "continue", "c":
+		if d.Done() {
+			fmt.Fprintln(out, "The program has ended")
+			break
+		}
+		if cmd == "step" || cmd == "s" {
+			d.Step()
+		} else {
+			d.Continue()
+		}
+		d.printPosition(out)
+
+	case "print", "p":
+		if len(args) != 1 {
+			fmt.Fprintln(out, "Usage: print <variable>")
+			break
+		}
+		v, err := d.Value(args[0])
+		if err != nil {
+			fmt.Fprintln(out, err)
+			break
+		}
+		raw, _ := json.Marshal(v)
+		fmt.Fprintf(out, "%s = %s\n", args[0], raw)
+
+	case "vars":
+		fmt.Fprintln(out, strings.Join(d.Variables(), " "))
+
+	case "where":
+		for i := len(d.stack) - 1; i >= 0; i-- {
+			if src := d.stack[i].source; src.Loop {
+				fmt.Fprintln(out, "  in a loop of", src.Name)
+			} else {
+				fmt.Fprintln(out, "  in", src.Name)
+			}
+		}
+
+	case "quit", "q":
+		return true
+
+	default:
+		fmt.Fprintln(out, replHelp)
+	}
+	return false
+}
+
+// printPosition writes the statement the debugger is stopped at, or the
+// outputs of the circuit when it has been run entirely
+func (d *Debugger) printPosition(out io.Writer) {
+	pos := d.Position()
+	if pos == nil {
+		for party, o := range d.Outputs() {
+			if o == nil {
+				continue
+			}
+			raw, _ := json.Marshal(ip.GetGoValue(o, d.C.Outputs[party].Type))
+			fmt.Fprintf(out, "out_%d = %s\n", party, raw)
+		}
+		return
+	}
+	fmt.Fprintf(out, "%s:%d in %s: %s\n", pos.Filename, pos.Line, d.Function(), d.sourceLine(pos.Line))
+}
+
+// sourceLine returns a line of the source of the statement the debugger is stopped at
+func (d *Debugger) sourceLine(line int) string {
+	f := d.Source.File(d.probe.Pos)
+	if f == nil {
+		return ""
+	}
+	lines := strings.Split(f.Source(), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
@@ -1,11 +1,12 @@
 package engine
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"strconv"
+	"sync"
 	"testing"
-	"time"
 
 	circ "ixxoprivacy/pkg/circuit"
 	compiler "ixxoprivacy/pkg/compiler"
@@ -13,147 +14,6 @@ import (
 	ip "ixxoprivacy/pkg/interpreter"
 )
 
-var debug bool = false
-
-func basicTest(testName string) {
-	fmt.Println("\t Running ", testName)
-	security := uint8(4)
-	Init(security)
-
-	var testNumber rune = []rune(testName)[4]
-	var entryRoot string = "../Tests/entry" + string(testNumber) + "-"
-	testName = "../Tests/" + testName
-	var reName string = testName + ".re"
-
-	// Compilation of the circuit
-	tStart := time.Now()
-	C1, err := compiler.CircuitFromJS(testName + ".js")
-	if err != nil {
-		fmt.Println("Compilation error:")
-		fmt.Println(err)
-	}
-	diff := time.Now().Sub(tStart)
-	fmt.Println("\t Compilation done in", diff)
-
-	C1.SaveToFile(reName)
-	C2 := circ.RetrieveCircuit(reName)
-	if debug {
-		C2.Print("\t\t")
-	}
-
-	// Garling of the circuit
-	tStart = time.Now()
-	TS, enc, dec := garble.Garble(C2, security)
-	diff = time.Now().Sub(tStart)
-	fmt.Println("\t Garbling done in", diff)
-
-	if debug { // Testing if enc and dec seem ok
-		fmt.Println("\t Encoder:")
-		enc.Print("\t\t")
-		fmt.Println("\n\t Decoder:")
-		dec.Print("\t\t")
-	}
-
-	// Retrieving inputs
-	tStart = time.Now()
-	inputFiles := make([]string, 0)
-	for i := 0; i < int(C2.Parties); i++ {
-		inputFiles = append(inputFiles, entryRoot+strconv.Itoa(i)+".json")
-	}
-	inputs := ip.GetAllInputs(C2.Inputs, inputFiles)
-	diff = time.Now().Sub(tStart)
-	fmt.Println("\t Getting inputs done in", diff)
-
-	if debug { // Testing if inputs were well retrieved
-		fmt.Println("\t Inputs:")
-		for i := uint8(0); i < C2.Parties; i++ {
-			inputs[i].Print("\t\t")
-			fmt.Println()
-		}
-	}
-
-	// Doing the interpretation in the clear way
-	tStart = time.Now()
-	ioutputs := ip.Interprete(C2, inputs)
-	diff = time.Now().Sub(tStart)
-	fmt.Println("\t Clear interpretation done in", diff)
-
-	if debug {
-		fmt.Println("\t Clear outputs:")
-		for i := uint8(0); i < C2.Parties; i++ {
-			if ioutputs[i] != nil {
-				ioutputs[i].Print("\t\t")
-				fmt.Println()
-			}
-		}
-	}
-
-	// We create the channels to be used
-	tStart = time.Now()
-	chtab := make(chan circ.GarbledTable, 5)
-	chin := make([]chan circ.GarbledValue, 0)
-	chout := make([]chan circ.DecodingKey, 0)
-	outputs := make([]*circ.UserInOut, 0)
-	for i := uint8(0); i < C2.Parties; i++ {
-		chin = append(chin, make(chan circ.GarbledValue, 5))
-		chout = append(chout, make(chan circ.DecodingKey, 5))
-		outputs = append(outputs, new(circ.UserInOut))
-	}
-
-	// We send those channels to specific functions and evaluate the circuit
-	wg.Add(1 + 2*int(C2.Parties))
-	go TabSender(TS, chtab)
-	for i := uint8(0); i < C2.Parties; i++ {
-		go InputSender(enc.User[i].Encode(enc.SecretKey, inputs[i]), chin[i])
-		go OutputReceiver(dec.User[i], chout[i], outputs[i])
-	}
-	Evaluate(C2, chtab, chin, chout)
-	wg.Wait()
-	diff = time.Now().Sub(tStart)
-	fmt.Println("\t Evaluation done in", diff)
-
-	if debug {
-		for party, outp := range outputs {
-			if len(*outp) != 0 {
-				fmt.Println("\t Wire output to party", party)
-				outp.Print("\t\t")
-				fmt.Println()
-			}
-		}
-	}
-
-	// We print the outputs if they are incorrect
-	for party, out := range outputs {
-		if ioutputs[party] != nil && !ioutputs[party].Equals(out) {
-			fmt.Println("Difference in results for party", party)
-			fmt.Print("\t Clear result\n\t\t")
-			ip.PrintResult(ioutputs[party], C2.Outputs[party].Type)
-			fmt.Print("\t Garbled result\n\t\t")
-			ip.PrintResult(out, C2.Outputs[party].Type)
-		} else {
-			if !C2.Outputs[party].IsVoid() {
-				path := "../Tests/result" + string(testNumber) + "-" + strconv.Itoa(party) + ".json"
-				ip.SaveOutput(out, C2.Outputs[party].Type, path)
-			}
-		}
-	}
-}
-
-func TestGarbledValue(t *testing.T) {
-	fmt.Println("Starting TestBattery")
-	basicTest("test0")
-	fmt.Println()
-	basicTest("test2")
-	fmt.Println()
-	basicTest("test3")
-	fmt.Println()
-	basicTest("test5_matrix4")
-	fmt.Println()
-	basicTest("test6_matrix16")
-	fmt.Println()
-	basicTest("test8_mult256")
-}
-
 func mTestOperations(t *testing.T) {
 	Init(2)
 	var x *big.Int = new(big.Int)
@@ -209,3 +69,120 @@ func mTestOT(t *testing.T) {
 	fmt.Println("Decrypted message:")
 	m.Print("\t")
 }
+
+// evaluationKeys garbles a test circuit and returns the decoding keys sent by
+// the serial and the parallel evaluations of the same garbled circuit, along
+// with the decoding set and the outputs of the interpreter
+func evaluationKeys(t *testing.T, testName string, workers int) ([][]circ.DecodingKey, [][]circ.DecodingKey, circ.DecodingSet, []*circ.UserInOut) {
+	security := uint8(4)
+	Init(security)
+	C, err := compiler.CircuitFromJS("../../Tests/" + testName + ".js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	TS, enc, dec := garble.Garble(C, security)
+	inputFiles := make([]string, 0)
+	for i := 0; i < int(C.Parties); i++ {
+		inputFiles = append(inputFiles, "../../Tests/entry"+testNumber(testName)+"-"+strconv.Itoa(i)+".json")
+	}
+	inputs := ip.GetAllInputs(C.Inputs, inputFiles)
+	encoded := make([][]circ.GarbledValue, C.Parties)
+	for i := range encoded {
+		encoded[i] = enc.User[i].Encode(enc.SecretKey, inputs[i])
+	}
+
+	run := func(evaluate func(chtab chan circ.GarbledTable, chin []chan circ.GarbledValue, chout []chan circ.DecodingKey)) [][]circ.DecodingKey {
+		chtab := make(chan circ.GarbledTable, 5)
+		chin := make([]chan circ.GarbledValue, C.Parties)
+		chout := make([]chan circ.DecodingKey, C.Parties)
+		keys := make([][]circ.DecodingKey, C.Parties)
+		var received sync.WaitGroup
+		wg.Add(1 + int(C.Parties))
+		received.Add(int(C.Parties))
+		go TabSender(TS, chtab)
+		for i := range chin {
+			chin[i] = make(chan circ.GarbledValue, 5)
+			chout[i] = make(chan circ.DecodingKey, 5)
+			go InputSender(encoded[i], chin[i])
+			go func(i int) {
+				defer received.Done()
+				for key := range chout[i] {
+					keys[i] = append(keys[i], key)
+				}
+			}(i)
+		}
+		evaluate(chtab, chin, chout)
+		wg.Wait()
+		for _, ch := range chout {
+			close(ch)
+		}
+		received.Wait()
+		return keys
+	}
+	serial := run(func(chtab chan circ.GarbledTable, chin []chan circ.GarbledValue, chout []chan circ.DecodingKey) {
+		Evaluate(C, chtab, chin, chout)
+	})
+	parallel := run(func(chtab chan circ.GarbledTable, chin []chan circ.GarbledValue, chout []chan circ.DecodingKey) {
+		EvaluateParallel(C, chtab, chin, chout, workers)
+	})
+	return serial, parallel, dec, ip.Interprete(C, inputs)
+}
+
+func TestEvaluateParallel(t *testing.T) {
+	fmt.Println("Starting TestEvaluateParallel")
+	for _, name := range testPrograms(t) {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			if largePrograms[name] && !*large {
+				t.Skip("large program, run with -large")
+			}
+			serial, parallel, dec, outputs := evaluationKeys(t, name, 4)
+			if fmt.Sprint(serial) != fmt.Sprint(parallel) {
+				t.Errorf("%s: the parallel evaluation sent different keys", name)
+			}
+			for party, out := range outputs {
+				if out != nil && fmt.Sprint([]bool(*out)) != fmt.Sprint(dec.User[party].Decode(parallel[party])) {
+					t.Errorf("%s: wrong outputs of party %d", name, party)
+				}
+			}
+		})
+	}
+}
+
+func TestTabReader(t *testing.T) {
+	fmt.Println("Starting TestTabReader")
+	security := uint8(4)
+	Init(security)
+	C, err := compiler.CircuitFromJS("../../Tests/test5_matrix4.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables bytes.Buffer
+	enc, dec, err := garble.GarbleStream(C, security, &tables, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := ip.GetAllInputs(C.Inputs, []string{"../../Tests/entry5-0.json", "../../Tests/entry5-1.json"})
+
+	chtab := make(chan circ.GarbledTable, 5)
+	chin := make([]chan circ.GarbledValue, C.Parties)
+	chout := make([]chan circ.DecodingKey, C.Parties)
+	outputs := make([]*circ.UserInOut, C.Parties)
+	wg.Add(1 + 2*int(C.Parties))
+	go TabReader(&tables, chtab)
+	for i := range chin {
+		chin[i] = make(chan circ.GarbledValue, 5)
+		chout[i] = make(chan circ.DecodingKey, 5)
+		outputs[i] = new(circ.UserInOut)
+		go InputSender(enc.User[i].Encode(enc.SecretKey, inputs[i]), chin[i])
+		go OutputReceiver(dec.User[i], chout[i], outputs[i])
+	}
+	EvaluateParallel(C, chtab, chin, chout, 4)
+	wg.Wait()
+
+	for party, out := range ip.Interprete(C, inputs) {
+		if out != nil && !out.Equals(outputs[party]) {
+			t.Errorf("wrong outputs of party %d", party)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package engine
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	circ "ixxoprivacy/pkg/circuit"
 	typ "ixxoprivacy/pkg/types"
 	"os"
@@ -26,14 +28,12 @@ func Evaluate(C circ.Circuit, chtab chan circ.GarbledTable, chin []chan circ.Gar
 	var outIndex uint32 = 0
 
 	var gt circ.GarbledTable
-	var wa, wb circ.GarbledValue
 
 	var com circ.Command
-	chcom := make(chan circ.Command, 5)
-	go C.Visit(chcom, C.Funcs)
+	cur := C.Cursor()
 
-	for k := uint32(0); k < C.XORgates+C.NonXORgates; k++ {
-		com = <-chcom
+	for cur.Next() {
+		com = cur.Command()
 		switch com.Kind {
 
 		case circ.INPUT:
@@ -58,14 +58,12 @@ func Evaluate(C circ.Circuit, chtab chan circ.GarbledTable, chin []chan circ.Gar
 			}
 
 		case circ.OUTPUT:
-			wa = wireSet[com.X]
-			chout[com.To] <- circ.DecodingKey{wa.P, circ.HashOut(wa.Key, outIndex)}
+			chout[com.To] <- outputKey(wireSet[com.X], outIndex)
 			outIndex += 1
 
 		case circ.MASS_OUTPUT:
 			for j := typ.Num(0); j < com.Y; j++ {
-				wa = wireSet[com.X+j]
-				chout[com.To] <- circ.DecodingKey{wa.P, circ.HashOut(wa.Key, outIndex)}
+				chout[com.To] <- outputKey(wireSet[com.X+j], outIndex)
 				outIndex += 1
 			}
 
@@ -75,9 +73,7 @@ func Evaluate(C circ.Circuit, chtab chan circ.GarbledTable, chin []chan circ.Gar
 					wireSet[com.To] = wireSet[com.X].XOR(wireSet[com.Y])
 				} else {
 					gt = <-chtab
-					wa = wireSet[com.X]
-					wb = wireSet[com.Y]
-					wireSet[com.To] = circ.HashGate(wa.Key, wb.Key, gateIndex, n).XOR(gt.GetValue(wa.P, wb.P))
+					wireSet[com.To] = evalGate(wireSet[com.X], wireSet[com.Y], gt, gateIndex)
 					gateIndex += 1
 				}
 			} else {
@@ -87,6 +83,17 @@ func Evaluate(C circ.Circuit, chtab chan circ.GarbledTable, chin []chan circ.Gar
 	}
 }
 
+// evalGate returns the value of the output wire of the non-XOR gate of a
+// given index from the values of its input wires and its garbled table
+func evalGate(wa, wb circ.GarbledValue, gt circ.GarbledTable, gateIndex uint32) circ.GarbledValue {
+	return circ.HashGate(wa.Key, wb.Key, gateIndex, n).XOR(gt.GetValue(wa.P, wb.P))
+}
+
+// outputKey returns the decoding key sent for the output of a given index
+func outputKey(w circ.GarbledValue, outIndex uint32) circ.DecodingKey {
+	return circ.DecodingKey{w.P, circ.HashOut(w.Key, outIndex)}
+}
+
 // TabSender sends progressively all table from a TableSet object to a given channel
 func TabSender(TS circ.TableSet, chtab chan<- circ.GarbledTable) {
 	defer wg.Done()
@@ -95,6 +102,55 @@ func TabSender(TS circ.TableSet, chtab chan<- circ.GarbledTable) {
 	}
 }
 
+// TabReader sends progressively to a given channel all tables of a stream
+// written by garbler.GarbleStream
+func TabReader(r io.Reader, chtab chan<- circ.GarbledTable) {
+	defer wg.Done()
+	readTables(r, chtab)
+}
+
+// readTables sends to a channel the tables of a stream until it is over
+func readTables(r io.Reader, chtab chan<- circ.GarbledTable) {
+	br := bufio.NewReader(r)
+	for {
+		tab, err := circ.ReadTable(br, n)
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			fmt.Println("Error in TabReader:", err)
+			os.Exit(64)
+		}
+		chtab <- tab
+	}
+}
+
+// EvaluateStream evaluates a circuit whose tables are read from a stream
+// written by garbler.GarbleStream as they are needed, so that the garbled
+// circuit is never held in memory. The evaluation is done by Evaluate, or by
+// EvaluateParallel when there are several workers. The stream is read up to
+// its end before EvaluateStream returns, so that the connection it comes
+// from can carry other messages afterwards.
+func EvaluateStream(C circ.Circuit, tables io.Reader, chin []chan circ.GarbledValue, chout []chan circ.DecodingKey, workers int) {
+	chtab := make(chan circ.GarbledTable, 5)
+	over := make(chan bool)
+	go func() {
+		readTables(tables, chtab)
+		close(over)
+	}()
+	if workers > 1 {
+		EvaluateParallel(C, chtab, chin, chout, workers)
+	} else {
+		Evaluate(C, chtab, chin, chout)
+	}
+	for {
+		select {
+		case <-chtab:
+		case <-over:
+			return
+		}
+	}
+}
+
 // InputSender sends to a channel the input values of one party
 func InputSender(inp []circ.GarbledValue, chin chan<- circ.GarbledValue) {
 	defer wg.Done()
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	circ "ixxoprivacy/pkg/circuit"
+	compiler "ixxoprivacy/pkg/compiler"
+	garble "ixxoprivacy/pkg/garbler"
+	ip "ixxoprivacy/pkg/interpreter"
+)
+
+/*
+ * The test programs of the Tests directory are the files testN.js or
+ * testN_description.js. The inputs of the party p of the program N are in
+ * entryN-p.json and its expected output in resultN-p.json.
+ *
+ * TestGolden runs every test program which has inputs and compares its outputs
+ * to the result files. The result files are written instead of being compared
+ * when the tests are run with -update:
+ *   go test ./pkg/engine -run TestGolden -update
+ * The largest programs are only run with -large.
+ */
+
+var update = flag.Bool("update", false, "write the result files of the test programs")
+var large = flag.Bool("large", false, "run the largest test programs too")
+
+const testsDir = "../../Tests/"
+
+// testProgram matches the file names of the test programs
+var testProgram = regexp.MustCompile(`^test(\d+)(_\w+)?\.js$`)
+
+// testNumber returns the number of a test program from its name
+func testNumber(testName string) string {
+	return testProgram.FindStringSubmatch(testName + ".js")[1]
+}
+
+// testPrograms returns the names of the test programs sorted by number
+func testPrograms(t *testing.T) []string {
+	files, err := ioutil.ReadDir(testsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, f := range files {
+		if testProgram.MatchString(f.Name()) {
+			names = append(names, f.Name()[:len(f.Name())-len(".js")])
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ni, _ := strconv.Atoi(testNumber(names[i]))
+		nj, _ := strconv.Atoi(testNumber(names[j]))
+		return ni < nj
+	})
+	return names
+}
+
+// largePrograms are the test programs only run with -large, by TestGolden
+// and TestEvaluateParallel
+var largePrograms = map[string]bool{
+	"test7_matrix64": true,
+}
+
+func TestGolden(t *testing.T) {
+	fmt.Println("Starting TestGolden")
+	for _, name := range testPrograms(t) {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			if largePrograms[name] && !*large {
+				t.Skip("large program, run with -large")
+			}
+			goldenTest(t, name)
+		})
+	}
+}
+
+// goldenTest compiles a test program, interprets it, garbles and evaluates it
+// and compares its outputs to the result files
+func goldenTest(t *testing.T, testName string) {
+	security := uint8(4)
+	Init(security)
+	root := testsDir + "entry" + testNumber(testName) + "-"
+	if _, err := os.Stat(root + "0.json"); os.IsNotExist(err) {
+		t.Skip("no inputs")
+	}
+
+	C1, err := compiler.CircuitFromJS(testsDir + testName + ".js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the circuit is run as read back from a file
+	reName := filepath.Join(t.TempDir(), testName+".re")
+	C1.SaveToFile(reName)
+	C := circ.RetrieveCircuit(reName)
+
+	inputFiles := make([]string, C.Parties)
+	for i := range inputFiles {
+		inputFiles[i] = root + strconv.Itoa(i) + ".json"
+		if _, err := os.Stat(inputFiles[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inputs := ip.GetAllInputs(C.Inputs, inputFiles)
+	ioutputs := ip.Interprete(C, inputs)
+
+	TS, enc, dec := garble.Garble(C, security)
+	chtab := make(chan circ.GarbledTable, 5)
+	chin := make([]chan circ.GarbledValue, C.Parties)
+	chout := make([]chan circ.DecodingKey, C.Parties)
+	outputs := make([]*circ.UserInOut, C.Parties)
+	wg.Add(1 + 2*int(C.Parties))
+	go TabSender(TS, chtab)
+	for i := range chin {
+		chin[i] = make(chan circ.GarbledValue, 5)
+		chout[i] = make(chan circ.DecodingKey, 5)
+		outputs[i] = new(circ.UserInOut)
+		go InputSender(enc.User[i].Encode(enc.SecretKey, inputs[i]), chin[i])
+		go OutputReceiver(dec.User[i], chout[i], outputs[i])
+	}
+	Evaluate(C, chtab, chin, chout)
+	wg.Wait()
+
+	for party, out := range C.Outputs {
+		if out.IsVoid() {
+			continue
+		}
+		if ioutputs[party] == nil || !ioutputs[party].Equals(outputs[party]) {
+			t.Errorf("the garbled circuit and the interpreter differ for party %d", party)
+			continue
+		}
+		path := testsDir + "result" + testNumber(testName) + "-" + strconv.Itoa(party) + ".json"
+		if *update {
+			ip.SaveOutput(outputs[party], out.Type, path)
+			continue
+		}
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("%v, run the test with -update to create it", err)
+			continue
+		}
+		got, _ := json.Marshal(ip.GetGoValue(outputs[party], out.Type))
+		if !jsonEqual(t, raw, got) {
+			t.Errorf("output of party %d is %s instead of %s", party, got, raw)
+		}
+	}
+}
+
+// jsonEqual tells whether two JSON documents hold the same value, the numbers
+// being compared as written so that the large integers are compared exactly
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	var va, vb interface{}
+	for _, doc := range []struct {
+		raw []byte
+		v   *interface{}
+	}{{a, &va}, {b, &vb}} {
+		dec := json.NewDecoder(bytes.NewReader(doc.raw))
+		dec.UseNumber()
+		if err := dec.Decode(doc.v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reflect.DeepEqual(va, vb)
+}
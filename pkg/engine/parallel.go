@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+
+	circ "ixxoprivacy/pkg/circuit"
+)
+
+// evalWindow is the number of steps scheduled at once by EvaluateParallel
+const evalWindow = 1 << 16
+
+// EvaluateParallel evaluates a circuit like Evaluate, using a number of workers
+// to evaluate the independent gates of the circuit at the same time.
+//
+// The circuit is evaluated window by window, the steps of a window being split
+// into layers of steps which can be evaluated in any order. Gate and output
+// indexes, tables and inputs are all taken in the order of the serial
+// evaluation, so the keys sent to the output channels are exactly the ones sent
+// by Evaluate, the keys of the outputs of a window being sent once the whole
+// window is evaluated.
+func EvaluateParallel(C circ.Circuit, chtab chan circ.GarbledTable, chin []chan circ.GarbledValue, chout []chan circ.DecodingKey, workers int) {
+	if n == 0 {
+		fmt.Println("Execution package not initialized")
+		os.Exit(64)
+	}
+
+	wireSet := make([]circ.GarbledValue, C.TotalWires)
+	wireSet[0] = circ.GarbledValue{P: false, Key: circ.NullKey(n)}
+
+	var gateIndex uint32 = 0
+	var outIndex uint32 = 0
+
+	layering := circ.NewLayering(C.TotalWires)
+	steps := make([]circ.Step, 0, evalWindow)
+	tables := make([]circ.GarbledTable, 0)
+	inputs := make([]circ.GarbledValue, 0)
+	outs := make([]circ.DecodingKey, 0)
+
+	run := func(i int32) {
+		s := &steps[i]
+		switch {
+		case s.Kind == circ.COPY:
+			wireSet[s.To] = wireSet[s.X]
+		case s.Kind == circ.INPUT:
+			wireSet[s.To] = inputs[s.Arg]
+		case s.Kind == circ.OUTPUT:
+			outs[s.Arg] = outputKey(wireSet[s.X], s.Index)
+		case s.Kind == circ.GATE_6:
+			wireSet[s.To] = wireSet[s.X].XOR(wireSet[s.Y])
+		default:
+			wireSet[s.To] = evalGate(wireSet[s.X], wireSet[s.Y], tables[s.Arg], s.Index)
+		}
+	}
+	flush := func() {
+		circ.RunLayers(layering.Layers(steps), workers, run)
+		for _, s := range steps {
+			if s.Kind == circ.OUTPUT {
+				chout[s.To] <- outs[s.Arg]
+			}
+		}
+		steps, tables, inputs, outs = steps[:0], tables[:0], inputs[:0], outs[:0]
+	}
+
+	cur := C.Cursor()
+	for cur.Next() {
+		com := cur.Command()
+		if com.Kind == circ.EMPTY_COMMAND || com.Kind > circ.GATE_15 {
+			fmt.Println("Error in EvaluateParallel: found unknown kind.")
+			continue
+		}
+		first := len(steps)
+		steps = circ.AppendSteps(steps, com, &gateIndex, &outIndex)
+
+		// the data of the steps are received in the order of the serial evaluation
+		for i := first; i < len(steps); i++ {
+			s := &steps[i]
+			switch {
+			case s.Kind == circ.INPUT:
+				s.Arg = len(inputs)
+				inputs = append(inputs, <-chin[s.X])
+			case s.Kind == circ.OUTPUT:
+				s.Arg = len(outs)
+				outs = append(outs, circ.DecodingKey{})
+			case s.IsGate() && s.Kind != circ.GATE_6:
+				s.Arg = len(tables)
+				tables = append(tables, <-chtab)
+			}
+		}
+
+		if len(steps) >= evalWindow {
+			flush()
+		}
+	}
+	flush()
+}
@@ -1,8 +1,13 @@
 package garbler
 
 import (
+	"bytes"
 	"fmt"
 	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+	"math/rand"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -19,14 +24,14 @@ func TestGate(t *testing.T) {
 	w1.Print("\t w1: ")
 	fmt.Println()
 
-	gt, w2 := tableFromWires(w0, w1, 5)
+	gt, w2 := tableFromWires(w0, w1, 5, gateIndex)
 	fmt.Println("\tTable:")
 	gt.Print("\t")
 	fmt.Println()
 
 	w2.Print("\t w2: ")
 	fmt.Println()
-	dk := outKey(w2)
+	dk := outKey(w2, outIndex)
 	dk.Print("\t dk:")
 	fmt.Println()
 
@@ -54,3 +59,111 @@ func TestGate(t *testing.T) {
 		}
 	}
 }
+
+// excluded are the test programs which are not garbled by TestGarbleStream,
+// with the reason why
+var excluded = map[string]string{
+	// the products of matrices take from tens of seconds to minutes to be
+	// garbled twice
+	"test6_matrix16": "too long to garble",
+	"test7_matrix64": "too long to garble",
+}
+
+// TestGarbleStream compares the tables streamed by GarbleStream to the ones
+// of Garble for every test program of the Tests directory
+func TestGarbleStream(t *testing.T) {
+	fmt.Println("Starting TestGarbleStream")
+	paths, err := filepath.Glob("../../Tests/test*.js")
+	if err != nil || len(paths) == 0 {
+		t.Fatal("no test program in ../../Tests", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".js")
+		t.Run(name, func(t *testing.T) {
+			if reason, ok := excluded[name]; ok {
+				fmt.Println("Skipping", name+":", reason)
+				t.Skip(reason)
+			}
+			C, err := compiler.CircuitFromJS(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// with the same random keys, both garblings give the same tables
+			circ.RandGen = rand.New(rand.NewSource(1))
+			TS, enc1, dec1 := Garble(C, 4)
+			var want bytes.Buffer
+			for _, gt := range TS {
+				circ.WriteTable(&want, gt)
+			}
+			circ.RandGen = rand.New(rand.NewSource(1))
+			var got bytes.Buffer
+			enc2, dec2, err := GarbleStream(C, 4, &got, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(want.Bytes(), got.Bytes()) {
+				t.Errorf("%s: the streamed tables differ from the tables of Garble", name)
+			}
+			if fmt.Sprint(enc1, dec1) != fmt.Sprint(enc2, dec2) {
+				t.Errorf("%s: the encoding and decoding sets differ from the ones of Garble", name)
+			}
+
+			for i := 0; i < len(TS); i++ {
+				gt, err := circ.ReadTable(&got, 4)
+				if err != nil || fmt.Sprint(gt) != fmt.Sprint(TS[i]) {
+					t.Fatalf("%s: table %d read back wrongly", name, i)
+				}
+			}
+		})
+	}
+}
+
+// benchCircuit compiles the circuit used by the benchmarks
+func benchCircuit(b *testing.B) circ.Circuit {
+	C, err := compiler.CircuitFromJS("../../Tests/test8_mult256.js")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	return C
+}
+
+// BenchmarkVisit goes through the commands of a circuit with a channel
+func BenchmarkVisit(b *testing.B) {
+	C := benchCircuit(b)
+	for i := 0; i < b.N; i++ {
+		chcom := make(chan circ.Command, 5)
+		go C.Visit(chcom, C.Funcs)
+		for k := uint32(0); k < C.XORgates+C.NonXORgates; k++ {
+			<-chcom
+		}
+	}
+}
+
+// BenchmarkCursor goes through the commands of a circuit with a cursor
+func BenchmarkCursor(b *testing.B) {
+	C := benchCircuit(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cur := C.Cursor()
+		for cur.Next() {
+			_ = cur.Command()
+		}
+	}
+}
+
+func BenchmarkGarble(b *testing.B) {
+	C := benchCircuit(b)
+	for i := 0; i < b.N; i++ {
+		Garble(C, 1)
+	}
+}
+
+func BenchmarkGarbleStream(b *testing.B) {
+	C := benchCircuit(b)
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		GarbleStream(C, 1, &buf, 4)
+	}
+}
@@ -75,11 +75,10 @@ func Garble(Cin circ.Circuit, n uint8) (circ.TableSet, circ.EncodingSet, circ.De
 	dec := circ.NewDecodingSet(Cin.Parties)
 
 	var com circ.Command
-	chcom := make(chan circ.Command, 5)
-	go Cin.Visit(chcom, Cin.Funcs)
+	cur := Cin.Cursor()
 
-	for k := uint32(0); k < Cin.XORgates+Cin.NonXORgates; k++ {
-		com = <-chcom
+	for cur.Next() {
+		com = cur.Command()
 		if debug {
 			com.Print("")
 		}
@@ -114,12 +113,12 @@ func Garble(Cin circ.Circuit, n uint8) (circ.TableSet, circ.EncodingSet, circ.De
 			}
 
 		case circ.OUTPUT:
-			dec.User[com.To] = append(dec.User[com.To], outKey(wireSet[com.X]))
+			dec.User[com.To] = append(dec.User[com.To], outKey(wireSet[com.X], outIndex))
 			outIndex += 1
 
 		case circ.MASS_OUTPUT:
 			for j := typ.Num(0); j < com.Y; j++ {
-				dec.User[com.To] = append(dec.User[com.To], outKey(wireSet[com.X+j]))
+				dec.User[com.To] = append(dec.User[com.To], outKey(wireSet[com.X+j], outIndex))
 				outIndex += 1
 			}
 
@@ -128,7 +127,7 @@ func Garble(Cin circ.Circuit, n uint8) (circ.TableSet, circ.EncodingSet, circ.De
 				if com.Kind == circ.GATE_6 {
 					wireSet[com.To] = wireSet[com.X].XOR(wireSet[com.Y])
 				} else {
-					TS[gateIndex], wireSet[com.To] = tableFromWires(wireSet[com.X], wireSet[com.Y], com.Gate())
+					TS[gateIndex], wireSet[com.To] = tableFromWires(wireSet[com.X], wireSet[com.Y], com.Gate(), gateIndex)
 					gateIndex += 1
 				}
 			} else {
@@ -158,9 +157,9 @@ func getKey(gv circ.GarbledValue, b bool) circ.GarbledKey {
 
 // outKey is used in case of an output command.
 // The argument provided is a certain garbled value, which corresponds to the wire we want
-// to output. Then outKey will compute the two boolean values of the decoding key which the
-// receiver will need to decrypt the result.
-func outKey(gv circ.GarbledValue) circ.DecodingKey {
+// to output, and the index of the output. Then outKey will compute the two boolean values
+// of the decoding key which the receiver will need to decrypt the result.
+func outKey(gv circ.GarbledValue, outIndex uint32) circ.DecodingKey {
 	e0 := circ.HashOut(gv.Key, outIndex)
 	e1 := !circ.HashOut(gv.Key.XOR(offsetR), outIndex)
 	if gv.P {
@@ -169,13 +168,13 @@ func outKey(gv circ.GarbledValue) circ.DecodingKey {
 	return [2]bool{e0, e1}
 }
 
-// tableFromWires creates a table from the given wires and operator
-func tableFromWires(wx, wy circ.GarbledValue, op uint8) (circ.GarbledTable, circ.GarbledValue) {
+// tableFromWires creates the table of the gate of a given index from the given wires and operator
+func tableFromWires(wx, wy circ.GarbledValue, op uint8, gateIndex uint32) (circ.GarbledTable, circ.GarbledValue) {
 	// We create the garbled table used for this gate
 	var table circ.GarbledTable
 
 	// We find the zero-value of the resulting wire
-	gvto := hashGate(getKey(wx, wx.P), getKey(wy, wy.P))
+	gvto := hashGate(getKey(wx, wx.P), getKey(wy, wy.P), gateIndex)
 	if boolsToInt(wx.P, wy.P)&op != 0 {
 		gvto.P = !gvto.P
 		gvto.Key = gvto.Key.XOR(offsetR)
@@ -193,7 +192,7 @@ func tableFromWires(wx, wy circ.GarbledValue, op uint8) (circ.GarbledTable, circ
 	for i := 1; i < 4; i++ {
 		px = (i/2 == 1) != wx.P
 		py = (i%2 == 1) != wy.P
-		table[i-1] = getVal(gvto, boolsToInt(px, py)&op != 0).XOR(hashGate(getKey(wx, px), getKey(wy, py)))
+		table[i-1] = getVal(gvto, boolsToInt(px, py)&op != 0).XOR(hashGate(getKey(wx, px), getKey(wy, py), gateIndex))
 	}
 	return table, gvto
 }
@@ -211,6 +210,6 @@ func boolsToInt(a, b bool) uint8 {
 }
 
 // hashGate produces the hash value used in case of a gate
-func hashGate(k1, k2 circ.GarbledKey) circ.GarbledValue {
+func hashGate(k1, k2 circ.GarbledKey, gateIndex uint32) circ.GarbledValue {
 	return circ.HashGate(k1, k2, gateIndex, N)
 }
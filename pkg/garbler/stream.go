@@ -0,0 +1,187 @@
+package garbler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// garbleWindow is the number of steps garbled at once by GarbleStream
+const garbleWindow = 1 << 16
+
+// GarbleStreamCompiledCircuit garbles a compiled circuit with GarbleStream,
+// writing its tables to a .tables file next to it.
+func GarbleStreamCompiledCircuit(fileName string, n uint8, workers int) {
+	if !strings.HasSuffix(fileName, ".re") {
+		fmt.Println("Warning: input file has no re extension.")
+	}
+	Cin := circ.RetrieveCircuit(fileName)
+	tStart := time.Now()
+
+	outputFileName := strings.TrimSuffix(fileName, ".re") + ".tables"
+	outputFile, err := os.Create(outputFileName)
+	if err != nil {
+		fmt.Println("Error in GarbleStreamCompiledCircuit: file creation failed")
+		fmt.Println(err)
+		os.Exit(64)
+	}
+	defer outputFile.Close()
+	if _, _, err := GarbleStream(Cin, n, outputFile, workers); err != nil {
+		fmt.Println("Error in GarbleStreamCompiledCircuit: writing the tables failed")
+		fmt.Println(err)
+		os.Exit(64)
+	}
+
+	diff := time.Now().Sub(tStart)
+	fmt.Println("Garbling achieved in ", diff)
+}
+
+// GarbleStream garbles a circuit like Garble, but writes the garbled tables to
+// w with circ.WriteTable as soon as they are produced instead of keeping them
+// all in memory, so that the memory used does not depend on the number of
+// gates of the circuit.
+//
+// The circuit is garbled window by window. When workers is more than one, the
+// steps of a window are split into layers of independent steps whose gates
+// are garbled in parallel. The gate and output indexes are still given in the
+// order of the serial garbling, and the tables are written in that order.
+func GarbleStream(Cin circ.Circuit, n uint8, w io.Writer, workers int) (circ.EncodingSet, circ.DecodingSet, error) {
+	return garbleStream(Cin, n, w, workers, nil)
+}
+
+// DrawEncoding draws the global offset and the keys of all the inputs of a
+// circuit before it is garbled with GarbleStreamEncoded, so that the inputs
+// can be encoded and sent while the tables are still being produced.
+func DrawEncoding(Cin circ.Circuit, n uint8) circ.EncodingSet {
+	enc := circ.NewEncodingSet(circ.RandomGarbledKey(n), Cin.Parties)
+	for _, com := range Cin.Commands {
+		switch com.Kind {
+		case circ.INPUT:
+			enc.User[com.X] = append(enc.User[com.X], circ.RandomGarbledValue(n))
+		case circ.MASS_INPUT:
+			for j := typ.Num(0); j < com.Y; j++ {
+				enc.User[com.X] = append(enc.User[com.X], circ.RandomGarbledValue(n))
+			}
+		}
+	}
+	return enc
+}
+
+// GarbleStreamEncoded garbles a circuit like GarbleStream, using the keys of
+// an encoding set drawn with DrawEncoding.
+func GarbleStreamEncoded(Cin circ.Circuit, n uint8, w io.Writer, workers int, enc circ.EncodingSet) (circ.DecodingSet, error) {
+	_, dec, err := garbleStream(Cin, n, w, workers, &enc)
+	return dec, err
+}
+
+// garbleStream garbles a circuit, drawing the keys of the inputs as they come
+// when drawn is nil
+func garbleStream(Cin circ.Circuit, n uint8, w io.Writer, workers int, drawn *circ.EncodingSet) (circ.EncodingSet, circ.DecodingSet, error) {
+	N = n
+	var enc circ.EncodingSet
+	used := make([]int, Cin.Parties) // number of drawn keys used for every party
+	if drawn != nil {
+		offsetR = drawn.SecretKey
+		enc = *drawn
+	} else {
+		offsetR = circ.RandomGarbledKey(n)
+		enc = circ.NewEncodingSet(offsetR, Cin.Parties)
+	}
+
+	wireSet := make([]circ.GarbledValue, Cin.TotalWires)
+	wireSet[0] = circ.GarbledValue{P: false, Key: circ.NullKey(n)}
+
+	dec := circ.NewDecodingSet(Cin.Parties)
+
+	var gateIndex uint32 = 0
+	var outIndex uint32 = 0
+
+	bw := bufio.NewWriter(w)
+	layering := circ.NewLayering(Cin.TotalWires)
+	steps := make([]circ.Step, 0, garbleWindow)
+	tables := make([]circ.GarbledTable, 0)
+	inputs := make([]circ.GarbledValue, 0)
+	outs := make([]circ.DecodingKey, 0)
+
+	run := func(i int32) {
+		s := &steps[i]
+		switch {
+		case s.Kind == circ.COPY:
+			wireSet[s.To] = wireSet[s.X]
+		case s.Kind == circ.INPUT:
+			wireSet[s.To] = inputs[s.Arg]
+		case s.Kind == circ.OUTPUT:
+			outs[s.Arg] = outKey(wireSet[s.X], s.Index)
+		case s.Kind == circ.GATE_6:
+			wireSet[s.To] = wireSet[s.X].XOR(wireSet[s.Y])
+		default:
+			tables[s.Arg], wireSet[s.To] = tableFromWires(wireSet[s.X], wireSet[s.Y], s.Gate(), s.Index)
+		}
+	}
+	flush := func() error {
+		circ.RunLayers(layering.Layers(steps), workers, run)
+		for _, s := range steps {
+			if s.Kind == circ.OUTPUT {
+				dec.User[s.To] = append(dec.User[s.To], outs[s.Arg])
+			}
+		}
+		for _, gt := range tables {
+			if err := circ.WriteTable(bw, gt); err != nil {
+				return err
+			}
+		}
+		steps, tables, inputs, outs = steps[:0], tables[:0], inputs[:0], outs[:0]
+		// the tables of the window can be evaluated without waiting for the next ones
+		return bw.Flush()
+	}
+
+	cur := Cin.Cursor()
+	for cur.Next() {
+		com := cur.Command()
+		if com.Kind == circ.EMPTY_COMMAND || com.Kind > circ.GATE_15 {
+			fmt.Println("Error in GarbleStream: found unknown kind.")
+			continue
+		}
+		first := len(steps)
+		steps = circ.AppendSteps(steps, com, &gateIndex, &outIndex)
+
+		// the keys of the inputs are drawn, or taken from the keys drawn
+		// beforehand, in the order of the serial garbling
+		for i := first; i < len(steps); i++ {
+			s := &steps[i]
+			switch {
+			case s.Kind == circ.INPUT && drawn != nil:
+				if used[s.X] == len(enc.User[s.X]) {
+					return enc, dec, fmt.Errorf("not enough keys drawn for the inputs of party %d", s.X)
+				}
+				s.Arg = len(inputs)
+				inputs = append(inputs, enc.User[s.X][used[s.X]])
+				used[s.X]++
+			case s.Kind == circ.INPUT:
+				s.Arg = len(inputs)
+				inputs = append(inputs, circ.RandomGarbledValue(N))
+				enc.User[s.X] = append(enc.User[s.X], inputs[s.Arg])
+			case s.Kind == circ.OUTPUT:
+				s.Arg = len(outs)
+				outs = append(outs, circ.DecodingKey{})
+			case s.IsGate() && s.Kind != circ.GATE_6:
+				s.Arg = len(tables)
+				tables = append(tables, circ.GarbledTable{})
+			}
+		}
+
+		if len(steps) >= garbleWindow {
+			if err := flush(); err != nil {
+				return enc, dec, err
+			}
+		}
+	}
+	err := flush()
+	return enc, dec, err
+}
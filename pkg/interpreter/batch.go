@@ -0,0 +1,156 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/rand"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// Lanes is the number of sets of inputs interpreted in a single pass by InterpreteBatch
+const Lanes = 64
+
+// InterpreteBatch runs a circuit on many sets of inputs, batch[i] holding the
+// inputs of all the parties for the i-th run as they are given to Interprete.
+// It returns the outputs of every run, as returned by Interprete.
+//
+// The runs are bit-sliced: every wire is a uint64 whose bit j holds the value
+// of the wire in the j-th run, so that the commands of the circuit are gone
+// through once for Lanes runs, every gate being computed with bitwise operations.
+func InterpreteBatch(C circ.Circuit, batch [][]*circ.UserInOut) [][]*circ.UserInOut {
+	results := make([][]*circ.UserInOut, 0, len(batch))
+	for start := 0; start < len(batch); start += Lanes {
+		end := start + Lanes
+		if end > len(batch) {
+			end = len(batch)
+		}
+		results = append(results, interpreteLanes(C, batch[start:end])...)
+	}
+	return results
+}
+
+// interpreteLanes runs a circuit on at most Lanes sets of inputs at once
+func interpreteLanes(C circ.Circuit, batch [][]*circ.UserInOut) [][]*circ.UserInOut {
+	parties := len(batch[0])
+
+	// the inputs of every party, bit k of a run being bit j of inputs[party][k]
+	inputs := make([][]uint64, parties)
+	for j, runInputs := range batch {
+		for party, inp := range runInputs {
+			for len(inputs[party]) < len(*inp) {
+				inputs[party] = append(inputs[party], 0)
+			}
+			for k, b := range *inp {
+				if b {
+					inputs[party][k] |= 1 << uint(j)
+				}
+			}
+		}
+	}
+	next := make([]int, parties) // index of the next input bit of every party
+	pop := func(party typ.Num) uint64 {
+		if next[party] == len(inputs[party]) {
+			fmt.Println("Error in InterpreteBatch: missing input of party", party)
+			return 0
+		}
+		next[party]++
+		return inputs[party][next[party]-1]
+	}
+
+	outputs := make([][]uint64, parties)
+	hasOutput := make([]bool, parties)
+	wires := make([]uint64, C.TotalWires)
+
+	cur := C.Cursor()
+	for cur.Next() {
+		com := cur.Command()
+		switch com.Kind {
+		case circ.COPY:
+			wires[com.To] = wires[com.X]
+
+		case circ.MASS_COPY:
+			for i := typ.Num(0); i < com.Y; i++ {
+				wires[com.To+i] = wires[com.X+i]
+			}
+
+		case circ.REPLICATE:
+			for i := typ.Num(0); i < com.Y; i++ {
+				wires[com.To+i] = wires[com.X]
+			}
+
+		case circ.INPUT:
+			wires[com.To] = pop(com.X)
+
+		case circ.MASS_INPUT:
+			for i := typ.Num(0); i < com.Y; i++ {
+				wires[com.To+i] = pop(com.X)
+			}
+
+		case circ.OUTPUT:
+			hasOutput[com.To] = true
+			outputs[com.To] = append(outputs[com.To], wires[com.X])
+
+		case circ.MASS_OUTPUT:
+			hasOutput[com.To] = true
+			outputs[com.To] = append(outputs[com.To], wires[com.X:com.X+com.Y]...)
+
+		default:
+			if com.IsGate() {
+				wires[com.To] = slicedGate(com.Gate(), wires[com.X], wires[com.Y])
+			} else {
+				fmt.Println("Error: unrecognized command type")
+			}
+		}
+	}
+
+	results := make([][]*circ.UserInOut, len(batch))
+	for j := range results {
+		results[j] = make([]*circ.UserInOut, parties)
+		for party, out := range outputs {
+			if !hasOutput[party] {
+				continue
+			}
+			uio := circ.NewUIO()
+			for _, w := range out {
+				uio.Add(w>>uint(j)&1 == 1)
+			}
+			results[j][party] = uio
+		}
+	}
+	return results
+}
+
+// slicedGate computes a gate for all the runs at once, bit 2a+b of the table
+// being the output of the gate for inputs a and b
+func slicedGate(table byte, a, b uint64) uint64 {
+	var r uint64
+	if table&1 != 0 {
+		r |= ^a & ^b
+	}
+	if table&2 != 0 {
+		r |= ^a & b
+	}
+	if table&4 != 0 {
+		r |= a & ^b
+	}
+	if table&8 != 0 {
+		r |= a & b
+	}
+	return r
+}
+
+// RandomInputs returns random inputs for all the parties of a circuit, of the
+// sizes of the input variables of the circuit
+func RandomInputs(C circ.Circuit, r *rand.Rand) []*circ.UserInOut {
+	inputs := make([]*circ.UserInOut, C.Parties)
+	for party := range inputs {
+		inputs[party] = circ.NewUIO()
+		if v := C.Inputs[party]; v != nil && v.Type != nil {
+			for i := typ.Num(0); i < v.Type.Size(); i++ {
+				inputs[party].Add(r.Intn(2) == 1)
+			}
+		}
+	}
+	return inputs
+}
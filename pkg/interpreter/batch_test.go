@@ -0,0 +1,89 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+)
+
+// randomBatch returns a number of random sets of inputs of a circuit
+func randomBatch(C circ.Circuit, runs int, r *rand.Rand) [][]*circ.UserInOut {
+	batch := make([][]*circ.UserInOut, runs)
+	for i := range batch {
+		batch[i] = RandomInputs(C, r)
+	}
+	return batch
+}
+
+// excluded are the test programs which are not run in batches, with the
+// reason why
+var excluded = map[string]string{
+	// the products of matrices take minutes to be compared with the runs
+	// one by one, more than a hundred of them
+	"test6_matrix16": "too long to run",
+	"test7_matrix64": "too long to run",
+}
+
+// TestInterpreteBatch compares the batches of runs of every test program of
+// the Tests directory to the runs one by one
+func TestInterpreteBatch(t *testing.T) {
+	fmt.Println("Starting TestInterpreteBatch")
+	paths, err := filepath.Glob("../../Tests/test*.js")
+	if err != nil || len(paths) == 0 {
+		t.Fatal("no test program in ../../Tests", err)
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".js")
+		t.Run(name, func(t *testing.T) {
+			if reason, ok := excluded[name]; ok {
+				fmt.Println("Skipping", name+":", reason)
+				t.Skip(reason)
+			}
+			C, err := compiler.CircuitFromJS(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// more than one pass, the last one not using all the lanes
+			batch := randomBatch(C, Lanes+Lanes/2+3, r)
+			outputs := InterpreteBatch(C, batch)
+			if len(outputs) != len(batch) {
+				t.Fatalf("%s: %d outputs for %d runs", name, len(outputs), len(batch))
+			}
+			for i, inputs := range batch {
+				expected := Interprete(C, inputs)
+				for party := range expected {
+					if (expected[party] == nil) != (outputs[i][party] == nil) ||
+						expected[party] != nil && !expected[party].Equals(outputs[i][party]) {
+						t.Errorf("%s: outputs of party %d differ in run %d", name, party, i)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkInterprete(b *testing.B) {
+	C, _ := compiler.CircuitFromJS("../../Tests/test8_mult256.js")
+	batch := randomBatch(C, Lanes, rand.New(rand.NewSource(1)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, inputs := range batch {
+			Interprete(C, inputs)
+		}
+	}
+}
+
+func BenchmarkInterpreteBatch(b *testing.B) {
+	C, _ := compiler.CircuitFromJS("../../Tests/test8_mult256.js")
+	batch := randomBatch(C, Lanes, rand.New(rand.NewSource(1)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		InterpreteBatch(C, batch)
+	}
+}
@@ -1,10 +1,15 @@
 package interpreter
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	circ "ixxoprivacy/pkg/circuit"
 	typ "ixxoprivacy/pkg/types"
@@ -20,6 +25,25 @@ func GetAllInputs(inputs []*circ.Var, fileNames []string) []*circ.UserInOut {
 	return B
 }
 
+// A ValidationError is an input value which does not fit its type. Pointer
+// is the JSON pointer of the value in the input document, "" for the root.
+type ValidationError struct {
+	Pointer string
+	Msg     string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%q: %s", e.Pointer, e.Msg)
+}
+
+// invalid returns a ValidationError at a JSON pointer
+func invalid(pointer, format string, a ...interface{}) error {
+	return &ValidationError{Pointer: pointer, Msg: fmt.Sprintf(format, a...)}
+}
+
+// pointerToken escapes a key of an object to be used in a JSON pointer
+var pointerToken = strings.NewReplacer("~", "~0", "/", "~1")
+
 // FindInput visits variable declarations of a program parsed by the otto parser
 // in order to find the initial value of a variable whose name is provided.
 // This value is then converted into bytes representing booleans and written
@@ -31,99 +55,191 @@ func GetInput(fileName string, t *typ.Type) *circ.UserInOut {
 		os.Exit(1)
 	}
 
+	// the numbers are kept as written, to be exact whatever their size
 	var data interface{}
-	err = json.Unmarshal(raw, &data)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	err = dec.Decode(&data)
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
-	inp := circ.NewUIO()
-	dataToBuf(data, t, inp)
+	inp, err := EncodeInput(data, t)
+	if err != nil {
+		fmt.Println("Error in the input file", fileName, "at", err)
+		os.Exit(64)
+	}
 	return inp
 }
 
+// EncodeInput checks that a value decoded from JSON fits a type, and returns its bits
+func EncodeInput(data interface{}, t *typ.Type) (*circ.UserInOut, error) {
+	inp := circ.NewUIO()
+	if err := dataToBuf(data, t, inp, ""); err != nil {
+		return nil, err
+	}
+	return inp, nil
+}
+
 // dataToBuf takes an generic data and break it down into bits
-// in its auxiliary function to send it to the buffer buf
-func dataToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut) {
+// in its auxiliary function to send it to the buffer buf.
+// The pointer is the JSON pointer of the data in the input document.
+func dataToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut, pointer string) error {
 	switch t.BaseType {
 	case typ.VOID:
-		fmt.Println("Error, input with type VoidType")
+		return invalid(pointer, "input with type VoidType")
 	case typ.BOOL:
-		booleanToBuf(data, inp)
+		return booleanToBuf(data, inp, pointer)
 	case typ.INT, typ.UINT:
-		numberToBuf(data, t, inp)
+		return numberToBuf(data, t, inp, pointer)
 	case typ.ARRAY:
-		arrayToBuf(data, t, inp)
+		return arrayToBuf(data, t, inp, pointer)
 	case typ.OBJECT:
-		objectToBuf(data, t, inp)
-	default:
-		fmt.Println("Error in dataToBuf: base type unrecognized, value received is", t.BaseType)
+		return objectToBuf(data, t, inp, pointer)
 	}
+	return invalid(pointer, "base type unrecognized, value received is %d", t.BaseType)
 }
 
 // booleanToBuf sends a boolean encoded value as bits to the buffer buf
-func booleanToBuf(data interface{}, inp *circ.UserInOut) {
+func booleanToBuf(data interface{}, inp *circ.UserInOut, pointer string) error {
 	b, ok := data.(bool)
 	if !ok {
-		fmt.Println("Error in booleanToBuf, data does not fit as bool")
+		return invalid(pointer, "%s is no boolean", describe(data))
 	}
 	inp.Add(b)
+	return nil
 }
 
-// positiveToBuf sends an encoded integer value as bits to the buffer buf
-func numberToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut) {
-	fval, ok := data.(float64)
-	if !ok {
-		fmt.Println("Error in numberToBuf: data of uncompatible type")
+// numberToBuf sends an encoded integer value as bits to the buffer buf. The
+// integer is either a JSON number or a string holding a decimal or hexadecimal
+// integer, such as "-123" or "0x7b", and is written in two's complement.
+func numberToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut, pointer string) error {
+	val, err := ParseInteger(data)
+	if err != nil {
+		return invalid(pointer, "%v", err)
+	}
+	if min, max := t.Bounds(); val.Cmp(min) < 0 || val.Cmp(max) > 0 {
+		return invalid(pointer, "%v is out of the range [%v, %v] of %s", val, min, max, typeName(t))
 	}
 
-	val := int64(fval)
-	if val >= 0 {
-		intToBuf(val, t.L, inp)
-	} else {
-		if t.BaseType == typ.UINT {
-			fmt.Println("Error in numberToBuf: positive expected and received negative number")
+	if val.Sign() < 0 {
+		val.Add(val, new(big.Int).Lsh(big.NewInt(1), uint(t.L)))
+	}
+	intToBuf(val, t.L, inp)
+	return nil
+}
+
+// ParseInteger returns the integer held by a value decoded from JSON
+func ParseInteger(data interface{}) (*big.Int, error) {
+	switch v := data.(type) {
+	case float64:
+		if val, acc := big.NewFloat(v).Int(nil); acc == big.Exact {
+			return val, nil
+		}
+	case json.Number:
+		if val, ok := new(big.Int).SetString(string(v), 10); ok {
+			return val, nil
+		}
+		// numbers such as 1e3 or 2.0 are integers too
+		if f, ok := new(big.Float).SetString(string(v)); ok && f.IsInt() {
+			val, _ := f.Int(nil)
+			return val, nil
+		}
+	case string:
+		digits, base := strings.TrimPrefix(v, "-"), 10
+		if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+			digits, base = digits[2:], 16
+		}
+		// the sign is only allowed before the prefix
+		if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+			break
+		}
+		if val, ok := new(big.Int).SetString(digits, base); ok {
+			if strings.HasPrefix(v, "-") {
+				val.Neg(val)
+			}
+			return val, nil
 		}
-		intToBuf((1<<t.L)-val, t.L, inp)
 	}
+	return nil, fmt.Errorf("%s is no integer", describe(data))
 }
 
-// positiveToBuf sends an encoded integer value as bits to the buffer buf
-func intToBuf(val int64, size typ.Num, inp *circ.UserInOut) {
-	for i := typ.Num(0); i < size; i++ {
-		inp.Add(val&(1<<i) != 0)
+// intToBuf sends the size lowest bits of a non negative integer to the buffer buf
+func intToBuf(val *big.Int, size typ.Num, inp *circ.UserInOut) {
+	for i := 0; i < int(size); i++ {
+		inp.Add(val.Bit(i) == 1)
 	}
 }
 
 // arrayToBuf sends an array encoded as bits to the buffer buf
-func arrayToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut) {
+func arrayToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut, pointer string) error {
 	arr, ok := data.([]interface{})
 	if !ok {
-		fmt.Println("Error in arrayToBuf: data provided is no array")
-		os.Exit(64)
+		return invalid(pointer, "%s is no array", describe(data))
 	}
 	if len(arr) != int(t.L) {
-		fmt.Println("Error in arrayToBuf: the array has improper length")
-		os.Exit(64)
+		return invalid(pointer, "the array has %d items instead of %d", len(arr), t.L)
 	}
-	for _, val := range arr {
-		dataToBuf(val, t.SubType, inp)
+	for i, val := range arr {
+		if err := dataToBuf(val, t.SubType, inp, pointer+"/"+strconv.Itoa(i)); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // objectToBuf sends an object encoded as bits to the buffer buf
-func objectToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut) {
+func objectToBuf(data interface{}, t *typ.Type, inp *circ.UserInOut, pointer string) error {
 	obj, ok := data.(map[string]interface{})
 	if !ok {
-		fmt.Println("Error in objectToBuf: data provided is no object")
-		os.Exit(64)
-	}
-	if len(obj) != len(t.List) {
-		fmt.Println("Error in objectToBuf: the object has improper length")
-		os.Exit(64)
+		return invalid(pointer, "%s is no object", describe(data))
 	}
+	keys := make(map[string]bool)
 	for i, st := range t.List {
-		dataToBuf(obj[t.Keys[i]], st, inp)
+		keys[t.Keys[i]] = true
+		val, ok := obj[t.Keys[i]]
+		if !ok {
+			return invalid(pointer, "the key %q is missing", t.Keys[i])
+		}
+		if err := dataToBuf(val, st, inp, pointer+"/"+pointerToken.Replace(t.Keys[i])); err != nil {
+			return err
+		}
+	}
+	extra := make([]string, 0)
+	for key := range obj {
+		if !keys[key] {
+			extra = append(extra, key)
+		}
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		return invalid(pointer+"/"+pointerToken.Replace(extra[0]), "the object has no key %q", extra[0])
+	}
+	return nil
+}
+
+// describe returns a short description of a value decoded from JSON for the errors
+func describe(data interface{}) string {
+	switch data.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprint(data)
+	case float64, json.Number:
+		return fmt.Sprint(data)
+	case string:
+		return fmt.Sprintf("the string %q", data)
+	case []interface{}:
+		return "an array"
+	}
+	return "an object"
+}
+
+// typeName returns the name of an integer type for the errors
+func typeName(t *typ.Type) string {
+	if t.BaseType == typ.UINT {
+		return "uint" + strconv.Itoa(int(t.L))
 	}
+	return "int" + strconv.Itoa(int(t.L))
 }
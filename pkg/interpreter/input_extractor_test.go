@@ -0,0 +1,49 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	typ "ixxoprivacy/pkg/types"
+)
+
+func TestEncodeInput(t *testing.T) {
+	fmt.Println("Starting TestEncodeInput")
+	obj := typ.NewObjType()
+	obj.Keys = []string{"a/b", "list"}
+	obj.List = []*typ.Type{typ.BoolType, typ.NewArrayType(2, typ.NewIntType(8))}
+
+	cases := []struct {
+		input   string
+		pointer string // JSON pointer of the error, "-" when the input is valid
+	}{
+		{`{"a/b": true, "list": [1, -128]}`, "-"},
+		{`{"a/b": 1, "list": [1, 2]}`, "/a~1b"},
+		{`{"a/b": true, "list": [1, 128]}`, "/list/1"},
+		{`{"a/b": true, "list": [1.5, 2]}`, "/list/0"},
+		{`{"a/b": true, "list": [1]}`, "/list"},
+		{`{"a/b": true}`, ""},
+		{`{"a/b": true, "list": [1, 2], "c": 3}`, "/c"},
+		{`[true, [1, 2]]`, ""},
+	}
+	for _, c := range cases {
+		var data interface{}
+		if err := json.Unmarshal([]byte(c.input), &data); err != nil {
+			t.Fatal(err)
+		}
+		_, err := EncodeInput(data, obj)
+		if c.pointer == "-" {
+			if err != nil {
+				t.Errorf("%s: %v", c.input, err)
+			}
+			continue
+		}
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: got %v instead of an error at %q", c.input, err, c.pointer)
+		} else if verr.Pointer != c.pointer {
+			t.Errorf("%s: error %v at %q instead of %q", c.input, err, verr.Pointer, c.pointer)
+		}
+	}
+}
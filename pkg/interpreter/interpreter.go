@@ -12,73 +12,98 @@ var seeDetails bool = false
 // Interprete is the entry point of the interpreter package.
 // It runs all commands contained in a circuit in order.
 func Interprete(C circ.Circuit, origInputs []*circ.UserInOut) []*circ.UserInOut {
-	outputs := make([]*circ.UserInOut, len(origInputs)) // The output buffers, one for each receiving party
-	wires := make(map[typ.Num]bool)                     // Set of booleans representing the wires used during the execution
+	m := NewMachine(origInputs)
+	cur := C.Cursor()
+	for cur.Next() {
+		m.Exec(cur.Command())
+	}
+	return m.Outputs()
+}
+
+// A Machine runs the commands of a circuit one at a time, keeping the values
+// of the wires between them.
+type Machine struct {
+	wires   map[typ.Num]bool  // Set of booleans representing the wires used during the execution
+	inputs  []*circ.UserInOut // The inputs still to read
+	outputs []*circ.UserInOut // The output buffers, one for each receiving party
+}
 
-	inputs := make([]*circ.UserInOut, len(origInputs))
+// NewMachine returns a machine reading the inputs given, which are not modified
+func NewMachine(origInputs []*circ.UserInOut) *Machine {
+	m := &Machine{
+		wires:   make(map[typ.Num]bool),
+		inputs:  make([]*circ.UserInOut, len(origInputs)),
+		outputs: make([]*circ.UserInOut, len(origInputs)),
+	}
 	for i, inp := range origInputs {
-		inputs[i] = inp.Copy()
+		m.inputs[i] = inp.Copy()
+	}
+	return m
+}
+
+// Wire returns the value of a wire
+func (m *Machine) Wire(w typ.Num) bool {
+	return m.wires[w]
+}
+
+// Outputs returns the outputs written so far, nil for the parties without output
+func (m *Machine) Outputs() []*circ.UserInOut {
+	return m.outputs
+}
+
+// Exec runs a command which is not a function call
+func (m *Machine) Exec(com circ.Command) {
+	wires, inputs, outputs := m.wires, m.inputs, m.outputs
+	if seeDetails {
+		com.Print("")
 	}
 
-	var com circ.Command
-	chcom := make(chan circ.Command, 5)
-	go C.Visit(chcom, C.Funcs)
+	switch com.Kind {
+	case circ.EMPTY_COMMAND:
+		fmt.Println("Error: empty command found")
 
-	for k := uint32(0); k < C.XORgates+C.NonXORgates; k++ {
-		com = <-chcom
-		if seeDetails {
-			com.Print("")
+	case circ.COPY:
+		wires[com.To] = wires[com.X]
+
+	case circ.MASS_COPY:
+		for i := typ.Num(0); i < com.Y; i++ {
+			wires[com.To+i] = wires[com.X+i]
 		}
 
-		switch com.Kind {
-		case circ.EMPTY_COMMAND:
-			fmt.Println("Error: empty command found")
-
-		case circ.COPY:
-			wires[com.To] = wires[com.X]
-
-		case circ.MASS_COPY:
-			for i := typ.Num(0); i < com.Y; i++ {
-				wires[com.To+i] = wires[com.X+i]
-			}
-
-		case circ.INPUT:
-			wires[com.To] = inputs[com.X].Pop()
-
-		case circ.MASS_INPUT:
-			for i := typ.Num(0); i < com.Y; i++ {
-				wires[com.To+i] = inputs[com.X].Pop()
-			}
-
-		case circ.OUTPUT:
-			if outputs[com.To] == nil {
-				outputs[com.To] = circ.NewUIO()
-			}
-			outputs[com.To].Add(wires[com.X])
-
-		case circ.MASS_OUTPUT:
-			if outputs[com.To] == nil {
-				outputs[com.To] = circ.NewUIO()
-			}
-			for i := typ.Num(0); i < com.Y; i++ {
-				outputs[com.To].Add(wires[com.X+i])
-			}
-
-		case circ.REPLICATE:
-			for i := typ.Num(0); i < com.Y; i++ {
-				wires[com.To+i] = wires[com.X]
-			}
-
-		default:
-			if com.IsGate() {
-				wires[com.To] = 1<<(2*conv(wires[com.X])+conv(wires[com.Y]))&com.Gate() != 0
-			} else {
-				fmt.Println("Error: unrecognized command type")
-			}
+	case circ.INPUT:
+		wires[com.To] = inputs[com.X].Pop()
+
+	case circ.MASS_INPUT:
+		for i := typ.Num(0); i < com.Y; i++ {
+			wires[com.To+i] = inputs[com.X].Pop()
+		}
+
+	case circ.OUTPUT:
+		if outputs[com.To] == nil {
+			outputs[com.To] = circ.NewUIO()
+		}
+		outputs[com.To].Add(wires[com.X])
+
+	case circ.MASS_OUTPUT:
+		if outputs[com.To] == nil {
+			outputs[com.To] = circ.NewUIO()
+		}
+		for i := typ.Num(0); i < com.Y; i++ {
+			outputs[com.To].Add(wires[com.X+i])
+		}
+
+	case circ.REPLICATE:
+		for i := typ.Num(0); i < com.Y; i++ {
+			wires[com.To+i] = wires[com.X]
 		}
-	}
 
-	return outputs
+	default:
+		if com.IsGate() {
+			wires[com.To] = 1<<(2*conv(wires[com.X])+conv(wires[com.Y]))&com.Gate() != 0
+		} else {
+			fmt.Println("Error: unrecognized command type")
+		}
+	}
 }
 
 // conv is an auxiliary function to convert from bool to uint
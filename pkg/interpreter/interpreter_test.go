@@ -1,81 +1,159 @@
 package interpreter
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
 	"testing"
 
-	"github.com/robertkrimen/otto/ast"
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
 )
 
-var bl0 ast.BooleanLiteral = ast.BooleanLiteral{
-	Literal: "false",
-	Value:   false,
+// bits returns the bits of a string of 0 and 1
+func bits(s string) *circ.UserInOut {
+	uio := circ.NewUIO()
+	for _, c := range s {
+		uio.Add(c == '1')
+	}
+	return uio
 }
 
-var bl1 ast.BooleanLiteral = ast.BooleanLiteral{
-	Literal: "true",
-	Value:   true,
-}
-
-var nl1 ast.NumberLiteral = ast.NumberLiteral{
-	Literal: "17",
-	Value:   int64(17),
-}
-
-var nl2 ast.NumberLiteral = ast.NumberLiteral{
-	Literal: "127",
-	Value:   int64(127),
-}
-
-var nl3 ast.NumberLiteral = ast.NumberLiteral{
-	Literal: "132",
-	Value:   int64(132),
-}
-
-var al ast.ArrayLiteral = ast.ArrayLiteral{
-	Value: []ast.Expression{&nl1, &nl2},
-}
-
-var ol ast.ObjectLiteral = ast.ObjectLiteral{
-	Value: []ast.Property{
-		ast.Property{Value: &bl0},
-		ast.Property{Value: &nl3},
-		ast.Property{Value: &al},
-	},
+// encode returns the bits of a JSON value decoded as an input file is
+func encode(t *testing.T, raw string, tp *typ.Type) *circ.UserInOut {
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatal(err)
+	}
+	inp, err := EncodeInput(data, tp)
+	if err != nil {
+		t.Fatalf("%s: %v", raw, err)
+	}
+	return inp
 }
 
 func TestBooleanToBuf(t *testing.T) {
 	fmt.Println("Starting TestBooleanToBuf")
-	buf := new(bytes.Buffer)
-	expressionToBuf(&bl1, buf)
-	PrintBuf(buf)
-	fmt.Println()
+	if inp := encode(t, "true", typ.BoolType); !inp.Equals(bits("1")) {
+		t.Errorf("true is encoded as %v", *inp)
+	}
 }
 
 func TestNumberToBuf(t *testing.T) {
-	intSize = 8
 	fmt.Println("Starting TestNumberToBuf")
-	buf := new(bytes.Buffer)
-	expressionToBuf(&nl1, buf)
-	PrintBuf(buf)
-	fmt.Println()
+	int8t := typ.NewIntType(8)
+	for raw, want := range map[string]string{
+		`17`:     "10001000",
+		`-1`:     "11111111",
+		`-128`:   "00000001",
+		`-3`:     "10111111",
+		`"0x7f"`: "11111110",
+		`"-0x2"`: "01111111",
+		`"-17"`:  "11110111",
+	} {
+		if inp := encode(t, raw, int8t); !inp.Equals(bits(want)) {
+			t.Errorf("%s is encoded as %v instead of %s", raw, *inp, want)
+		}
+	}
+	for _, raw := range []string{`"0x-2"`, `"--2"`, `"1.5"`, `"017x"`, `128`, `-129`} {
+		var data interface{}
+		json.Unmarshal([]byte(raw), &data)
+		if _, err := EncodeInput(data, int8t); err == nil {
+			t.Errorf("%s is encoded as an int8", raw)
+		}
+	}
 }
 
 func TestArrayToBuf(t *testing.T) {
-	intSize = 8
 	fmt.Println("Starting TestArrayToBuf")
-	buf := new(bytes.Buffer)
-	expressionToBuf(&al, buf)
-	PrintBuf(buf)
-	fmt.Println()
+	at := typ.NewArrayType(2, typ.NewIntType(8))
+	if inp := encode(t, "[17, 127]", at); !inp.Equals(bits("1000100011111110")) {
+		t.Errorf("[17, 127] is encoded as %v", *inp)
+	}
 }
 
 func TestObjectToBuf(t *testing.T) {
-	intSize = 8
 	fmt.Println("Starting TestObjectToBuf")
-	buf := new(bytes.Buffer)
-	expressionToBuf(&ol, buf)
-	PrintBuf(buf)
-	fmt.Println()
+	ot := typ.NewObjType()
+	ot.Keys = []string{"b", "n", "a"}
+	ot.List = []*typ.Type{typ.BoolType, typ.NewUIntType(8), typ.NewArrayType(2, typ.NewIntType(8))}
+	inp := encode(t, `{"a": [17, 127], "b": false, "n": 132}`, ot)
+	if !inp.Equals(bits("0" + "00100001" + "1000100011111110")) {
+		t.Errorf("the object is encoded as %v", *inp)
+	}
+}
+
+// printed returns what printData writes on the standard output
+func printed(t *testing.T, outp *circ.UserInOut, tp *typ.Type) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	printData(outp, tp)
+	os.Stdout = stdout
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	return string(out)
+}
+
+func TestPrintArray(t *testing.T) {
+	fmt.Println("Starting TestPrintArray")
+	at := typ.NewArrayType(2, typ.NewIntType(8))
+	if out := printed(t, bits("1000100011111110"), at); out != "[17, 127]\n" {
+		t.Errorf("[17, 127] is printed as %q", out)
+	}
+	ot := typ.NewObjType()
+	ot.Keys = []string{"b", "n"}
+	ot.List = []*typ.Type{typ.BoolType, typ.NewUIntType(8)}
+	if out := printed(t, bits("1"+"00100001"), ot); out != "{ b:true, n:132, }" {
+		t.Errorf("the object is printed as %q", out)
+	}
+}
+
+// TestBigIntegers checks that integers wider than 64 bits are encoded and
+// decoded without losing precision
+func TestBigIntegers(t *testing.T) {
+	fmt.Println("Starting TestBigIntegers")
+	int1024 := typ.NewIntType(1024)
+	uint256 := typ.NewUIntType(256)
+	min, max := int1024.Bounds()
+	cases := []struct {
+		raw  string
+		tp   *typ.Type
+		want string
+	}{
+		{`123456789012345678901234567890123456789`, int1024, "123456789012345678901234567890123456789"},
+		{`-123456789012345678901234567890123456789`, int1024, "-123456789012345678901234567890123456789"},
+		{`"-0xdeadbeefdeadbeefdeadbeefdeadbeef"`, int1024, "-295990755083049101712519384020072382191"},
+		{`"` + min.String() + `"`, int1024, min.String()},
+		{`"` + max.String() + `"`, int1024, max.String()},
+		{`"0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"`, uint256, "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+	}
+	for _, c := range cases {
+		dec := json.NewDecoder(strings.NewReader(c.raw))
+		dec.UseNumber()
+		var data interface{}
+		if err := dec.Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+		inp, err := EncodeInput(data, c.tp)
+		if err != nil {
+			t.Fatalf("%s: %v", c.raw, err)
+		}
+		got, ok := GetGoValue(inp, c.tp).(*big.Int)
+		if !ok || got.String() != c.want {
+			t.Errorf("%s is decoded as %v instead of %s", c.raw, got, c.want)
+		}
+		if raw, _ := json.Marshal(got); string(raw) != c.want {
+			t.Errorf("%s is written as %s", c.raw, raw)
+		}
+	}
+	if f := GetGoFloat(bits("00"+strings.Repeat("1", 65)), 67); f != float64(-4) {
+		t.Errorf("the 67 bits -4 is decoded as the float %v", f)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 
 	circ "ixxoprivacy/pkg/circuit"
@@ -37,9 +38,11 @@ func GetGoValue(outp *circ.UserInOut, t *typ.Type) interface{} {
 	return x
 }
 
+// GetGoInt returns a signed integer from the buffer, as an int64 up to 64
+// bits and as a *big.Int above
 func GetGoInt(outp *circ.UserInOut, size typ.Num) interface{} {
 	if size > 64 {
-		return GetGoFloat(outp, size)
+		return bigFromBuf(outp, size, true)
 	} else {
 		var x int64 = 0
 		for i := typ.Num(0); i < size-1; i++ {
@@ -55,26 +58,17 @@ func GetGoInt(outp *circ.UserInOut, size typ.Num) interface{} {
 	}
 }
 
+// GetGoFloat returns a signed integer from the buffer as the nearest float64
 func GetGoFloat(outp *circ.UserInOut, size typ.Num) interface{} {
-	var x float64 = 0
-	for i := typ.Num(0); i < size-1; i++ {
-		if (*outp)[i] {
-			tmp := 1 << i
-			x += float64(tmp)
-		}
-	}
-	// The negative part
-	if (*outp)[size-1] {
-		tmp := 1 << (size - 1)
-		x -= float64(tmp)
-	}
+	x, _ := new(big.Float).SetInt(bigFromBuf(outp, size, true)).Float64()
 	return x
 }
 
-// UIntFromBuf prints a non negative integer from the buffer
+// UIntFromBuf prints a non negative integer from the buffer, as an uint64 up
+// to 64 bits and as a *big.Int above
 func GetGoUInt(outp *circ.UserInOut, size typ.Num) interface{} {
 	if size > 64 {
-		return GetGoPosFloat(outp, size)
+		return bigFromBuf(outp, size, false)
 	} else {
 		var x uint64 = 0
 		for i := typ.Num(0); i < size; i++ {
@@ -86,21 +80,32 @@ func GetGoUInt(outp *circ.UserInOut, size typ.Num) interface{} {
 	}
 }
 
+// GetGoPosFloat returns a non negative integer from the buffer as the nearest float64
 func GetGoPosFloat(outp *circ.UserInOut, size typ.Num) interface{} {
-	var x float64 = 0
+	x, _ := new(big.Float).SetInt(bigFromBuf(outp, size, false)).Float64()
+	return x
+}
+
+// bigFromBuf returns the integer written with size bits in the buffer, in
+// two's complement when it is signed
+func bigFromBuf(outp *circ.UserInOut, size typ.Num, signed bool) *big.Int {
+	x := new(big.Int)
 	for i := typ.Num(0); i < size; i++ {
 		if (*outp)[i] {
-			tmp := 1 << i
-			x += float64(tmp)
+			x.SetBit(x, int(i), 1)
 		}
 	}
+	// The negative part
+	if signed && (*outp)[size-1] {
+		x.Sub(x, new(big.Int).Lsh(big.NewInt(1), uint(size)))
+	}
 	return x
 }
 
 func GetGoArray(outp *circ.UserInOut, len typ.Num, item_t *typ.Type) interface{} {
 	var x []interface{} = make([]interface{}, 0)
 	item_len := item_t.Size()
-	for i := typ.Num(0); i < len-1; i++ {
+	for i := typ.Num(0); i < len; i++ {
 		x = append(x, GetGoValue(outp.SubUIO(i*item_len, item_len), item_t))
 	}
 	return x
@@ -151,69 +156,36 @@ func printBool(outp *circ.UserInOut) {
 
 // IntFromBuf prints a (relative integer) from the buffer
 func printInt(outp *circ.UserInOut, size typ.Num) {
-	if size > 64 {
-		printFloat(outp, size)
-	} else {
-		x := 0
-		for i := typ.Num(0); i < size-1; i++ {
-			if (*outp)[i] {
-				x += 1 << i
-			}
-		}
-		// The negative part
-		if (*outp)[size-1] {
-			x -= 1 << (size - 1)
-		}
-		fmt.Print(x)
-	}
+	fmt.Print(GetGoInt(outp, size))
 }
 
 // UIntFromBuf prints a non negative integer from the buffer
 func printUInt(outp *circ.UserInOut, size typ.Num) {
-	x := 0
-	for i := typ.Num(0); i < size; i++ {
-		if (*outp)[i] {
-			x += 1 << i
-		}
-	}
-	fmt.Print(x)
-}
-
-// IntFromBuf prints a big number from the buffer
-func printFloat(outp *circ.UserInOut, size typ.Num) {
-	x := float64(0)
-	for i := typ.Num(0); i < size-1; i++ {
-		if (*outp)[i] {
-			tmp := 1 << i
-			x += float64(tmp)
-		}
-	}
-	// The negative part
-	if (*outp)[size-1] {
-		tmp := 1 << (size - 1)
-		x -= float64(tmp)
-	}
-	fmt.Print(x)
+	fmt.Print(GetGoUInt(outp, size))
 }
 
 // ArrayFromBuf prints an array of a given type from the buffer
 func printArray(outp *circ.UserInOut, len typ.Num, item_t *typ.Type) {
+	item_len := item_t.Size()
 	fmt.Print("[")
-	for i := typ.Num(0); i < len-1; i++ {
-		printData(outp, item_t)
-		fmt.Print(", ")
+	for i := typ.Num(0); i < len; i++ {
+		if i > 0 {
+			fmt.Print(", ")
+		}
+		printData(outp.SubUIO(i*item_len, item_len), item_t)
 	}
-	printData(outp, item_t)
 	fmt.Println("]")
 }
 
 // ObjFromBuf prints an object of a given type from the buffer
 func printObj(outp *circ.UserInOut, t *typ.Type) {
+	var ind typ.Num = typ.Num(0)
 	fmt.Print("{ ")
 	for i, st := range t.List {
 		fmt.Print(t.Keys[i], ":")
-		printData(outp, st)
+		printData(outp.SubUIO(ind, st.Size()), st)
 		fmt.Print(", ")
+		ind += st.Size()
 	}
 	fmt.Print("}")
 }
@@ -0,0 +1,245 @@
+package optimizer
+
+import (
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// constants describes the wires of a circuit which hold the same value during
+// the whole evaluation: wires only written by GATE_0 or GATE_15 gates at the
+// beginning of the main function, before they are read and before any call.
+// The compiler creates two of them, for the values 0 and 1.
+type constants struct {
+	value map[typ.Num]bool // value of each constant wire
+	wire  [2]typ.Num       // a wire holding 0 and a wire holding 1
+	has   [2]bool          // whether such wires exist
+	pos   map[typ.Num]int  // position in the main function of the gate writing each constant wire
+}
+
+// findConstants looks for the constant wires of a circuit
+func findConstants(C *circ.Circuit) constants {
+	cs := constants{value: make(map[typ.Num]bool), pos: make(map[typ.Num]int)}
+	rejected := make(map[typ.Num]bool)
+	reject := func(w typ.Num) {
+		delete(cs.value, w)
+		rejected[w] = true
+	}
+
+	// candidates are written by a constant gate before any call of the main function
+	called := false
+	for i, com := range C.Commands {
+		if com.Kind == circ.FUNCTION_CALL {
+			called = true
+			continue
+		}
+		if !called && (com.Kind == circ.GATE_0 || com.Kind == circ.GATE_15) && !rejected[com.To] {
+			v := com.Kind == circ.GATE_15
+			if prev, ok := cs.value[com.To]; ok && prev != v {
+				reject(com.To)
+			} else if !ok {
+				cs.value[com.To] = v
+				cs.pos[com.To] = i
+			}
+			continue
+		}
+		if com.Kind == circ.GATE_0 || com.Kind == circ.GATE_15 {
+			// a constant wire may be written again with the same value
+			if v, ok := cs.value[com.To]; ok && v == (com.Kind == circ.GATE_15) {
+				continue
+			}
+		}
+		com.Reads(func(w typ.Num) {
+			if _, ok := cs.value[w]; !ok {
+				rejected[w] = true
+			}
+		})
+		com.Writes(reject)
+	}
+	// constant wires must not be written by any other function
+	for _, f := range C.Funcs {
+		for _, com := range f.Commands {
+			if com.Kind != circ.FUNCTION_CALL {
+				com.Writes(reject)
+			}
+		}
+	}
+	for w, v := range cs.value {
+		b := 0
+		if v {
+			b = 1
+		}
+		if !cs.has[b] || w < cs.wire[b] {
+			cs.wire[b], cs.has[b] = w, true
+		}
+	}
+	return cs
+}
+
+// available tells if a constant wire holding value v can be read at position i of function f
+func (cs constants) available(v bool, main bool, i int) bool {
+	b := 0
+	if v {
+		b = 1
+	}
+	return cs.has[b] && (!main || cs.pos[cs.wire[b]] < i)
+}
+
+func (cs constants) wireFor(v bool) typ.Num {
+	if v {
+		return cs.wire[1]
+	}
+	return cs.wire[0]
+}
+
+// evalTable returns the output of a gate for given input values
+func evalTable(table byte, a, b bool) bool {
+	i := 0
+	if a {
+		i += 2
+	}
+	if b {
+		i++
+	}
+	return table>>i&1 == 1
+}
+
+// A unary function of a single wire, obtained when the other input of a gate is known
+type unary byte
+
+const (
+	unaryZero unary = iota
+	unaryOne
+	unaryIdentity
+	unaryNot
+)
+
+func classify(f0, f1 bool) unary {
+	switch {
+	case !f0 && !f1:
+		return unaryZero
+	case f0 && f1:
+		return unaryOne
+	case !f0 && f1:
+		return unaryIdentity
+	}
+	return unaryNot
+}
+
+// rewriteUnary returns a command computing u(w) into wire to, using only free
+// commands, or false if the constant wires it would need are not available.
+func (cs constants) rewriteUnary(u unary, w, to typ.Num, main bool, i int) (circ.Command, bool) {
+	switch u {
+	case unaryZero, unaryOne:
+		v := u == unaryOne
+		if !cs.available(v, main, i) {
+			return circ.Command{}, false
+		}
+		return circ.Command{Kind: circ.COPY, X: cs.wireFor(v), To: to}, true
+	case unaryIdentity:
+		return circ.Command{Kind: circ.COPY, X: w, To: to}, true
+	}
+	if !cs.available(true, main, i) {
+		return circ.Command{}, false
+	}
+	return circ.Command{Kind: circ.GATE_6, X: w, Y: cs.wireFor(true), To: to}, true
+}
+
+// ConstantPropagation propagates the values of the constant wires of the
+// circuit through the gates, replacing the gates whose output is constant
+// or depends on a single of their inputs by free commands.
+type ConstantPropagation struct{}
+
+func (p *ConstantPropagation) Name() string {
+	return "constant propagation"
+}
+
+func (p *ConstantPropagation) Run(C *circ.Circuit) {
+	cs := findConstants(C)
+	usages := C.Usages()
+	for fi, f := range functions(C) {
+		main := fi == 0
+		known := make(map[typ.Num]bool) // wires whose value is known at this point
+		if !main {
+			// functions are only called once the constant wires are written
+			for w, v := range cs.value {
+				known[w] = v
+			}
+		}
+		for i, com := range f.Commands {
+			switch {
+			case com.Kind == circ.FUNCTION_CALL:
+				for w := range usages[com.X].Writes {
+					delete(known, w)
+				}
+
+			case com.Kind == circ.COPY:
+				if v, ok := known[com.X]; ok {
+					known[com.To] = v
+				} else {
+					delete(known, com.To)
+				}
+
+			case com.IsGate():
+				if v, ok := cs.value[com.To]; ok {
+					if main && cs.pos[com.To] == i {
+						known[com.To] = v
+						continue
+					}
+					if cs.available(v, main, i) {
+						// the constant wire already holds this value
+						f.Commands[i] = circ.Command{Kind: circ.EMPTY_COMMAND}
+						continue
+					}
+				}
+				newCom, value, isKnown := cs.simplify(com, known, main, i)
+				f.Commands[i] = newCom
+				if isKnown {
+					known[com.To] = value
+				} else {
+					delete(known, com.To)
+				}
+
+			default:
+				com.Writes(func(w typ.Num) {
+					delete(known, w)
+				})
+			}
+		}
+	}
+}
+
+// simplify rewrites a gate knowing the values of some wires. It returns the
+// new command, and the value of its output when it is known.
+func (cs constants) simplify(com circ.Command, known map[typ.Num]bool, main bool, i int) (circ.Command, bool, bool) {
+	table := com.Gate()
+	va, ka := known[com.X]
+	vb, kb := known[com.Y]
+	var u unary
+	var w typ.Num
+	switch {
+	case com.Kind == circ.GATE_0 || com.Kind == circ.GATE_15:
+		u = classify(com.Kind == circ.GATE_15, com.Kind == circ.GATE_15)
+	case ka && kb:
+		v := evalTable(table, va, vb)
+		u = classify(v, v)
+	case ka:
+		u, w = classify(evalTable(table, va, false), evalTable(table, va, true)), com.Y
+	case kb:
+		u, w = classify(evalTable(table, false, vb), evalTable(table, true, vb)), com.X
+	case com.X == com.Y:
+		u, w = classify(evalTable(table, false, false), evalTable(table, true, true)), com.X
+	default:
+		return com, false, false
+	}
+	newCom, ok := cs.rewriteUnary(u, w, com.To, main, i)
+	if !ok {
+		newCom = com
+	}
+	switch u {
+	case unaryZero:
+		return newCom, false, true
+	case unaryOne:
+		return newCom, true, true
+	}
+	return newCom, false, false
+}
@@ -0,0 +1,106 @@
+package optimizer
+
+import (
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// StructuralHashing numbers the values computed in every function of the
+// circuit and replaces the gates computing a value which is still held by a
+// wire by a copy of this wire. Gates read the first wire that received their
+// inputs, so that intermediate copies may then be removed.
+type StructuralHashing struct{}
+
+func (p *StructuralHashing) Name() string {
+	return "structural hashing"
+}
+
+// A gateKey identifies the value computed by a gate from the values of its inputs
+type gateKey struct {
+	table byte
+	a, b  int
+}
+
+// symmetric tells if a gate table gives the same result when its inputs are swapped
+func symmetric(table byte) bool {
+	return evalTable(table, false, true) == evalTable(table, true, false)
+}
+
+func (p *StructuralHashing) Run(C *circ.Circuit) {
+	usages := C.Usages()
+	for _, f := range functions(C) {
+		next := 0
+		val := make(map[typ.Num]int)    // value held by each wire
+		holder := make(map[int]typ.Num) // first wire which received each value
+		gates := make(map[gateKey]int)  // values computed by the gates
+		fresh := func(w typ.Num) {
+			next++
+			val[w] = next
+			holder[next] = w
+		}
+		valueOf := func(w typ.Num) int {
+			if _, ok := val[w]; !ok {
+				fresh(w)
+			}
+			return val[w]
+		}
+		// canonical returns a wire holding the same value as w
+		canonical := func(w typ.Num) typ.Num {
+			v := valueOf(w)
+			if h := holder[v]; val[h] == v {
+				return h
+			}
+			holder[v] = w
+			return w
+		}
+
+		for i, com := range f.Commands {
+			switch {
+			case com.Kind == circ.FUNCTION_CALL:
+				for w := range usages[com.X].Writes {
+					fresh(w)
+				}
+
+			case com.Kind == circ.COPY:
+				com.X = canonical(com.X)
+				if com.X == com.To {
+					f.Commands[i] = circ.Command{Kind: circ.EMPTY_COMMAND}
+					continue
+				}
+				f.Commands[i] = com
+				val[com.To] = val[com.X]
+
+			case com.IsGate():
+				table := com.Gate()
+				key := gateKey{table: table}
+				if com.Kind != circ.GATE_0 && com.Kind != circ.GATE_15 {
+					com.X, com.Y = canonical(com.X), canonical(com.Y)
+					key.a, key.b = val[com.X], val[com.Y]
+					if symmetric(table) && key.a > key.b {
+						key.a, key.b = key.b, key.a
+					}
+				}
+				if v, ok := gates[key]; ok {
+					if h := holder[v]; val[h] == v {
+						if h == com.To {
+							f.Commands[i] = circ.Command{Kind: circ.EMPTY_COMMAND}
+						} else {
+							f.Commands[i] = circ.Command{Kind: circ.COPY, X: h, To: com.To}
+							val[com.To] = v
+						}
+						continue
+					}
+				}
+				f.Commands[i] = com
+				fresh(com.To)
+				gates[key] = val[com.To]
+
+			case com.Kind == circ.OUTPUT:
+				f.Commands[i].X = canonical(com.X)
+
+			default:
+				com.Writes(fresh)
+			}
+		}
+	}
+}
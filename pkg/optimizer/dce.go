@@ -0,0 +1,120 @@
+package optimizer
+
+import (
+	circ "ixxoprivacy/pkg/circuit"
+)
+
+// DeadGateElimination removes the gates and copies whose result is never used
+// to compute an output of the circuit, and the calls to functions left empty.
+// Inputs are always kept since they define the format of the circuit.
+type DeadGateElimination struct{}
+
+func (p *DeadGateElimination) Name() string {
+	return "dead gate elimination"
+}
+
+// liveness holds the wires which are live after the calls to every function
+type liveness struct {
+	C       *circ.Circuit
+	sums    []summary
+	liveOut []wireSet
+	changed bool
+	// keepDead makes the gates whose result is not used read their inputs,
+	// as they do when they are not removed from the circuit
+	keepDead bool
+}
+
+// solveLiveness computes the wires live after each function of a circuit
+func solveLiveness(C *circ.Circuit, keepDead bool) *liveness {
+	l := &liveness{C: C, sums: summaries(C), liveOut: make([]wireSet, len(C.Funcs)), keepDead: keepDead}
+	for i := range C.Funcs {
+		l.liveOut[i] = newWireSet(C.TotalWires)
+	}
+	for l.changed = true; l.changed; {
+		l.changed = false
+		for fi, f := range functions(C) {
+			live := l.atEnd(fi)
+			for i := len(f.Commands) - 1; i >= 0; i-- {
+				l.step(f.Commands[i], live)
+			}
+		}
+	}
+	return l
+}
+
+// atEnd returns the wires live at the end of a function, given its index in functions(C)
+func (l *liveness) atEnd(fi int) wireSet {
+	if fi == 0 {
+		return newWireSet(l.C.TotalWires)
+	}
+	return l.liveOut[fi-1].copy()
+}
+
+// step transforms live, the set of wires live after command com, into the
+// set of wires live before it. It returns false when the command is useless.
+// The wires live after the calls to functions are gathered on the way.
+func (l *liveness) step(com circ.Command, live wireSet) bool {
+	switch {
+	case com.Kind == circ.FUNCTION_CALL:
+		s := l.sums[com.X]
+		if l.liveOut[com.X].union(live) {
+			l.changed = true
+		}
+		if com.Y > 1 && l.liveOut[com.X].union(s.gen) {
+			// the next iteration reads the wires written by the previous one
+			l.changed = true
+		}
+		live.minus(s.kill)
+		live.union(s.gen)
+		return true
+
+	case com.Kind == circ.COPY || com.IsGate():
+		if !live.has(com.To) && !l.keepDead {
+			return false
+		}
+		live.remove(com.To)
+		com.Reads(live.add)
+		return true
+
+	case com.Kind == circ.EMPTY_COMMAND:
+		return false
+	}
+	com.Writes(live.remove)
+	com.Reads(live.add)
+	return true
+}
+
+func (p *DeadGateElimination) Run(C *circ.Circuit) {
+	l := solveLiveness(C, false)
+	for fi, f := range functions(C) {
+		live := l.atEnd(fi)
+		for i := len(f.Commands) - 1; i >= 0; i-- {
+			if !l.step(f.Commands[i], live) {
+				f.Commands[i] = circ.Command{Kind: circ.EMPTY_COMMAND}
+			}
+		}
+	}
+	removeEmptyCalls(C)
+}
+
+// removeEmptyCalls removes the calls to functions which do nothing
+func removeEmptyCalls(C *circ.Circuit) {
+	empty := make([]bool, len(C.Funcs))
+	for changed := true; changed; {
+		changed = false
+		for fi, f := range functions(C) {
+			isEmpty := true
+			for i, com := range f.Commands {
+				if com.Kind == circ.FUNCTION_CALL && empty[com.X] {
+					f.Commands[i] = circ.Command{Kind: circ.EMPTY_COMMAND}
+				} else if com.Kind != circ.EMPTY_COMMAND {
+					isEmpty = false
+				}
+			}
+			if fi > 0 && isEmpty && !empty[fi-1] {
+				empty[fi-1] = true
+				changed = true
+			}
+		}
+	}
+}
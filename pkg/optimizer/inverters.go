@@ -0,0 +1,132 @@
+package optimizer
+
+import (
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+	wr "ixxoprivacy/pkg/wires"
+)
+
+// InverterChains replaces the inverters of the circuit by free XOR gates with
+// the constant wire holding 1, absorbs inversions into the tables of the gates
+// reading them and removes double inversions. It also rewrites the gates
+// depending on a single input and the XNOR gates, which only need free gates.
+type InverterChains struct{}
+
+func (p *InverterChains) Name() string {
+	return "inverter chains"
+}
+
+// An inversion records that a wire holds the negation of the value which
+// wire src had at a given version.
+type inversion struct {
+	src     typ.Num
+	version int
+}
+
+func (p *InverterChains) Run(C *circ.Circuit) {
+	cs := findConstants(C)
+	usages := C.Usages()
+	for fi, f := range functions(C) {
+		main := fi == 0
+		inv := make(map[typ.Num]inversion)
+		version := make(map[typ.Num]int)
+		written := func(w typ.Num) {
+			version[w]++
+			delete(inv, w)
+		}
+		// source returns the wire whose negation is held by w, if it is still valid
+		source := func(w typ.Num) (typ.Num, bool) {
+			in, ok := inv[w]
+			if !ok || version[in.src] != in.version {
+				return 0, false
+			}
+			return in.src, true
+		}
+		isOne := func(w typ.Num, i int) bool {
+			v, ok := cs.value[w]
+			return ok && v && (!main || cs.pos[w] < i)
+		}
+
+		coms := make([]circ.Command, 0, len(f.Commands))
+		for i, com := range f.Commands {
+			if com.Kind == circ.FUNCTION_CALL {
+				for w := range usages[com.X].Writes {
+					written(w)
+				}
+				coms = append(coms, com)
+				continue
+			}
+			if !com.IsGate() || com.Kind == circ.GATE_0 || com.Kind == circ.GATE_15 {
+				com.Writes(written)
+				coms = append(coms, com)
+				continue
+			}
+
+			table, x, y := com.Gate(), com.X, com.Y
+			if table != 6 {
+				// absorbing an inversion into a XOR gate would make it an expensive XNOR
+				if s, ok := source(x); ok {
+					x, table = s, wr.InvertTable(false, table)
+				}
+				if s, ok := source(y); ok {
+					y, table = s, wr.InvertTable(true, table)
+				}
+			} else {
+				sx, okx := source(x)
+				sy, oky := source(y)
+				switch {
+				case okx && oky:
+					x, y = sx, sy
+				case okx && isOne(y, i):
+					com = circ.Command{Kind: circ.COPY, X: sx, To: com.To}
+				case oky && isOne(x, i):
+					com = circ.Command{Kind: circ.COPY, X: sy, To: com.To}
+				}
+				if com.Kind == circ.COPY {
+					written(com.To)
+					coms = append(coms, com)
+					continue
+				}
+			}
+
+			var notOf typ.Num // wire whose negation is written, if any
+			negation := false
+			newComs := []circ.Command{{Kind: circ.GATE_0 + circ.CommandType(table), X: x, Y: y, To: com.To}}
+			one := cs.wire[1]
+			hasOne := cs.available(true, main, i)
+			switch table {
+			case 12:
+				newComs = []circ.Command{{Kind: circ.COPY, X: x, To: com.To}}
+			case 10:
+				newComs = []circ.Command{{Kind: circ.COPY, X: y, To: com.To}}
+			case 3, 5:
+				notOf, negation = x, true
+				if table == 5 {
+					notOf = y
+				}
+				if hasOne {
+					newComs = []circ.Command{{Kind: circ.GATE_6, X: notOf, Y: one, To: com.To}}
+				}
+			case 9:
+				if hasOne {
+					newComs = []circ.Command{
+						{Kind: circ.GATE_6, X: x, Y: y, To: com.To},
+						{Kind: circ.GATE_6, X: com.To, Y: one, To: com.To},
+					}
+				}
+			case 6:
+				if isOne(y, i) {
+					notOf, negation = x, true
+				} else if isOne(x, i) {
+					notOf, negation = y, true
+				}
+			}
+			written(com.To)
+			if negation && notOf != com.To {
+				inv[com.To] = inversion{notOf, version[notOf]}
+			}
+			coms = append(coms, newComs...)
+		}
+		f.Commands = coms
+	}
+}
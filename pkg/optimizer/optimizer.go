@@ -0,0 +1,214 @@
+// Package optimizer rewrites compiled circuits in order to reduce their number
+// of non-XOR gates, which are the expensive ones to garble and to evaluate.
+// It works on circuits produced by the compiler, as a series of passes run by
+// a pass manager.
+package optimizer
+
+import (
+	"fmt"
+	"io"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// A Pass is a transformation of a circuit which preserves its semantics.
+// Passes work on circuits whose copies have been expanded into single COPY
+// commands, and may leave EMPTY_COMMAND commands in place of removed commands.
+type Pass interface {
+	Name() string
+	Run(C *circ.Circuit)
+}
+
+// A Report gives the gate counts of the circuit before and after a pass
+type Report struct {
+	Pass         string
+	NonXORBefore uint64
+	NonXORAfter  uint64
+	XORBefore    uint64
+	XORAfter     uint64
+	WiresBefore  typ.Num
+	WiresAfter   typ.Num
+}
+
+// A Manager runs a list of passes over circuits
+type Manager struct {
+	Passes []Pass
+	// Rounds is the maximal number of times the whole list of passes is run,
+	// the manager stops earlier when a round does not remove any gate.
+	Rounds int
+}
+
+// NewManager returns a pass manager running the given passes once
+func NewManager(passes ...Pass) *Manager {
+	return &Manager{Passes: passes, Rounds: 1}
+}
+
+// DefaultPasses returns the passes used by Optimize, in the order they are run
+func DefaultPasses() []Pass {
+	return []Pass{
+		new(ConstantPropagation),
+		new(InverterChains),
+		new(StructuralHashing),
+		new(DeadGateElimination),
+	}
+}
+
+// Optimize runs the default passes on a circuit until they do not improve it
+// anymore, then renumbers its wires, and returns the reports of every pass run.
+func Optimize(C *circ.Circuit) []Report {
+	m := NewManager(DefaultPasses()...)
+	m.Rounds = 4
+	reports := m.Run(C)
+	return append(reports, NewManager(new(WireRenumbering)).Run(C)...)
+}
+
+// Run runs the passes of the manager on circuit C, which is modified in place.
+// The gate counts of the circuit and of its functions are kept up to date.
+func (m *Manager) Run(C *circ.Circuit) []Report {
+	reports := make([]Report, 0)
+	expandCopies(C)
+	nonXOR, xor := UpdateCounts(C)
+	for round := 0; round < m.Rounds; round++ {
+		startNonXOR, startXOR, startWires := nonXOR, xor, C.TotalWires
+		for _, p := range m.Passes {
+			r := Report{Pass: p.Name(), NonXORBefore: nonXOR, XORBefore: xor, WiresBefore: C.TotalWires}
+			p.Run(C)
+			nonXOR, xor = UpdateCounts(C)
+			r.NonXORAfter, r.XORAfter, r.WiresAfter = nonXOR, xor, C.TotalWires
+			reports = append(reports, r)
+		}
+		if nonXOR == startNonXOR && xor == startXOR && C.TotalWires == startWires {
+			break
+		}
+	}
+	compact(C)
+	UpdateCounts(C)
+	return reports
+}
+
+// Print writes a report in a human readable way
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "%-24s non-XOR %10d -> %10d (%+d), XOR %10d -> %10d (%+d), wires %8d -> %8d\n", r.Pass,
+		r.NonXORBefore, r.NonXORAfter, int64(r.NonXORAfter)-int64(r.NonXORBefore),
+		r.XORBefore, r.XORAfter, int64(r.XORAfter)-int64(r.XORBefore),
+		r.WiresBefore, r.WiresAfter)
+}
+
+// functions returns all the functions of a circuit, the main one first
+func functions(C *circ.Circuit) []*circ.Function {
+	return append([]*circ.Function{&C.Function}, C.Funcs...)
+}
+
+// UpdateCounts removes the empty commands of the functions of a circuit and
+// computes again their numbers of XOR and non-XOR gates.
+// It returns the numbers of non-XOR and XOR gates of the whole circuit.
+func UpdateCounts(C *circ.Circuit) (nonXOR, xor uint64) {
+	type counts struct{ xor, nonXOR uint64 }
+	done := make([]bool, len(C.Funcs))
+	cs := make([]counts, len(C.Funcs))
+	var countFunc func(f *circ.Function) counts
+	countFunc = func(f *circ.Function) counts {
+		var c counts
+		coms := f.Commands[:0]
+		for _, com := range f.Commands {
+			switch {
+			case com.Kind == circ.EMPTY_COMMAND:
+				continue
+			case com.Kind == circ.FUNCTION_CALL:
+				if !done[com.X] {
+					cs[com.X] = countFunc(C.Funcs[com.X])
+					done[com.X] = true
+				}
+				reps := uint64(1)
+				if com.Y > 0 {
+					reps = uint64(com.Y)
+				}
+				c.xor += reps * cs[com.X].xor
+				c.nonXOR += reps * cs[com.X].nonXOR
+			case com.IsGate() && com.Kind != circ.GATE_6:
+				c.nonXOR++
+			default:
+				c.xor++
+			}
+			coms = append(coms, com)
+		}
+		f.Commands = coms
+		f.XORgates, f.NonXORgates = uint32(c.xor), uint32(c.nonXOR)
+		return c
+	}
+	for i, f := range C.Funcs {
+		if !done[i] {
+			cs[i] = countFunc(f)
+			done[i] = true
+		}
+	}
+	c := countFunc(&C.Function)
+	return c.nonXOR, c.xor
+}
+
+// expandCopies replaces the MASS_COPY and REPLICATE commands of a circuit by
+// the equivalent series of COPY commands.
+func expandCopies(C *circ.Circuit) {
+	for _, f := range functions(C) {
+		coms := make([]circ.Command, 0, len(f.Commands))
+		for _, com := range f.Commands {
+			switch com.Kind {
+			case circ.MASS_COPY:
+				for i := typ.Num(0); i < com.Y; i++ {
+					coms = append(coms, circ.Command{Kind: circ.COPY, X: com.X + i, To: com.To + i})
+				}
+			case circ.REPLICATE:
+				for i := typ.Num(0); i < com.Y; i++ {
+					coms = append(coms, circ.Command{Kind: circ.COPY, X: com.X, To: com.To + i})
+				}
+			default:
+				coms = append(coms, com)
+			}
+		}
+		f.Commands = coms
+	}
+}
+
+// compact merges consecutive COPY, INPUT and OUTPUT commands into MASS_COPY,
+// REPLICATE, MASS_INPUT and MASS_OUTPUT commands, in the same way as the
+// function writer of the compiler does.
+func compact(C *circ.Circuit) {
+	for _, f := range functions(C) {
+		coms := make([]circ.Command, 0, len(f.Commands))
+		for _, com := range f.Commands {
+			if len(coms) > 0 {
+				prev := &coms[len(coms)-1]
+				switch {
+				case com.Kind == circ.INPUT && prev.Kind == circ.INPUT && prev.X == com.X && prev.To+1 == com.To:
+					prev.Kind, prev.Y = circ.MASS_INPUT, 2
+					continue
+				case com.Kind == circ.INPUT && prev.Kind == circ.MASS_INPUT && prev.X == com.X && prev.To+prev.Y == com.To:
+					prev.Y++
+					continue
+				case com.Kind == circ.OUTPUT && prev.Kind == circ.OUTPUT && prev.To == com.To && prev.X+1 == com.X:
+					prev.Kind, prev.Y = circ.MASS_OUTPUT, 2
+					continue
+				case com.Kind == circ.OUTPUT && prev.Kind == circ.MASS_OUTPUT && prev.To == com.To && prev.X+prev.Y == com.X:
+					prev.Y++
+					continue
+				case com.Kind != circ.COPY:
+				case prev.Kind == circ.COPY && prev.X+1 == com.X && prev.To+1 == com.To:
+					prev.Kind, prev.Y = circ.MASS_COPY, 2
+					continue
+				case prev.Kind == circ.COPY && prev.X == com.X && prev.To+1 == com.To:
+					prev.Kind, prev.Y = circ.REPLICATE, 2
+					continue
+				case prev.Kind == circ.MASS_COPY && prev.X+prev.Y == com.X && prev.To+prev.Y == com.To:
+					prev.Y++
+					continue
+				case prev.Kind == circ.REPLICATE && prev.X == com.X && prev.To+prev.Y == com.To:
+					prev.Y++
+					continue
+				}
+			}
+			coms = append(coms, com)
+		}
+		f.Commands = coms
+	}
+}
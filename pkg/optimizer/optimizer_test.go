@@ -0,0 +1,109 @@
+package optimizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+	ip "ixxoprivacy/pkg/interpreter"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// optimizeTest compiles a test program, optimizes it and checks that the
+// optimized circuit gives the same outputs as the original one.
+func optimizeTest(t *testing.T, testName string) {
+	fmt.Println("\t Optimizing", testName)
+	testNumber := strings.TrimPrefix(strings.SplitN(testName, "_", 2)[0], "test")
+	C1, err := compiler.CircuitFromJS("../../Tests/" + testName + ".js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	C2, _ := compiler.CircuitFromJS("../../Tests/" + testName + ".js")
+
+	inputFiles := make([]string, 0)
+	for i := 0; i < int(C1.Parties); i++ {
+		inputFiles = append(inputFiles, "../../Tests/entry"+testNumber+"-"+strconv.Itoa(i)+".json")
+	}
+	inputs := ip.GetAllInputs(C1.Inputs, inputFiles)
+
+	reports := Optimize(&C2)
+	for _, r := range reports {
+		r.Print(os.Stdout)
+	}
+	if C2.NonXORgates > C1.NonXORgates {
+		t.Errorf("%s: optimization increased the number of non-XOR gates from %d to %d",
+			testName, C1.NonXORgates, C2.NonXORgates)
+	}
+	if C2.TotalWires > C1.TotalWires {
+		t.Errorf("%s: renumbering increased the number of wires from %d to %d",
+			testName, C1.TotalWires, C2.TotalWires)
+	}
+	for _, f := range append([]*circ.Function{&C2.Function}, C2.Funcs...) {
+		for _, com := range f.Commands {
+			if com.Kind == circ.FUNCTION_CALL {
+				continue
+			}
+			check := func(w typ.Num) {
+				if w >= C2.TotalWires {
+					t.Fatalf("%s: wire %d used by a circuit of %d wires", testName, w, C2.TotalWires)
+				}
+			}
+			com.Reads(check)
+			com.Writes(check)
+		}
+	}
+
+	out1 := ip.Interprete(C1, inputs)
+	out2 := ip.Interprete(C2, inputs)
+	for party := range out1 {
+		if (out1[party] == nil) != (out2[party] == nil) ||
+			out1[party] != nil && !out1[party].Equals(out2[party]) {
+			t.Errorf("%s: outputs of party %d differ after optimization", testName, party)
+		}
+	}
+}
+
+// excluded are the test programs which are not optimized, with the reason
+// why
+var excluded = map[string]string{
+	// the matrices of 64x64 integers take minutes
+	"test7_matrix64": "too long to run",
+}
+
+// TestOptimize optimizes every test program of the Tests directory
+func TestOptimize(t *testing.T) {
+	fmt.Println("Starting TestOptimize")
+	paths, err := filepath.Glob("../../Tests/test*.js")
+	if err != nil || len(paths) == 0 {
+		t.Fatal("no test program in ../../Tests", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".js")
+		t.Run(name, func(t *testing.T) {
+			if reason, ok := excluded[name]; ok {
+				fmt.Println("Skipping", name+":", reason)
+				t.Skip(reason)
+			}
+			optimizeTest(t, name)
+		})
+	}
+}
+
+func TestWireSet(t *testing.T) {
+	fmt.Println("Starting TestWireSet")
+	s := newWireSet(130)
+	for _, w := range []typ.Num{0, 63, 64, 129} {
+		s.add(w)
+	}
+	s.remove(63)
+	got := make([]typ.Num, 0)
+	s.each(func(w typ.Num) { got = append(got, w) })
+	if fmt.Sprint(got) != "[0 64 129]" {
+		t.Errorf("wire set contains %v", got)
+	}
+}
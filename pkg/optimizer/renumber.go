@@ -0,0 +1,222 @@
+package optimizer
+
+import (
+	"container/heap"
+	"sort"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// WireRenumbering renumbers the wires of a circuit so that wires whose values
+// are never needed at the same time share the same number, which reduces
+// TotalWires and thus the memory needed to garble and evaluate the circuit.
+//
+// A wire keeps the same number in the whole circuit, so the commands of all
+// the functions are laid out one after the other and every wire is given the
+// span of this layout during which it may hold a useful value. The wires live
+// during a call, or between two iterations of a repeated call, cover the whole
+// span of the called function and of the functions it calls. Wires whose spans
+// do not overlap then receive the same number, as in a linear scan register
+// allocation. Wire 0, which is read by all constant gates, is kept in place.
+// The pass compacts the commands of the circuit, so it should be run last.
+type WireRenumbering struct{}
+
+func (p *WireRenumbering) Name() string {
+	return "wire renumbering"
+}
+
+func (p *WireRenumbering) Run(C *circ.Circuit) {
+	if C.TotalWires == 0 {
+		return
+	}
+	expandInOut(C)
+	funcs := functions(C)
+	for _, f := range funcs {
+		for i, com := range f.Commands {
+			if com.Kind == circ.GATE_0 || com.Kind == circ.GATE_15 {
+				// the value of the inputs of constant gates does not matter
+				f.Commands[i].X, f.Commands[i].Y = 0, 0
+			}
+		}
+	}
+
+	// layout of the functions, each command taking two positions: one where
+	// it reads its inputs and one where it writes its outputs
+	start := make([]int, len(funcs))
+	pos := 0
+	for fi, f := range funcs {
+		start[fi] = pos
+		pos += 2*len(f.Commands) + 2
+	}
+	lo, hi := spans(C, start)
+
+	l := solveLiveness(C, true)
+	first := make([]int, C.TotalWires)
+	last := make([]int, C.TotalWires)
+	for w := range first {
+		first[w], last[w] = pos, -1
+	}
+	extend := func(w typ.Num, a, b int) {
+		if a < first[w] {
+			first[w] = a
+		}
+		if b > last[w] {
+			last[w] = b
+		}
+	}
+	for fi, f := range funcs {
+		live := l.atEnd(fi)
+		end := start[fi] + 2*len(f.Commands)
+		live.each(func(w typ.Num) { extend(w, end, end) })
+		for i := len(f.Commands) - 1; i >= 0; i-- {
+			com := f.Commands[i]
+			p := start[fi] + 2*i
+			if com.Kind == circ.FUNCTION_CALL {
+				s := l.sums[com.X]
+				through := live.copy()
+				through.minus(s.kill)
+				through.each(func(w typ.Num) { extend(w, lo[com.X], hi[com.X]) })
+				s.kill.each(func(w typ.Num) { extend(w, p+1, p+1) })
+				s.gen.each(func(w typ.Num) { extend(w, p, p) })
+			} else {
+				com.Writes(func(w typ.Num) { extend(w, p+1, p+1) })
+				com.Reads(func(w typ.Num) { extend(w, p, p) })
+			}
+			l.step(com, live)
+		}
+		live.each(func(w typ.Num) { extend(w, start[fi], start[fi]) })
+	}
+
+	// linear scan over the spans
+	order := make([]typ.Num, 0, C.TotalWires)
+	for w := typ.Num(1); w < C.TotalWires; w++ {
+		if last[w] >= 0 {
+			order = append(order, w)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return first[order[i]] < first[order[j]]
+	})
+	renum := make([]typ.Num, C.TotalWires)
+	next := typ.Num(1)
+	free := make([]typ.Num, 0)
+	active := &spanHeap{}
+	for _, w := range order {
+		for active.Len() > 0 && (*active)[0].last < first[w] {
+			free = append(free, heap.Pop(active).(activeSpan).wire)
+		}
+		if len(free) > 0 {
+			renum[w] = free[len(free)-1]
+			free = free[:len(free)-1]
+		} else {
+			renum[w] = next
+			next++
+		}
+		heap.Push(active, activeSpan{last[w], renum[w]})
+	}
+
+	rename := func(w *typ.Num) {
+		*w = renum[*w]
+	}
+	for _, f := range funcs {
+		for i := range f.Commands {
+			com := &f.Commands[i]
+			switch {
+			case com.Kind == circ.COPY:
+				rename(&com.X)
+				rename(&com.To)
+			case com.Kind == circ.INPUT:
+				rename(&com.To)
+			case com.Kind == circ.OUTPUT:
+				rename(&com.X)
+			case com.IsGate():
+				rename(&com.X)
+				rename(&com.Y)
+				rename(&com.To)
+			}
+		}
+	}
+	for _, v := range append(append([]*circ.Var{}, C.Inputs...), C.Outputs...) {
+		if v != nil && v.Wirebase < C.TotalWires && last[v.Wirebase] >= 0 {
+			rename(&v.Wirebase)
+		}
+	}
+	C.TotalWires = next
+	compact(C)
+}
+
+// spans returns for every function the first and last positions of its
+// commands and of the commands of the functions it calls in the layout
+func spans(C *circ.Circuit, start []int) (lo, hi []int) {
+	lo = make([]int, len(C.Funcs))
+	hi = make([]int, len(C.Funcs))
+	done := make([]bool, len(C.Funcs))
+	var span func(i typ.Num)
+	span = func(i typ.Num) {
+		if done[i] {
+			return
+		}
+		done[i] = true
+		lo[i] = start[i+1]
+		hi[i] = start[i+1] + 2*len(C.Funcs[i].Commands)
+		for _, com := range C.Funcs[i].Commands {
+			if com.Kind == circ.FUNCTION_CALL {
+				span(com.X)
+				if lo[com.X] < lo[i] {
+					lo[i] = lo[com.X]
+				}
+				if hi[com.X] > hi[i] {
+					hi[i] = hi[com.X]
+				}
+			}
+		}
+	}
+	for i := range C.Funcs {
+		span(typ.Num(i))
+	}
+	return lo, hi
+}
+
+// expandInOut replaces the MASS_INPUT and MASS_OUTPUT commands of a circuit
+// by the equivalent series of INPUT and OUTPUT commands.
+func expandInOut(C *circ.Circuit) {
+	for _, f := range functions(C) {
+		coms := make([]circ.Command, 0, len(f.Commands))
+		for _, com := range f.Commands {
+			switch com.Kind {
+			case circ.MASS_INPUT:
+				for i := typ.Num(0); i < com.Y; i++ {
+					coms = append(coms, circ.Command{Kind: circ.INPUT, X: com.X, To: com.To + i})
+				}
+			case circ.MASS_OUTPUT:
+				for i := typ.Num(0); i < com.Y; i++ {
+					coms = append(coms, circ.Command{Kind: circ.OUTPUT, X: com.X + i, To: com.To})
+				}
+			default:
+				coms = append(coms, com)
+			}
+		}
+		f.Commands = coms
+	}
+}
+
+// An activeSpan is the span of a wire number still in use during the linear scan
+type activeSpan struct {
+	last int
+	wire typ.Num
+}
+
+// A spanHeap orders the active spans by the position where they end
+type spanHeap []activeSpan
+
+func (h spanHeap) Len() int            { return len(h) }
+func (h spanHeap) Less(i, j int) bool  { return h[i].last < h[j].last }
+func (h spanHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *spanHeap) Push(x interface{}) { *h = append(*h, x.(activeSpan)) }
+func (h *spanHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,87 @@
+package optimizer
+
+import (
+	"math/bits"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// A wireSet is a set of wires stored as a bitset
+type wireSet []uint64
+
+func newWireSet(totalWires typ.Num) wireSet {
+	return make(wireSet, (totalWires+63)/64)
+}
+
+func (s wireSet) has(w typ.Num) bool {
+	return s[w/64]&(1<<(w%64)) != 0
+}
+
+func (s wireSet) add(w typ.Num) {
+	s[w/64] |= 1 << (w % 64)
+}
+
+func (s wireSet) remove(w typ.Num) {
+	s[w/64] &^= 1 << (w % 64)
+}
+
+func (s wireSet) copy() wireSet {
+	return append(wireSet{}, s...)
+}
+
+// union adds the wires of s2 to s and tells if s was modified
+func (s wireSet) union(s2 wireSet) bool {
+	changed := false
+	for i, b := range s2 {
+		if s[i]|b != s[i] {
+			s[i] |= b
+			changed = true
+		}
+	}
+	return changed
+}
+
+// minus removes the wires of s2 from s
+func (s wireSet) minus(s2 wireSet) {
+	for i, b := range s2 {
+		s[i] &^= b
+	}
+}
+
+// each calls fn on every wire of the set in increasing order
+func (s wireSet) each(fn func(w typ.Num)) {
+	for i, b := range s {
+		for b != 0 {
+			j := bits.TrailingZeros64(b)
+			fn(typ.Num(i*64 + j))
+			b &^= 1 << j
+		}
+	}
+}
+
+// A summary describes the effect of a call to a function on the wires:
+// gen contains the wires read before being written, kill the wires written.
+// Since functions contain no branching, every wire of kill is written by all
+// calls to the function.
+type summary struct {
+	gen  wireSet
+	kill wireSet
+}
+
+// summaries computes the summaries of all functions of a circuit, with the
+// same indexes as C.Funcs
+func summaries(C *circ.Circuit) []summary {
+	usages := C.Usages()
+	sums := make([]summary, len(C.Funcs))
+	for i, u := range usages {
+		sums[i] = summary{newWireSet(C.TotalWires), newWireSet(C.TotalWires)}
+		for w := range u.Reads {
+			sums[i].gen.add(w)
+		}
+		for w := range u.Writes {
+			sums[i].kill.add(w)
+		}
+	}
+	return sums
+}
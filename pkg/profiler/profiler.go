@@ -0,0 +1,307 @@
+// Package profiler relates the gates of a compiled circuit to the lines of
+// the JavaScript source they come from, in order to find out which parts of
+// a program are expensive to garble.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+
+	"github.com/robertkrimen/otto/file"
+)
+
+// Counts stores a number of XOR and non-XOR gates.
+// As in the circuit structures, commands which are not gates (copies,
+// inputs, outputs...) are counted as XOR gates since they are free.
+type Counts struct {
+	XOR    uint64
+	NonXOR uint64
+}
+
+// Add adds the counts c2 multiplied by n to c
+func (c *Counts) Add(c2 Counts, n uint64) {
+	c.XOR += c2.XOR * n
+	c.NonXOR += c2.NonXOR * n
+}
+
+// A LineProfile contains the gates attributed to a line of the source.
+// Flat counts the gates written by the line itself, Cum also counts the gates
+// of the functions it calls.
+type LineProfile struct {
+	Flat Counts
+	Cum  Counts
+}
+
+// A Routine is a JavaScript function, or the main body of the program,
+// along with the gates which are attributed to it.
+type Routine struct {
+	Name       string
+	File       *file.File
+	Start, End int // first and last lines of the routine
+	Flat       Counts
+	Cum        Counts
+	Unknown    LineProfile // gates without source position, generated by the compiler
+	Lines      map[int]*LineProfile
+	nested     []*Routine // routines declared inside the span of this one
+}
+
+// A Profile gathers the gate counts of a whole circuit by routine and by line.
+type Profile struct {
+	Total    Counts
+	Routines []*Routine
+}
+
+// NewProfile computes the profile of circuit C using the source map sm
+// produced by the compiler along with it.
+func NewProfile(C circ.Circuit, sm compiler.SourceMap) *Profile {
+	funcs := append([]*circ.Function{&C.Function}, C.Funcs...)
+	sources := append([]compiler.FuncSource{sm.Main}, sm.Funcs...)
+	for i, f := range funcs {
+		if len(sources[i].Positions) != len(f.Commands) {
+			fmt.Println("Error in NewProfile: source map does not match the circuit")
+			return nil
+		}
+	}
+
+	// cumulated counts of each function for a single call
+	cum := make([]Counts, len(funcs))
+	done := make([]bool, len(funcs))
+	var cumOf func(i int) Counts
+	cumOf = func(i int) Counts {
+		if !done[i] {
+			for _, com := range funcs[i].Commands {
+				if com.Kind == circ.FUNCTION_CALL {
+					cum[i].Add(cumOf(int(com.X)+1), repetitions(com))
+				} else {
+					cum[i].Add(commandCounts(com), 1)
+				}
+			}
+			done[i] = true
+		}
+		return cum[i]
+	}
+
+	execs := executions(funcs)
+	p := &Profile{Total: cumOf(0)}
+	routines := make(map[string]*Routine)
+	for i, f := range funcs {
+		src := sources[i]
+		r := routines[src.Name]
+		if r == nil {
+			r = &Routine{Name: src.Name, Lines: make(map[int]*LineProfile)}
+			if fl := sm.File(src.Start); fl != nil {
+				r.File = fl
+				r.Start = sm.Position(src.Start).Line
+				r.End = r.Start
+				if pos := sm.Position(src.End - 1); pos != nil {
+					r.End = pos.Line
+				}
+			}
+			routines[src.Name] = r
+			p.Routines = append(p.Routines, r)
+		}
+		for j, com := range f.Commands {
+			var flat, total Counts
+			if com.Kind == circ.FUNCTION_CALL {
+				total.Add(cumOf(int(com.X)+1), repetitions(com))
+			} else {
+				flat = commandCounts(com)
+				total = flat
+			}
+			r.Flat.Add(flat, execs[i])
+			if !src.Loop {
+				r.Cum.Add(total, execs[i])
+			}
+			pos := sm.Position(src.Positions[j])
+			if src.Positions[j] == 0 || pos == nil {
+				r.Unknown.Flat.Add(flat, execs[i])
+				r.Unknown.Cum.Add(total, execs[i])
+				continue
+			}
+			lp := r.Lines[pos.Line]
+			if lp == nil {
+				lp = new(LineProfile)
+				r.Lines[pos.Line] = lp
+			}
+			lp.Flat.Add(flat, execs[i])
+			lp.Cum.Add(total, execs[i])
+		}
+	}
+
+	// the functions declared in a file are not listed with its main body
+	for _, r := range p.Routines {
+		for _, r2 := range p.Routines {
+			if r2 != r && r2.File != nil && r2.File == r.File && r2.Start >= r.Start && r2.End <= r.End {
+				r.nested = append(r.nested, r2)
+			}
+		}
+	}
+	return p
+}
+
+// commandCounts returns the gates counted for a single command which is not
+// a function call
+func commandCounts(com circ.Command) Counts {
+	if com.IsGate() && com.Kind != circ.GATE_6 {
+		return Counts{NonXOR: 1}
+	}
+	return Counts{XOR: 1}
+}
+
+// repetitions returns the number of times the function called by com is executed
+func repetitions(com circ.Command) uint64 {
+	if com.Y > 0 {
+		return uint64(com.Y)
+	}
+	return 1
+}
+
+// executions returns how many times each function of funcs is executed during
+// an evaluation of the circuit, the main function having index 0.
+func executions(funcs []*circ.Function) []uint64 {
+	type call struct {
+		caller int
+		reps   uint64
+	}
+	callers := make([][]call, len(funcs))
+	for j, f := range funcs {
+		for _, com := range f.Commands {
+			if com.Kind == circ.FUNCTION_CALL {
+				callers[com.X+1] = append(callers[com.X+1], call{j, repetitions(com)})
+			}
+		}
+	}
+	execs := make([]uint64, len(funcs))
+	done := make([]bool, len(funcs))
+	var execsOf func(i int) uint64
+	execsOf = func(i int) uint64 {
+		if !done[i] {
+			if i == 0 {
+				execs[i] = 1
+			}
+			for _, c := range callers[i] {
+				execs[i] += execsOf(c.caller) * c.reps
+			}
+			done[i] = true
+		}
+		return execs[i]
+	}
+	for i := range funcs {
+		execsOf(i)
+	}
+	return execs
+}
+
+// Top prints the routines sorted by cumulated number of non-XOR gates,
+// in the manner of the top command of pprof.
+func (p *Profile) Top(w io.Writer) {
+	routines := append([]*Routine{}, p.Routines...)
+	sort.SliceStable(routines, func(i, j int) bool {
+		return routines[i].Cum.NonXOR > routines[j].Cum.NonXOR
+	})
+	fmt.Fprintf(w, "Total: %d non-XOR gates, %d XOR gates\n", p.Total.NonXOR, p.Total.XOR)
+	fmt.Fprintf(w, "%10s %7s %10s %7s %10s %10s  %s\n", "flat", "flat%", "cum", "cum%", "xor flat", "xor cum", "routine")
+	for _, r := range routines {
+		fmt.Fprintf(w, "%10d %6.2f%% %10d %6.2f%% %10d %10d  %s\n",
+			r.Flat.NonXOR, percent(r.Flat.NonXOR, p.Total.NonXOR),
+			r.Cum.NonXOR, percent(r.Cum.NonXOR, p.Total.NonXOR),
+			r.Flat.XOR, r.Cum.XOR, r.Name)
+	}
+}
+
+// List prints the source of the routines whose name matches the regular
+// expression given, each line being annotated with its non-XOR and XOR counts,
+// in the manner of the list command of pprof.
+func (p *Profile) List(w io.Writer, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, r := range p.Routines {
+		if !re.MatchString(r.Name) {
+			continue
+		}
+		found = true
+		r.list(w, p.Total)
+	}
+	if !found {
+		return fmt.Errorf("no routine matches %q", pattern)
+	}
+	return nil
+}
+
+func (r *Routine) list(w io.Writer, total Counts) {
+	fileName := "<unknown>"
+	if r.File != nil {
+		fileName = r.File.Name()
+	}
+	fmt.Fprintf(w, "ROUTINE ======================== %s in %s\n", r.Name, fileName)
+	fmt.Fprintf(w, "%10d %10d (non-XOR flat, cum) %.2f%% of Total\n", r.Flat.NonXOR, r.Cum.NonXOR, percent(r.Cum.NonXOR, total.NonXOR))
+	fmt.Fprintf(w, "%10d %10d (XOR flat, cum)\n", r.Flat.XOR, r.Cum.XOR)
+	if r.File == nil {
+		return
+	}
+	lines := strings.Split(r.File.Source(), "\n")
+	for l := r.Start; l <= r.End && l <= len(lines); l++ {
+		if r.isNested(l) {
+			continue
+		}
+		var lp LineProfile
+		if r.Lines[l] != nil {
+			lp = *r.Lines[l]
+		}
+		fmt.Fprintf(w, "%10s %10s %10s %10s %6d:%s\n",
+			count(lp.Flat.NonXOR), count(lp.Cum.NonXOR), count(lp.Flat.XOR), count(lp.Cum.XOR), l, lines[l-1])
+	}
+	if r.Unknown.Cum.NonXOR != 0 || r.Unknown.Cum.XOR != 0 {
+		fmt.Fprintf(w, "%10s %10s %10s %10s %6s (generated by the compiler)\n",
+			count(r.Unknown.Flat.NonXOR), count(r.Unknown.Cum.NonXOR), count(r.Unknown.Flat.XOR), count(r.Unknown.Cum.XOR), "?:")
+	}
+}
+
+// isNested tells whether a line belongs to a routine declared inside r
+func (r *Routine) isNested(l int) bool {
+	for _, r2 := range r.nested {
+		if l >= r2.Start && l <= r2.End {
+			return true
+		}
+	}
+	return false
+}
+
+func count(n uint64) string {
+	if n == 0 {
+		return "."
+	}
+	return fmt.Sprint(n)
+}
+
+func percent(n, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+// ProfileJS compiles the JavaScript file given and prints its profile to w,
+// listing the routines whose name matches pattern, or all of them if it is empty.
+func ProfileJS(fileName, pattern string, w io.Writer) error {
+	C, err := compiler.CircuitFromJS(fileName)
+	if err != nil {
+		return err
+	}
+	p := NewProfile(C, compiler.GetSourceMap())
+	if p == nil {
+		return fmt.Errorf("could not profile %s", fileName)
+	}
+	p.Top(w)
+	fmt.Fprintln(w)
+	return p.List(w, pattern)
+}
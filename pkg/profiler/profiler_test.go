@@ -0,0 +1,78 @@
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ixxoprivacy/pkg/compiler"
+)
+
+func TestProfile(t *testing.T) {
+	fmt.Println("Starting TestProfile")
+	C, err := compiler.CircuitFromJS("../../Tests/test1_pgcd.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewProfile(C, compiler.GetSourceMap())
+	if p == nil {
+		t.Fatal("source map does not match the circuit")
+	}
+	if p.Total.NonXOR != uint64(C.NonXORgates) || p.Total.XOR != uint64(C.XORgates) {
+		t.Errorf("totals %d/%d differ from the circuit %d/%d", p.Total.NonXOR, p.Total.XOR, C.NonXORgates, C.XORgates)
+	}
+
+	var sum Counts
+	for _, r := range p.Routines {
+		sum.Add(r.Flat, 1)
+	}
+	if sum != p.Total {
+		t.Errorf("flat counts of routines sum to %v instead of %v", sum, p.Total)
+	}
+
+	var out bytes.Buffer
+	if err := p.List(&out, "^aux$"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(out.String())
+	found := false
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasSuffix(line, "var result = b % a") {
+			found = !strings.HasPrefix(strings.TrimSpace(line), ".")
+		}
+	}
+	if !found {
+		t.Error("no non-XOR gate attributed to the modulo of aux")
+	}
+	p.Top(&out)
+
+	// the loop procedure is at its for statement, and the functions are
+	// not listed with the main body
+	out.Reset()
+	if err := p.List(&out, "^main$"); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(out.String())
+	var main, aux *Routine
+	for _, r := range p.Routines {
+		switch r.Name {
+		case "main":
+			main = r
+		case "aux":
+			aux = r
+		}
+	}
+	if main.Unknown.Flat.NonXOR > main.Flat.NonXOR || main.Unknown.Cum != main.Unknown.Flat {
+		t.Errorf("the gates of main without position are %v flat and %v cum", main.Unknown.Flat, main.Unknown.Cum)
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if strings.HasSuffix(line, "for(var $i = 0; $i < 10; $i++){") && (len(fields) < 2 || fields[1] != fmt.Sprint(aux.Cum.NonXOR)) {
+			t.Errorf("the for loop is listed as %q", line)
+		}
+		if strings.Contains(line, "function aux") {
+			t.Errorf("aux is listed with main: %q", line)
+		}
+	}
+}
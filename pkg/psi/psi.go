@@ -0,0 +1,325 @@
+// Package psi generates the programs computing the private set intersection
+// (PSI) of the sets of two or more parties, and the inputs of these programs.
+//
+// The sets are lists of distinct signed integers of a public width. Their
+// sizes are secret: every party pads its set to a public maximum size and
+// gives the number of its elements along with it, so that its input is
+//
+//	{"n": 3, "set": [12, -7, 40, 0, 0]}
+//
+// Every party receives the same output: either the intersection, as the
+// number of common elements followed by these elements in increasing order,
+// padded with zeros, or only the number of common elements.
+package psi
+
+import (
+	"fmt"
+	"strings"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+	ip "ixxoprivacy/pkg/interpreter"
+	typ "ixxoprivacy/pkg/types"
+
+	"github.com/robertkrimen/otto/parser"
+)
+
+// Method is the way a program finds the elements common to all the sets
+type Method int
+
+const (
+	// Pairwise compares every element of the party 0 with every element of
+	// the other parties: (N-1)*size^2 comparisons for N parties
+	Pairwise Method = iota
+	// SortCompare sorts the elements of all the parties together and compares
+	// the neighbours: about M*log(M)^2/4 compare-exchanges for M = N*size,
+	// which is cheaper than Pairwise for large sets
+	SortCompare
+)
+
+// methodNames are the names of the methods on the command line
+var methodNames = map[string]Method{
+	"pairwise": Pairwise,
+	"sort":     SortCompare,
+}
+
+// ParseMethod returns the method of a name, pairwise or sort
+func ParseMethod(name string) (Method, error) {
+	if m, ok := methodNames[name]; ok {
+		return m, nil
+	}
+	return 0, fmt.Errorf("unknown PSI method %q, use pairwise or sort", name)
+}
+
+func (m Method) String() string {
+	for name, m2 := range methodNames {
+		if m2 == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// Params are the public parameters of a PSI program
+type Params struct {
+	Parties     int    // number of parties, at least 2
+	Size        int    // maximum number of elements of a set
+	Width       int    // number of bits of the elements, at most 53 so that they are exact JavaScript numbers
+	Method      Method // way the common elements are found
+	Cardinality bool   // only the number of common elements is output
+}
+
+// Check returns an error when a program cannot be generated for the parameters
+func (p Params) Check() error {
+	switch {
+	case p.Parties < 2:
+		return fmt.Errorf("a PSI needs at least 2 parties, got %d", p.Parties)
+	case p.Size < 1:
+		return fmt.Errorf("the maximum size of the sets must be positive, got %d", p.Size)
+	case p.Width < 2 || p.Width > 53:
+		return fmt.Errorf("the width of the elements must be between 2 and 53 bits, got %d", p.Width)
+	case int64(p.Size) > p.max() || int64(p.Parties) > p.max():
+		return fmt.Errorf("the sizes and the number of parties must fit in %d bits", p.Width)
+	case p.Method != Pairwise && p.Method != SortCompare:
+		return fmt.Errorf("unknown PSI method %v", p.Method)
+	}
+	return nil
+}
+
+// max returns the largest element, which replaces the padding in the programs
+func (p Params) max() int64 {
+	return 1<<uint(p.Width-1) - 1
+}
+
+// Input is the input of a party to a PSI program
+type Input struct {
+	N   int           `json:"n"`
+	Set []interface{} `json:"set"`
+}
+
+// Pad returns the input of a party from its set, given as the values decoded
+// from a JSON list. The values are checked to be distinct integers of the
+// width of the elements.
+func (p Params) Pad(set []interface{}) (Input, error) {
+	if len(set) > p.Size {
+		return Input{}, fmt.Errorf("the set has %d elements, more than the maximum size %d", len(set), p.Size)
+	}
+	elemt := typ.NewIntType(typ.Num(p.Width))
+	min, max := elemt.Bounds()
+	seen := make(map[string]bool)
+	for i, v := range set {
+		val, err := ip.ParseInteger(v)
+		if err != nil {
+			return Input{}, fmt.Errorf("element %d: %v", i, err)
+		}
+		if val.Cmp(min) < 0 || val.Cmp(max) > 0 {
+			return Input{}, fmt.Errorf("element %d: %v is out of the range [%v, %v]", i, val, min, max)
+		}
+		if seen[val.String()] {
+			return Input{}, fmt.Errorf("element %d: %v is in the set twice", i, val)
+		}
+		seen[val.String()] = true
+	}
+	inp := Input{N: len(set), Set: make([]interface{}, p.Size)}
+	copy(inp.Set, set)
+	for i := len(set); i < p.Size; i++ {
+		inp.Set[i] = 0
+	}
+	return inp, nil
+}
+
+// Compile returns the circuit of the PSI program of the parameters
+func Compile(p Params) (circ.Circuit, error) {
+	src, err := Program(p)
+	if err != nil {
+		return circ.Circuit{}, err
+	}
+	prog, err := parser.ParseFile(nil, "psi.js", src, 0)
+	if err != nil {
+		return circ.Circuit{}, err
+	}
+	return compiler.CircuitFromAST(prog)
+}
+
+/*
+ * The programs replace the padding by the largest element $max. The elements
+ * common to all the sets are gathered in the array common, in which the other
+ * elements are replaced by $max too, so that sorting it puts the common
+ * elements first. $max itself may be in all the sets, and it is then the last
+ * common element after the sort.
+ */
+
+// Program returns the JavaScript program of a PSI
+func Program(p Params) (string, error) {
+	if err := p.Check(); err != nil {
+		return "", err
+	}
+	g := &generator{Params: p, declared: make(map[string]bool)}
+	output := "the intersection"
+	if p.Cardinality {
+		output = "the number of common elements"
+	}
+	g.line("// Private set intersection of %d parties, giving %s to every party.", p.Parties, output)
+	g.line("// Generated with: psi -parties %d -size %d -width %d -method %v%s", p.Parties, p.Size, p.Width, p.Method, map[bool]string{true: " -cardinality"}[p.Cardinality])
+	g.line("")
+	g.line("var $parties = %d", p.Parties)
+	g.line("var $intsize = %d", p.Width)
+	g.line("var $size = %d", p.Size)
+	g.line("var $max = %d", p.max())
+	g.line("")
+	for i := 0; i < p.Parties; i++ {
+		g.line("var in_%d = {n: 0, set: %s}", i, zeros(p.Size))
+	}
+	for i := 0; i < p.Parties; i++ {
+		if p.Cardinality {
+			g.line("var out_%d = 0", i)
+		} else {
+			g.line("var out_%d = {n: 0, set: %s}", i, zeros(p.Size))
+		}
+	}
+	g.line("")
+	if p.Method == Pairwise {
+		g.pairwise()
+	} else {
+		g.sortCompare()
+	}
+	g.output()
+	return g.String(), nil
+}
+
+// generator writes a program line by line
+type generator struct {
+	Params
+	strings.Builder
+	indent   int
+	declared map[string]bool
+}
+
+// loop writes the beginning of a loop on a dollar variable, which is declared
+// by its first loop only
+func (g *generator) loop(v, bound string) {
+	decl := "var "
+	if g.declared[v] {
+		decl = ""
+	}
+	g.declared[v] = true
+	g.line("for (%s%s = 0; %s < %s; %s++) {", decl, v, v, bound, v)
+}
+
+// line writes a line of the program, a line ending with { or beginning
+// with } changing the indentation
+func (g *generator) line(format string, a ...interface{}) {
+	s := fmt.Sprintf(format, a...)
+	if strings.HasPrefix(s, "}") {
+		g.indent--
+	}
+	if s != "" {
+		g.WriteString(strings.Repeat("\t", g.indent))
+	}
+	g.WriteString(s + "\n")
+	if strings.HasSuffix(s, "{") {
+		g.indent++
+	}
+}
+
+// zeros returns an array literal of n zeros
+func zeros(n int) string {
+	return "[" + strings.TrimSuffix(strings.Repeat("0, ", n), ", ") + "]"
+}
+
+// pairwise writes the search of the elements of the party 0 in the other sets
+func (g *generator) pairwise() {
+	g.line("// the elements of the party 0 found in all the other sets")
+	if !g.Cardinality {
+		g.line("var common = %s", zeros(g.Size))
+	}
+	g.line("var count = 0")
+	g.line("var found = false")
+	g.loop("$i", "$size")
+	g.line("var member = $i < in_0.n")
+	for p := 1; p < g.Parties; p++ {
+		g.line("found = false")
+		g.loop("$j", "$size")
+		g.line("found = found || ($j < in_%d.n && in_0.set[$i] == in_%d.set[$j])", p, p)
+		g.line("}")
+		g.line("member = member && found")
+	}
+	g.gather("$i", "in_0.set[$i]", "member")
+	g.line("}")
+	g.line("")
+}
+
+// sortCompare writes the search of the runs of equal elements of all the sets
+func (g *generator) sortCompare() {
+	all := g.Parties * g.Size
+	runs := all - g.Parties + 1
+	g.line("// the elements of all the sets, the padding being replaced by $max")
+	g.line("var all = %s", zeros(all))
+	g.line("var maxes = 0")
+	g.loop("$i", "$size")
+	for p := 0; p < g.Parties; p++ {
+		index := "$i"
+		if p > 0 {
+			index = fmt.Sprintf("%d + $i", p*g.Size)
+		}
+		g.line("all[%s] = $max", index)
+		g.line("if ($i < in_%d.n) {", p)
+		g.line("all[%s] = in_%d.set[$i]", index, p)
+		g.line("if (in_%d.set[$i] == $max) {", p)
+		g.line("maxes = maxes + 1")
+		g.line("}")
+		g.line("}")
+	}
+	g.line("}")
+	g.line("var sorted = Sort(all)")
+	g.line("")
+	g.line("// an element is in all the sets when it starts a run of $parties equal elements")
+	if !g.Cardinality {
+		g.line("var common = %s", zeros(runs))
+	}
+	g.line("var count = 0")
+	g.loop("$k", fmt.Sprint(runs))
+	g.line("var start = sorted[$k] != $max")
+	g.line("for (var $t = 1; $t < $parties; $t++) {")
+	g.line("start = start && sorted[$k] == sorted[$k + $t]")
+	g.line("}")
+	g.gather("$k", "sorted[$k]", "start")
+	g.line("}")
+	g.line("if (maxes == $parties) {")
+	g.line("count = count + 1")
+	g.line("}")
+	g.line("")
+}
+
+// gather writes the counting of an element, and its copy to the array common
+// when the intersection is output
+func (g *generator) gather(index, elem, cond string) {
+	if !g.Cardinality {
+		g.line("common[%s] = $max", index)
+	}
+	g.line("if (%s) {", cond)
+	if !g.Cardinality {
+		g.line("common[%s] = %s", index, elem)
+	}
+	g.line("count = count + 1")
+	g.line("}")
+}
+
+// output writes the assignment of the outputs of the parties
+func (g *generator) output() {
+	res := "count"
+	if !g.Cardinality {
+		res = "result"
+		g.line("var first = Sort(common)")
+		g.line("var result = {n: 0, set: %s}", zeros(g.Size))
+		g.line("result.n = count")
+		g.loop("$k", "$size")
+		g.line("if ($k < count) {")
+		g.line("result.set[$k] = first[$k]")
+		g.line("}")
+		g.line("}")
+	}
+	for i := 0; i < g.Parties; i++ {
+		g.line("out_%d = %s", i, res)
+	}
+}
@@ -0,0 +1,154 @@
+package psi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	circ "ixxoprivacy/pkg/circuit"
+	ip "ixxoprivacy/pkg/interpreter"
+)
+
+// intersection returns the output of a PSI program computed in the clear
+func intersection(p Params, sets [][]int64) interface{} {
+	common := make([]int64, 0)
+	for _, x := range sets[0] {
+		all := true
+		for _, set := range sets[1:] {
+			found := false
+			for _, y := range set {
+				found = found || x == y
+			}
+			all = all && found
+		}
+		if all {
+			common = append(common, x)
+		}
+	}
+	if p.Cardinality {
+		return len(common)
+	}
+	sort.Slice(common, func(i, j int) bool { return common[i] < common[j] })
+	res := Input{N: len(common), Set: make([]interface{}, p.Size)}
+	for i := range res.Set {
+		res.Set[i] = 0
+		if i < len(common) {
+			res.Set[i] = common[i]
+		}
+	}
+	return res
+}
+
+// randomSets returns sets of distinct elements taken in a small pool so that
+// they have elements in common, the largest element being in the pool too
+func randomSets(p Params, r *rand.Rand) [][]int64 {
+	pool := []int64{p.max(), -p.max() - 1, 0}
+	for len(pool) < p.Size+3 {
+		pool = append(pool, r.Int63n(2*p.max()+2)-p.max()-1)
+	}
+	sets := make([][]int64, p.Parties)
+	for i := range sets {
+		sets[i] = make([]int64, 0)
+		seen := make(map[int64]bool)
+		for _, k := range r.Perm(len(pool))[:r.Intn(p.Size+1)] {
+			if !seen[pool[k]] {
+				seen[pool[k]] = true
+				sets[i] = append(sets[i], pool[k])
+			}
+		}
+	}
+	return sets
+}
+
+// jsonValue returns a value as decoded from its JSON encoding
+func jsonValue(t *testing.T, v interface{}) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestProgram(t *testing.T) {
+	fmt.Println("Starting TestProgram")
+	r := rand.New(rand.NewSource(1))
+	for _, parties := range []int{2, 3} {
+		for _, method := range []Method{Pairwise, SortCompare} {
+			for _, card := range []bool{false, true} {
+				p := Params{Parties: parties, Size: 4, Width: 6, Method: method, Cardinality: card}
+				C, err := Compile(p)
+				if err != nil {
+					t.Fatalf("%+v: %v", p, err)
+				}
+				for run := 0; run < 50; run++ {
+					sets := randomSets(p, r)
+					inputs := make([]*circ.UserInOut, parties)
+					for i, set := range sets {
+						inp, err := p.Pad(jsonValue(t, set).([]interface{}))
+						if err != nil {
+							t.Fatalf("%v: %v", set, err)
+						}
+						if inputs[i], err = ip.EncodeInput(jsonValue(t, inp), C.Inputs[i].Type); err != nil {
+							t.Fatalf("%v: %v", inp, err)
+						}
+					}
+					want := jsonValue(t, intersection(p, sets))
+					for i, outp := range ip.Interprete(C, inputs) {
+						got := jsonValue(t, ip.GetGoValue(outp, C.Outputs[i].Type))
+						if !reflect.DeepEqual(got, want) {
+							t.Fatalf("%+v: output %v to party %d of the sets %v instead of %v", p, got, i, sets, want)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	fmt.Println("Starting TestPad")
+	p := Params{Parties: 2, Size: 3, Width: 8}
+	inp, err := p.Pad([]interface{}{float64(5), "-0x10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Input{N: 2, Set: []interface{}{float64(5), "-0x10", 0}}); !reflect.DeepEqual(inp, want) {
+		t.Errorf("the set is padded as %v instead of %v", inp, want)
+	}
+	for _, set := range [][]interface{}{
+		{float64(1), float64(2), float64(3), float64(4)},
+		{float64(128)},
+		{float64(3), "0x3"},
+		{true},
+	} {
+		if _, err := p.Pad(set); err == nil {
+			t.Errorf("the set %v is padded", set)
+		}
+	}
+}
+
+// TestTestsPrograms checks that the PSI programs of the Tests directory are
+// the ones generated for their parameters
+func TestTestsPrograms(t *testing.T) {
+	fmt.Println("Starting TestTestsPrograms")
+	for name, p := range map[string]Params{
+		"test11_psi.js":             {Parties: 2, Size: 6, Width: 16, Method: Pairwise},
+		"test12_psi_cardinality.js": {Parties: 3, Size: 5, Width: 16, Method: SortCompare, Cardinality: true},
+	} {
+		raw, err := ioutil.ReadFile("../../Tests/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if src, _ := Program(p); string(raw) != src {
+			t.Errorf("%s is not the program generated for %+v", name, p)
+		}
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"flag"
 	"fmt"
 	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/debugger"
 	garbler "ixxoprivacy/pkg/garbler"
 	ip "ixxoprivacy/pkg/interpreter"
+	"ixxoprivacy/pkg/transport"
+	"net"
 	"os"
 	"strings"
 	"time"
@@ -110,6 +113,17 @@ func RunCircuit(circuitfilename string, inputFiles []string) {
 	fmt.Println("Interpretation achieved in ", diff)
 }
 
+// DebugProgram compiles a JavaScript program and runs it statement by
+// statement, reading the commands of the debugger from the standard input.
+func DebugProgram(fileName string, inputFiles []string) {
+	d, err := debugger.NewFromFiles(fileName, inputFiles)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(64)
+	}
+	d.Run(os.Stdin, os.Stdout)
+}
+
 func GarbleCircuit(circuitFileName string) {
 	/*
 	 * First we process args
@@ -148,3 +162,64 @@ func GarbleCircuit(circuitFileName string) {
 		fmt.Println("Garbling achieved in ", diff)
 	}
 }
+
+// ServeCircuit waits for an evaluator on a network address, and runs with it
+// a circuit on the inputs of all the parties, the tables being streamed as
+// they are garbled. The outputs of the parties are printed once decoded.
+func ServeCircuit(address string, circuitFileName string, inputFiles []string, workers int) {
+	circuit := circ.RetrieveCircuit(circuitFileName)
+	if circuit.Parties != uint8(len(inputFiles)) {
+		fmt.Println("Error: number of argument doesn't match number of parties of the circuit.")
+		os.Exit(64)
+	}
+	inputs := ip.GetAllInputs(circuit.Inputs, inputFiles)
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		fmt.Println("Error in ServeCircuit:", err)
+		os.Exit(64)
+	}
+	defer ln.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		fmt.Println("Error in ServeCircuit:", err)
+		os.Exit(64)
+	}
+	defer conn.Close()
+
+	tStart := time.Now()
+	outputs, err := transport.Garble(conn, circuit, 8, workers, inputs)
+	if err != nil {
+		fmt.Println("Error in ServeCircuit:", err)
+		os.Exit(64)
+	}
+	for party := range outputs {
+		fmt.Println("Evaluated output to party", party)
+		ip.PrintResult(&outputs[party], circuit.Outputs[party].Type)
+	}
+	if printTime {
+		fmt.Println("Garbling and evaluation achieved in ", time.Now().Sub(tStart))
+	}
+}
+
+// EvaluateCircuit connects to a garbler serving a circuit with ServeCircuit
+// and evaluates the tables as they come, window being the number of bytes
+// of tables the garbler may send in advance.
+func EvaluateCircuit(address string, circuitFileName string, window int, workers int) {
+	circuit := circ.RetrieveCircuit(circuitFileName)
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		fmt.Println("Error in EvaluateCircuit:", err)
+		os.Exit(64)
+	}
+	defer conn.Close()
+
+	tStart := time.Now()
+	if err := transport.Evaluate(conn, circuit, window, workers); err != nil {
+		fmt.Println("Error in EvaluateCircuit:", err)
+		os.Exit(64)
+	}
+	if printTime {
+		fmt.Println("Evaluation achieved in ", time.Now().Sub(tStart))
+	}
+}
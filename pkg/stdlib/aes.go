@@ -0,0 +1,245 @@
+package stdlib
+
+import (
+	typ "ixxoprivacy/pkg/types"
+)
+
+/*
+ * AES128(key, block) encrypts a block of 16 bytes with a key of 16 bytes as
+ * in FIPS-197, the bytes being in the order of the standard. The key schedule
+ * is computed by the circuit, so that the key can be secret. The S-box is the
+ * circuit of Boyar and Peralta of depth 16, with 34 AND gates, the other steps
+ * being linear: AES128 costs 200 S-boxes, that is 6800 AND gates.
+ */
+
+func buildAES128(b *builder, intsize typ.Num) {
+	bytes := typ.NewArrayType(16, typ.NewIntType(intsize))
+	params := b.params(bytes, bytes)
+	key, state := words(params[0], 8), words(params[1], 8)
+
+	keys := b.expandKey(key)
+	state = b.addRoundKey(state, keys[0])
+	for round := 1; round <= 10; round++ {
+		for i := range state {
+			state[i] = b.sbox(state[i])
+		}
+		state = shiftRows(state)
+		if round < 10 {
+			state = b.mixColumns(state)
+		}
+		state = b.addRoundKey(state, keys[round])
+	}
+	b.ret(bytes, state)
+}
+
+// expandKey returns the 11 round keys of a key
+func (b *builder) expandKey(key []word) [][]word {
+	keys := [][]word{key}
+	rcon := uint64(1)
+	for round := 1; round <= 10; round++ {
+		prev := keys[round-1]
+		// the last column rotated, substituted and added to the round constant
+		t := []word{b.sbox(prev[13]), b.sbox(prev[14]), b.sbox(prev[15]), b.sbox(prev[12])}
+		t[0] = b.xorWords(t[0], constant(rcon, 8))
+		k := make([]word, 16)
+		for i := range k {
+			if i < 4 {
+				k[i] = b.xorWords(prev[i], t[i])
+			} else {
+				k[i] = b.xorWords(prev[i], k[i-4])
+			}
+		}
+		keys = append(keys, k)
+		rcon = xtimeByte(rcon)
+	}
+	return keys
+}
+
+func xtimeByte(x uint64) uint64 {
+	x <<= 1
+	if x&0x100 != 0 {
+		x ^= 0x11b
+	}
+	return x
+}
+
+func (b *builder) addRoundKey(state, key []word) []word {
+	next := make([]word, 16)
+	for i := range state {
+		next[i] = b.xorWords(state[i], key[i])
+	}
+	return next
+}
+
+// shiftRows rotates the row r of the state, made of the bytes r, r+4, r+8
+// and r+12, by r bytes to the left
+func shiftRows(state []word) []word {
+	next := make([]word, 16)
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			next[r+4*c] = state[r+4*((c+r)%4)]
+		}
+	}
+	return next
+}
+
+// xtime returns a byte multiplied by x in GF(2^8)
+func (b *builder) xtime(a word) word {
+	return word{a[7], b.xor(a[0], a[7]), a[1], b.xor(a[2], a[7]), b.xor(a[3], a[7]), a[4], a[5], a[6]}
+}
+
+func (b *builder) mixColumns(state []word) []word {
+	next := make([]word, 16)
+	for c := 0; c < 4; c++ {
+		col := state[4*c : 4*c+4]
+		all := b.xorWords(b.xorWords(col[0], col[1]), b.xorWords(col[2], col[3]))
+		for r := 0; r < 4; r++ {
+			next[4*c+r] = b.xorWords(b.xorWords(col[r], all), b.xtime(b.xorWords(col[r], col[(r+1)%4])))
+		}
+	}
+	return next
+}
+
+// sbox returns the byte substituted to a byte by the S-box of AES, with the
+// circuit of Boyar and Peralta in which U0 is the highest bit
+func (b *builder) sbox(x word) word {
+	U0, U1, U2, U3, U4, U5, U6, U7 := x[7], x[6], x[5], x[4], x[3], x[2], x[1], x[0]
+	xor, and := b.xor, b.and
+	xnor := func(x, y typ.Num) typ.Num { return b.not(b.xor(x, y)) }
+
+	// top linear transform
+	T1 := xor(U0, U3)
+	T2 := xor(U0, U5)
+	T3 := xor(U0, U6)
+	T4 := xor(U3, U5)
+	T5 := xor(U4, U6)
+	T6 := xor(T1, T5)
+	T7 := xor(U1, U2)
+	T8 := xor(U7, T6)
+	T9 := xor(U7, T7)
+	T10 := xor(T6, T7)
+	T11 := xor(U1, U5)
+	T12 := xor(U2, U5)
+	T13 := xor(T3, T4)
+	T14 := xor(T6, T11)
+	T15 := xor(T5, T11)
+	T16 := xor(T5, T12)
+	T17 := xor(T9, T16)
+	T18 := xor(U3, U7)
+	T19 := xor(T7, T18)
+	T20 := xor(T1, T19)
+	T21 := xor(U6, U7)
+	T22 := xor(T7, T21)
+	T23 := xor(T2, T22)
+	T24 := xor(T2, T10)
+	T25 := xor(T20, T17)
+	T26 := xor(T3, T16)
+	T27 := xor(T1, T12)
+
+	// shared non-linear part
+	M1 := and(T13, T6)
+	M2 := and(T23, T8)
+	M3 := xor(T14, M1)
+	M4 := and(T19, U7)
+	M5 := xor(M4, M1)
+	M6 := and(T3, T16)
+	M7 := and(T22, T9)
+	M8 := xor(T26, M6)
+	M9 := and(T20, T17)
+	M10 := xor(M9, M6)
+	M11 := and(T1, T15)
+	M12 := and(T4, T27)
+	M13 := xor(M12, M11)
+	M14 := and(T2, T10)
+	M15 := xor(M14, M11)
+	M16 := xor(M3, M2)
+	M17 := xor(M5, T24)
+	M18 := xor(M8, M7)
+	M19 := xor(M10, M15)
+	M20 := xor(M16, M13)
+	M21 := xor(M17, M15)
+	M22 := xor(M18, M13)
+	M23 := xor(M19, T25)
+	M24 := xor(M22, M23)
+	M25 := and(M22, M20)
+	M26 := xor(M21, M25)
+	M27 := xor(M20, M21)
+	M28 := xor(M23, M25)
+	M29 := and(M28, M27)
+	M30 := and(M26, M24)
+	M31 := and(M20, M23)
+	M32 := and(M27, M31)
+	M33 := xor(M27, M25)
+	M34 := and(M21, M22)
+	M35 := and(M24, M34)
+	M36 := xor(M24, M25)
+	M37 := xor(M21, M29)
+	M38 := xor(M32, M33)
+	M39 := xor(M23, M30)
+	M40 := xor(M35, M36)
+	M41 := xor(M38, M40)
+	M42 := xor(M37, M39)
+	M43 := xor(M37, M38)
+	M44 := xor(M39, M40)
+	M45 := xor(M42, M41)
+	M46 := and(M44, T6)
+	M47 := and(M40, T8)
+	M48 := and(M39, U7)
+	M49 := and(M43, T16)
+	M50 := and(M38, T9)
+	M51 := and(M37, T17)
+	M52 := and(M42, T15)
+	M53 := and(M45, T27)
+	M54 := and(M41, T10)
+	M55 := and(M44, T13)
+	M56 := and(M40, T23)
+	M57 := and(M39, T19)
+	M58 := and(M43, T3)
+	M59 := and(M38, T22)
+	M60 := and(M37, T20)
+	M61 := and(M42, T1)
+	M62 := and(M45, T4)
+	M63 := and(M41, T2)
+
+	// bottom linear transform
+	L0 := xor(M61, M62)
+	L1 := xor(M50, M56)
+	L2 := xor(M46, M48)
+	L3 := xor(M47, M55)
+	L4 := xor(M54, M58)
+	L5 := xor(M49, M61)
+	L6 := xor(M62, L5)
+	L7 := xor(M46, L3)
+	L8 := xor(M51, M59)
+	L9 := xor(M52, M53)
+	L10 := xor(M53, L4)
+	L11 := xor(M60, L2)
+	L12 := xor(M48, M51)
+	L13 := xor(M50, L0)
+	L14 := xor(M52, M61)
+	L15 := xor(M55, L1)
+	L16 := xor(M56, L0)
+	L17 := xor(M57, L1)
+	L18 := xor(M58, L8)
+	L19 := xor(M63, L4)
+	L20 := xor(L0, L1)
+	L21 := xor(L1, L7)
+	L22 := xor(L3, L12)
+	L23 := xor(L18, L2)
+	L24 := xor(L15, L9)
+	L25 := xor(L6, L10)
+	L26 := xor(L7, L9)
+	L27 := xor(L8, L10)
+	L28 := xor(L11, L14)
+	L29 := xor(L11, L17)
+
+	S0 := xor(L6, L24)
+	S1 := xnor(L16, L26)
+	S2 := xnor(L19, L28)
+	S3 := xor(L6, L21)
+	S4 := xor(L20, L22)
+	S5 := xor(L25, L29)
+	S6 := xnor(L13, L27)
+	S7 := xnor(L6, L23)
+	return word{S7, S6, S5, S4, S3, S2, S1, S0}
+}
@@ -0,0 +1,61 @@
+package stdlib
+
+import (
+	typ "ixxoprivacy/pkg/types"
+)
+
+/*
+ * ChaCha20Block(key, counter, nonce) returns the block of 16 words of
+ * keystream of ChaCha20 as in RFC 8439, for a key of 8 words, a block counter
+ * and a nonce of 3 words. The words are the little-endian words of the bytes
+ * of the RFC, a message being encrypted by XORing the keystream with it. The
+ * 20 rounds cost 320 additions modulo 2^32 of 31 AND gates each: a block
+ * costs 10,400 AND gates with the final additions.
+ */
+
+var chachaConstants = [4]uint64{0x61707865, 0x3320646e, 0x79622d32, 0x6b206574}
+
+func buildChaCha20Block(b *builder, intsize typ.Num) {
+	intt := typ.NewIntType(intsize)
+	params := b.params(typ.NewArrayType(8, intt), intt, typ.NewArrayType(3, intt))
+
+	init := make([]word, 0, 16)
+	for _, c := range chachaConstants {
+		init = append(init, constant(c, 32))
+	}
+	init = append(init, words(params[0], 32)...)
+	init = append(init, words(params[1], 32)...)
+	init = append(init, words(params[2], 32)...)
+
+	x := make([]word, 16)
+	copy(x, init)
+	quarterRound := func(a, c, d, e int) {
+		x[a] = b.add(x[a], x[c])
+		x[e] = rotl(b.xorWords(x[e], x[a]), 16)
+		x[d] = b.add(x[d], x[e])
+		x[c] = rotl(b.xorWords(x[c], x[d]), 12)
+		x[a] = b.add(x[a], x[c])
+		x[e] = rotl(b.xorWords(x[e], x[a]), 8)
+		x[d] = b.add(x[d], x[e])
+		x[c] = rotl(b.xorWords(x[c], x[d]), 7)
+	}
+	for i := 0; i < 10; i++ {
+		quarterRound(0, 4, 8, 12)
+		quarterRound(1, 5, 9, 13)
+		quarterRound(2, 6, 10, 14)
+		quarterRound(3, 7, 11, 15)
+		quarterRound(0, 5, 10, 15)
+		quarterRound(1, 6, 11, 12)
+		quarterRound(2, 7, 8, 13)
+		quarterRound(3, 4, 9, 14)
+	}
+	for i := range x {
+		x[i] = b.add(x[i], init[i])
+	}
+	b.ret(typ.NewArrayType(16, intt), x)
+}
+
+// rotl returns a word rotated left by k bits
+func rotl(x word, k int) word {
+	return rotr(x, len(x)-k)
+}
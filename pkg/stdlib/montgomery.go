@@ -0,0 +1,57 @@
+package stdlib
+
+import (
+	typ "ixxoprivacy/pkg/types"
+)
+
+/*
+ * MontMul(a, b, n) returns a * b / 2^k modulo n, where k is the $intsize, the
+ * integers being read as unsigned ones. The modulus n, which can be secret,
+ * must be odd and the operands must be less than n, the result being then
+ * less than n too. A product modulo n is the Montgomery product of the
+ * Montgomery product of a and b by 2^2k modulo n:
+ *
+ *	var ab = MontMul(MontMul(a, b, n), r2, n)
+ *
+ * The bits of a are added one at a time, with the multiple of n which makes
+ * the sum even before it is halved, and n is subtracted at the end when the
+ * result is not less than it: MontMul costs 4k^2 + 3k - 3 AND gates.
+ */
+
+func buildMontMul(b *builder, intsize typ.Num) {
+	intt := typ.NewIntType(intsize)
+	params := b.params(intt, intt, intt)
+	k := int(intsize)
+	x, y, n := words(params[0], k)[0], words(params[1], k)[0], words(params[2], k)[0]
+
+	// t is less than 2n, sums have two more bits than the operands
+	extend := func(w word) word { return append(append(word{}, w...), zero, zero) }
+	t := constant(0, k+2)
+	for i := 0; i < k; i++ {
+		xy := make(word, k)
+		for j := range xy {
+			xy[j] = b.and(x[i], y[j])
+		}
+		t = b.add(t, extend(xy))
+		// the lowest bit of t + q * n is 0, and its carry is q
+		q := t[0]
+		qn := make(word, k)
+		for j := range qn {
+			qn[j] = b.and(q, n[j])
+		}
+		t, _ = b.addCarry(t[1:], extend(qn)[1:], q)
+		t = append(t, zero)
+	}
+
+	// t - n is computed as t + ^n + 1, whose carry tells if t is not less than n
+	notn := make(word, k+2)
+	for j, w := range extend(n) {
+		notn[j] = b.not(w)
+	}
+	d, ge := b.addCarry(t, notn, one)
+	r := make(word, k)
+	for j := range r {
+		r[j] = b.mux(ge, d[j], t[j])
+	}
+	b.ret(intt, []word{r})
+}
@@ -0,0 +1,64 @@
+package stdlib
+
+import (
+	typ "ixxoprivacy/pkg/types"
+)
+
+/*
+ * SHA256Compress(state, block) returns the state of 8 words of SHA-256 after
+ * a block of 16 words of the message, as in FIPS 180-4. The hash of a message
+ * is the state after its last block, starting from the initial hash value,
+ * the message being padded by the program. Each addition modulo 2^32 costs 31
+ * AND gates, Ch and Maj 32 each: the compression costs 22,573 AND gates.
+ */
+
+var sha256K = [64]uint64{
+	0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+	0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+	0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+	0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+	0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+	0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+	0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+	0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+}
+
+func buildSHA256Compress(b *builder, intsize typ.Num) {
+	intt := typ.NewIntType(intsize)
+	params := b.params(typ.NewArrayType(8, intt), typ.NewArrayType(16, intt))
+	state, block := words(params[0], 32), words(params[1], 32)
+
+	w := make([]word, 64)
+	copy(w, block)
+	for t := 16; t < 64; t++ {
+		s0 := b.xorWords(b.xorWords(rotr(w[t-15], 7), rotr(w[t-15], 18)), shr(w[t-15], 3))
+		s1 := b.xorWords(b.xorWords(rotr(w[t-2], 17), rotr(w[t-2], 19)), shr(w[t-2], 10))
+		w[t] = b.add(b.add(s1, w[t-7]), b.add(s0, w[t-16]))
+	}
+
+	v := make([]word, 8)
+	copy(v, state)
+	for t := 0; t < 64; t++ {
+		a, e := v[0], v[4]
+		S1 := b.xorWords(b.xorWords(rotr(e, 6), rotr(e, 11)), rotr(e, 25))
+		// Ch(e, f, g) is g ^ (e & (f ^ g))
+		ch := make(word, 32)
+		for i := range ch {
+			ch[i] = b.xor(v[6][i], b.and(e[i], b.xor(v[5][i], v[6][i])))
+		}
+		t1 := b.add(b.add(v[7], S1), b.add(ch, b.add(constant(sha256K[t], 32), w[t])))
+		S0 := b.xorWords(b.xorWords(rotr(a, 2), rotr(a, 13)), rotr(a, 22))
+		// Maj(a, b, c) is b ^ ((a ^ b) & (b ^ c))
+		maj := make(word, 32)
+		for i := range maj {
+			maj[i] = b.xor(v[1][i], b.and(b.xor(a[i], v[1][i]), b.xor(v[1][i], v[2][i])))
+		}
+		t2 := b.add(S0, maj)
+		v = []word{b.add(t1, t2), v[0], v[1], v[2], b.add(v[3], t1), v[4], v[5], v[6]}
+	}
+
+	for i := range v {
+		v[i] = b.add(state[i], v[i])
+	}
+	b.ret(params[0].Type, v)
+}
@@ -0,0 +1,233 @@
+package stdlib
+
+import (
+	"fmt"
+	"sort"
+
+	circ "ixxoprivacy/pkg/circuit"
+	typ "ixxoprivacy/pkg/types"
+)
+
+/*
+ * The standard library is made of circuits of cryptographic primitives,
+ * written gate by gate instead of being compiled from JavaScript so that they
+ * use as few AND gates as possible, XOR gates being free in garbled circuits.
+ * The programs call them as built-in functions:
+ *
+ *	AES128(key, block)                  encrypts a block of 16 bytes with a key of 16 bytes
+ *	SHA256Compress(state, block)        compresses a block of 16 words into a state of 8 words
+ *	ChaCha20Block(key, counter, nonce)  returns the block of 16 words of keystream of RFC 8439
+ *	MontMul(a, b, n)                    returns a * b / 2^$intsize modulo an odd n
+ *
+ * The bytes and words are arrays of integers, of which only the lowest 8 or
+ * 32 bits are read, the other bits of the results being 0: the words are
+ * positive in the programs whose $intsize is above 32. The functions are
+ * built for the $intsize of the program calling them, as objects linked into
+ * its circuit like the ones of the other libraries.
+ */
+
+// function describes a function of the standard library
+type function struct {
+	minIntSize typ.Num                 // the smallest $intsize the function is built for
+	build      func(*builder, typ.Num) // builds the function for an $intsize
+}
+
+var functions = map[string]function{
+	"AES128":         {8, buildAES128},
+	"SHA256Compress": {32, buildSHA256Compress},
+	"ChaCha20Block":  {32, buildChaCha20Block},
+	"MontMul":        {2, buildMontMul},
+}
+
+var objects = make(map[string]*circ.Object) // the objects built, by name and $intsize
+
+// Names returns the names of the functions of the standard library, sorted
+func Names() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Object returns the object of the function of the standard library of the
+// given name, built for integers of intsize bits, or nil when the library has
+// no function of this name
+func Object(name string, intsize typ.Num) (*circ.Object, error) {
+	fn, ok := functions[name]
+	if !ok {
+		return nil, nil
+	}
+	if intsize < fn.minIntSize {
+		return nil, fmt.Errorf("%s needs a $intsize of at least %d", name, fn.minIntSize)
+	}
+	key := fmt.Sprint(name, "/", intsize)
+	if o, ok := objects[key]; ok {
+		return o, nil
+	}
+	b := &builder{f: circ.NewFunctionPt(), next: circ.FirstRelocatableWire, name: name}
+	fn.build(b, intsize)
+	b.o.IntSize = intsize
+	objects[key] = b.o
+	return b.o, nil
+}
+
+/*                 Building the circuits                      */
+/*------------------------------------------------------------*/
+
+// The wires 0 and 1 of an object hold false and true
+const zero, one typ.Num = 0, 1
+
+// builder writes the commands of an object, simplifying the gates whose
+// inputs are constants
+type builder struct {
+	f    *circ.Function
+	next typ.Num // the next wire to use
+	name string
+	o    *circ.Object
+}
+
+// word is a number given by its wires, the lowest bit first
+type word []typ.Num
+
+func (b *builder) gate(kind circ.CommandType, x, y typ.Num) typ.Num {
+	b.f.PushNonFunctionCall(circ.Command{Kind: kind, X: x, Y: y, To: b.next})
+	b.next++
+	return b.next - 1
+}
+
+func (b *builder) xor(x, y typ.Num) typ.Num {
+	switch {
+	case x == y:
+		return zero
+	case x == zero:
+		return y
+	case y == zero:
+		return x
+	}
+	return b.gate(circ.GATE_6, x, y)
+}
+
+func (b *builder) and(x, y typ.Num) typ.Num {
+	switch {
+	case x == zero || y == zero:
+		return zero
+	case x == one || x == y:
+		return y
+	case y == one:
+		return x
+	}
+	return b.gate(circ.GATE_8, x, y)
+}
+
+func (b *builder) not(x typ.Num) typ.Num {
+	switch x {
+	case zero:
+		return one
+	case one:
+		return zero
+	}
+	return b.gate(circ.GATE_6, x, one)
+}
+
+// mux returns x when s is true and y otherwise
+func (b *builder) mux(s, x, y typ.Num) typ.Num {
+	return b.xor(y, b.and(s, b.xor(x, y)))
+}
+
+// params gives the wires of the parameters of the object, of the given types
+func (b *builder) params(types ...*typ.Type) []*circ.Var {
+	vars := make([]*circ.Var, len(types))
+	for i, t := range types {
+		vars[i] = &circ.Var{Type: t, Wirebase: b.next}
+		b.next += t.Size()
+	}
+	b.o = &circ.Object{Name: b.name, Params: vars, Funcs: []*circ.Function{b.f}}
+	return vars
+}
+
+// words returns the lowest bits of the integers of a parameter, which is an
+// integer or an array of integers
+func words(v *circ.Var, bits int) []word {
+	n, size := typ.Num(1), v.Type.L
+	if v.Type.IsArrayType() {
+		n, size = v.Type.L, v.Type.SubType.L
+	}
+	ws := make([]word, n)
+	for i := range ws {
+		ws[i] = make(word, bits)
+		for j := range ws[i] {
+			ws[i][j] = v.Wirebase + typ.Num(i)*size + typ.Num(j)
+		}
+	}
+	return ws
+}
+
+// ret copies the words of the result of the object into its return value, of
+// the given type, the higher bits of its integers being 0
+func (b *builder) ret(t *typ.Type, ws []word) {
+	size := t.L
+	if t.IsArrayType() {
+		size = t.SubType.L
+	}
+	b.o.Return = &circ.Var{Type: t, Wirebase: b.next}
+	b.next += t.Size()
+	for i, w := range ws {
+		for j := typ.Num(0); j < size; j++ {
+			from := zero
+			if int(j) < len(w) {
+				from = w[j]
+			}
+			b.f.PushNonFunctionCall(circ.Command{Kind: circ.COPY, X: from, To: b.o.Return.Wirebase + typ.Num(i)*size + j})
+		}
+	}
+	b.o.Wires = b.next
+}
+
+// constant returns the word of bits bits holding a constant
+func constant(val uint64, bits int) word {
+	w := make(word, bits)
+	for i := range w {
+		w[i] = typ.Num(val >> uint(i) & 1)
+	}
+	return w
+}
+
+func (b *builder) xorWords(x, y word) word {
+	z := make(word, len(x))
+	for i := range x {
+		z[i] = b.xor(x[i], y[i])
+	}
+	return z
+}
+
+// addCarry returns the sum of two words of the same size and of a carry, and
+// the carry out, with one AND gate per bit
+func (b *builder) addCarry(x, y word, c typ.Num) (word, typ.Num) {
+	s := make(word, len(x))
+	for i := range x {
+		xc, yc := b.xor(x[i], c), b.xor(y[i], c)
+		s[i] = b.xor(xc, y[i])
+		c = b.xor(c, b.and(xc, yc))
+	}
+	return s, c
+}
+
+// add returns the sum of two words modulo 2^len(x), with one AND gate per
+// bit but the highest one
+func (b *builder) add(x, y word) word {
+	last := len(x) - 1
+	s, c := b.addCarry(x[:last], y[:last], zero)
+	return append(s, b.xor(b.xor(x[last], y[last]), c))
+}
+
+// rotr returns a word rotated right by k bits
+func rotr(x word, k int) word {
+	return append(append(word{}, x[k:]...), x[:k]...)
+}
+
+// shr returns a word shifted right by k bits
+func shr(x word, k int) word {
+	return append(append(word{}, x[k:]...), constant(0, k)...)
+}
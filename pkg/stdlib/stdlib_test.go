@@ -0,0 +1,186 @@
+package stdlib
+
+import (
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	circ "ixxoprivacy/pkg/circuit"
+	ip "ixxoprivacy/pkg/interpreter"
+	typ "ixxoprivacy/pkg/types"
+)
+
+// run links a function of the standard library into a circuit reading its
+// arguments from one party each and giving its result to the party 0, and
+// runs the circuit with the interpreter
+func run(t *testing.T, name string, intsize typ.Num, args ...interface{}) interface{} {
+	o, err := Object(name, intsize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	C := circ.NewCircuit(intsize, uint8(len(args)))
+	C.PushNonFunctionCall(circ.Command{Kind: circ.GATE_0, To: 0})
+	C.PushNonFunctionCall(circ.Command{Kind: circ.GATE_15, To: 1})
+	l, err := C.Link(o, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := make([]*circ.UserInOut, len(args))
+	for i, p := range l.Params {
+		C.Inputs[i] = p
+		C.PushNonFunctionCall(circ.Command{Kind: circ.MASS_INPUT, X: typ.Num(i), Y: p.Size(), To: p.Wirebase})
+		if inputs[i], err = ip.EncodeInput(args[i], p.Type); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := C.Funcs[l.Function]
+	C.PushFunctionCall(circ.Command{Kind: circ.FUNCTION_CALL, X: l.Function}, f.XORgates, f.NonXORgates)
+	C.Outputs[0] = l.Return
+	C.PushNonFunctionCall(circ.Command{Kind: circ.MASS_OUTPUT, X: l.Return.Wirebase, Y: l.Return.Size(), To: 0})
+	return ip.GetGoValue(ip.Interprete(C, inputs)[0], l.Return.Type)
+}
+
+// values returns the numbers of a byte or word slice as inputs
+func values(xs []uint64) []interface{} {
+	vals := make([]interface{}, len(xs))
+	for i, x := range xs {
+		vals[i] = float64(x)
+	}
+	return vals
+}
+
+// compare checks that an array output holds the numbers expected
+func compare(t *testing.T, what string, got interface{}, expected []uint64) {
+	arr := got.([]interface{})
+	for i, x := range expected {
+		if arr[i].(int64) != int64(x) {
+			t.Errorf("%s: item %d is %#x instead of %#x", what, i, arr[i], x)
+		}
+	}
+}
+
+func TestAES128(t *testing.T) {
+	fmt.Println("Starting TestAES128")
+	// the example of the appendix C.1 of FIPS-197, and random blocks checked with crypto/aes
+	r := rand.New(rand.NewSource(1))
+	key := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	block := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	toU := func(bs []byte) []uint64 {
+		us := make([]uint64, len(bs))
+		for i, x := range bs {
+			us[i] = uint64(x)
+		}
+		return us
+	}
+	for i := 0; i < 4; i++ {
+		expected := make([]byte, 16)
+		c, _ := aes.NewCipher(key)
+		c.Encrypt(expected, block)
+		if i == 0 && fmt.Sprintf("%x", expected) != "69c4e0d86a7b0430d8cdb78070b4c55a" {
+			t.Fatalf("wrong test vector %x", expected)
+		}
+		got := run(t, "AES128", 16, values(toU(key)), values(toU(block)))
+		compare(t, fmt.Sprintf("AES128(%x, %x)", key, block), got, toU(expected))
+		r.Read(key)
+		r.Read(block)
+	}
+	if o, _ := Object("AES128", 16); o.Funcs[0].NonXORgates != 6800 {
+		t.Errorf("AES128 has %d AND gates instead of 6800", o.Funcs[0].NonXORgates)
+	}
+}
+
+func TestSHA256Compress(t *testing.T) {
+	fmt.Println("Starting TestSHA256Compress")
+	state := []uint64{0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19}
+	// a message of two blocks once padded
+	msg := []byte("abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq")
+	padded := append(append([]byte{}, msg...), 0x80)
+	for len(padded)%64 != 56 {
+		padded = append(padded, 0)
+	}
+	padded = append(padded, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(padded[len(padded)-8:], uint64(len(msg))*8)
+
+	for i := 0; i < len(padded); i += 64 {
+		block := make([]uint64, 16)
+		for j := range block {
+			block[j] = uint64(binary.BigEndian.Uint32(padded[i+4*j:]))
+		}
+		arr := run(t, "SHA256Compress", 40, values(state), values(block)).([]interface{})
+		for j := range state {
+			state[j] = uint64(arr[j].(int64))
+		}
+	}
+	digest := sha256.Sum256(msg)
+	expected := make([]uint64, 8)
+	for j := range expected {
+		expected[j] = uint64(binary.BigEndian.Uint32(digest[4*j:]))
+	}
+	for j := range state {
+		if state[j] != expected[j] {
+			t.Errorf("SHA-256 of %s: word %d is %#x instead of %#x", msg, j, state[j], expected[j])
+		}
+	}
+}
+
+func TestChaCha20Block(t *testing.T) {
+	fmt.Println("Starting TestChaCha20Block")
+	// the test vector of the section 2.3.2 of RFC 8439
+	key := make([]uint64, 8)
+	for i := range key {
+		key[i] = uint64(binary.LittleEndian.Uint32([]byte{byte(4 * i), byte(4*i + 1), byte(4*i + 2), byte(4*i + 3)}))
+	}
+	nonce := []uint64{0x09000000, 0x4a000000, 0x00000000}
+	expected := []uint64{
+		0xe4e7f110, 0x15593bd1, 0x1fdd0f50, 0xc47120a3,
+		0xc7f4d1c7, 0x0368c033, 0x9aaa2204, 0x4e6cd4c3,
+		0x466482d2, 0x09aa9f07, 0x05d7c214, 0xa2028bd9,
+		0xd19c12b5, 0xb94e16de, 0xe883d0cb, 0x4e3c50a2,
+	}
+	got := run(t, "ChaCha20Block", 33, values(key), float64(1), values(nonce))
+	compare(t, "ChaCha20Block", got, expected)
+}
+
+func TestMontMul(t *testing.T) {
+	fmt.Println("Starting TestMontMul")
+	r := rand.New(rand.NewSource(1))
+	for _, k := range []uint{8, 24, 64} {
+		R := new(big.Int).Lsh(big.NewInt(1), k)
+		Rinv := new(big.Int)
+		for i := 0; i < 10; i++ {
+			// odd moduli below 2^(k-1), so that the integers are positive
+			n := new(big.Int).Rand(r, new(big.Int).Rsh(R, 1))
+			n.SetBit(n, 0, 1)
+			if n.Cmp(big.NewInt(1)) == 0 {
+				n.SetInt64(3)
+			}
+			a, b := new(big.Int).Rand(r, n), new(big.Int).Rand(r, n)
+			if i == 0 {
+				a.Sub(n, big.NewInt(1))
+				b.Sub(n, big.NewInt(1))
+			}
+			Rinv.ModInverse(R, n)
+			expected := new(big.Int).Mul(a, b)
+			expected.Mul(expected, Rinv).Mod(expected, n)
+
+			got := run(t, "MontMul", typ.Num(k), a.String(), b.String(), n.String())
+			if fmt.Sprint(got) != expected.String() {
+				t.Errorf("MontMul(%v, %v, %v) with k = %d is %v instead of %v", a, b, n, k, got, expected)
+			}
+		}
+	}
+}
+
+func TestObjectIntSize(t *testing.T) {
+	fmt.Println("Starting TestObjectIntSize")
+	if _, err := Object("SHA256Compress", 16); err == nil {
+		t.Error("SHA256Compress was built for integers of 16 bits")
+	}
+	if o, err := Object("Sort", 16); o != nil || err != nil {
+		t.Error("an object was found for a function out of the standard library")
+	}
+}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/engine"
+	"ixxoprivacy/pkg/garbler"
+)
+
+/*
+ * A session runs a circuit between a garbler and an evaluator at both ends
+ * of a connection, the tables being evaluated while they are garbled:
+ *
+ *   1. the garbler sends the security parameter and the keys of the inputs
+ *      of all the parties, drawn before the circuit is garbled
+ *   2. the garbler streams the tables through a Sender as it garbles them,
+ *      and the evaluator reads them through a Receiver as it evaluates them
+ *   3. once the stream is over, the evaluator sends back the keys of the
+ *      outputs of every party, which the garbler decodes
+ *
+ * The messages of steps 1 and 3 are encoded with gob and preceded by their
+ * length on 4 bytes, so that they are read no further than their end.
+ */
+
+// inputMessage is the first message of a session
+type inputMessage struct {
+	Security uint8
+	Inputs   []circ.UserEncoder
+}
+
+// writeMessage sends a value encoded with gob, preceded by its length
+func writeMessage(w io.Writer, v interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(buf.Len()))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// readMessage receives a value sent by writeMessage
+func readMessage(r io.Reader, v interface{}) error {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return err
+	}
+	buf := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(buf)).Decode(v)
+}
+
+// Garble runs the garbler's side of a session on the inputs of all the
+// parties, and returns the outputs of every party.
+func Garble(conn io.ReadWriter, C circ.Circuit, n uint8, workers int, inputs []*circ.UserInOut) ([]circ.UserInOut, error) {
+	if len(inputs) != int(C.Parties) {
+		return nil, fmt.Errorf("the circuit has %d parties but %d inputs are given", C.Parties, len(inputs))
+	}
+	enc := garbler.DrawEncoding(C, n)
+	msg := inputMessage{Security: n, Inputs: make([]circ.UserEncoder, C.Parties)}
+	for i := range inputs {
+		msg.Inputs[i] = enc.User[i].Encode(enc.SecretKey, inputs[i])
+	}
+	if err := writeMessage(conn, msg); err != nil {
+		return nil, err
+	}
+
+	s := NewSender(conn)
+	dec, err := garbler.GarbleStreamEncoded(C, n, s, workers, enc)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Close(); err != nil {
+		return nil, err
+	}
+
+	var keys [][]circ.DecodingKey
+	if err := readMessage(conn, &keys); err != nil {
+		return nil, err
+	}
+	if len(keys) != int(C.Parties) {
+		return nil, fmt.Errorf("the evaluator sent the outputs of %d parties instead of %d", len(keys), C.Parties)
+	}
+	outputs := make([]circ.UserInOut, C.Parties)
+	for party := range outputs {
+		if len(keys[party]) != len(dec.User[party]) {
+			return nil, fmt.Errorf("the evaluator sent %d output keys to party %d instead of %d", len(keys[party]), party, len(dec.User[party]))
+		}
+		outputs[party] = dec.User[party].Decode(keys[party])
+	}
+	return outputs, nil
+}
+
+// Evaluate runs the evaluator's side of a session, letting the garbler send
+// a window of bytes of tables in advance, DefaultWindow if window is not
+// positive.
+func Evaluate(conn io.ReadWriter, C circ.Circuit, window, workers int) error {
+	var msg inputMessage
+	if err := readMessage(conn, &msg); err != nil {
+		return err
+	}
+	if len(msg.Inputs) != int(C.Parties) {
+		return fmt.Errorf("the garbler sent the inputs of %d parties to a circuit of %d parties", len(msg.Inputs), C.Parties)
+	}
+	engine.Init(msg.Security)
+
+	chin := make([]chan circ.GarbledValue, C.Parties)
+	chout := make([]chan circ.DecodingKey, C.Parties)
+	keys := make([][]circ.DecodingKey, C.Parties)
+	done := make(chan bool)
+	for i := range chin {
+		chin[i] = make(chan circ.GarbledValue, 5)
+		chout[i] = make(chan circ.DecodingKey, 5)
+		go func(i int) {
+			for _, v := range msg.Inputs[i] {
+				chin[i] <- v
+			}
+		}(i)
+		go func(i int) {
+			keys[i] = make([]circ.DecodingKey, 0)
+			for key := range chout[i] {
+				keys[i] = append(keys[i], key)
+			}
+			done <- true
+		}(i)
+	}
+	engine.EvaluateStream(C, NewReceiver(conn, window), chin, chout, workers)
+	for i := range chout {
+		close(chout[i])
+		<-done
+	}
+	return writeMessage(conn, keys)
+}
@@ -0,0 +1,200 @@
+package transport
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+/*
+ * This package carries a stream of bytes, typically the garbled tables
+ * written by garbler.GarbleStream, over a connection between the garbler
+ * and the evaluator, so that the circuit is evaluated while it is garbled.
+ *
+ * The flow is controlled by the receiver: it gives the sender credits, in
+ * bytes, and the sender never sends more than the credits received. The
+ * receiver gives back the credits of the bytes as they are read, so at most
+ * a window of bytes is ever on its way, and a sender going faster than the
+ * evaluator is blocked in Write.
+ *
+ * From the sender to the receiver, the bytes are sent in frames made of
+ * their length on 4 bytes followed by the bytes, an empty frame ending the
+ * stream. From the receiver to the sender, every message is a number of
+ * credits on 4 bytes, 0 acknowledging the end of the stream.
+ */
+
+// DefaultWindow is the number of bytes a receiver lets the sender send in advance
+const DefaultWindow = 1 << 20
+
+// maxFrame is the largest number of bytes sent in a single frame
+const maxFrame = 1 << 16
+
+// A Sender is the writing end of a stream sent over a connection
+type Sender struct {
+	conn io.ReadWriter
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	credit int   // number of bytes which can be sent
+	sent   int64 // number of bytes sent so far
+	acked  bool  // the receiver has read the whole stream
+	err    error // error met reading the credits
+}
+
+// NewSender returns a sender writing to a connection. The connection must not
+// be read by anyone else, the sender reading the credits of the receiver.
+func NewSender(conn io.ReadWriter) *Sender {
+	s := &Sender{conn: conn}
+	s.cond = sync.NewCond(&s.mu)
+	go s.readCredits()
+	return s
+}
+
+// readCredits receives the credits given by the receiver until the end of the stream
+func (s *Sender) readCredits() {
+	var buf [4]byte
+	for {
+		_, err := io.ReadFull(s.conn, buf[:])
+		s.mu.Lock()
+		if err != nil {
+			s.err = err
+		} else if c := binary.BigEndian.Uint32(buf[:]); c == 0 {
+			s.acked = true
+		} else {
+			s.credit += int(c)
+		}
+		s.cond.Broadcast()
+		done := s.err != nil || s.acked
+		s.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// Write sends bytes to the receiver, waiting for credits when needed
+func (s *Sender) Write(p []byte) (int, error) {
+	written := 0
+	header := make([]byte, 4)
+	for len(p) > 0 {
+		s.mu.Lock()
+		for s.credit == 0 && s.err == nil && !s.acked {
+			s.cond.Wait()
+		}
+		if s.credit == 0 {
+			err := s.err
+			s.mu.Unlock()
+			if err == nil {
+				err = errors.New("transport: the receiver stopped reading")
+			}
+			return written, err
+		}
+		k := len(p)
+		if k > s.credit {
+			k = s.credit
+		}
+		if k > maxFrame {
+			k = maxFrame
+		}
+		s.credit -= k
+		s.sent += int64(k)
+		s.mu.Unlock()
+
+		binary.BigEndian.PutUint32(header, uint32(k))
+		if _, err := s.conn.Write(header); err != nil {
+			return written, err
+		}
+		if _, err := s.conn.Write(p[:k]); err != nil {
+			return written, err
+		}
+		written += k
+		p = p[k:]
+	}
+	return written, nil
+}
+
+// Close ends the stream and waits until the receiver has read all of it
+func (s *Sender) Close() error {
+	if _, err := s.conn.Write(make([]byte, 4)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.acked && s.err == nil {
+		s.cond.Wait()
+	}
+	if s.acked {
+		return nil
+	}
+	return s.err
+}
+
+// A Receiver is the reading end of a stream sent over a connection
+type Receiver struct {
+	conn     io.ReadWriter
+	window   int
+	started  bool
+	left     int // bytes of the current frame still to read
+	consumed int // bytes read since the last credits were given
+	done     bool
+}
+
+// NewReceiver returns a receiver reading from a connection, which lets the
+// sender send a window of bytes in advance, DefaultWindow if window is not
+// positive.
+func NewReceiver(conn io.ReadWriter, window int) *Receiver {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Receiver{conn: conn, window: window}
+}
+
+// give sends credits to the sender
+func (r *Receiver) give(credit int) error {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(credit))
+	_, err := r.conn.Write(buf)
+	return err
+}
+
+// Read reads the bytes sent, giving back credits to the sender as they are read.
+// It returns io.EOF once the sender has closed the stream.
+func (r *Receiver) Read(p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		if err := r.give(r.window); err != nil {
+			return 0, err
+		}
+	}
+	if r.done {
+		return 0, io.EOF
+	}
+	if r.left == 0 {
+		var header [4]byte
+		if _, err := io.ReadFull(r.conn, header[:]); err != nil {
+			return 0, err
+		}
+		r.left = int(binary.BigEndian.Uint32(header[:]))
+		if r.left == 0 {
+			r.done = true
+			if err := r.give(0); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+	}
+	if len(p) > r.left {
+		p = p[:r.left]
+	}
+	k, err := r.conn.Read(p)
+	r.left -= k
+	r.consumed += k
+	if r.consumed >= r.window/2 {
+		if err := r.give(r.consumed); err != nil {
+			return k, err
+		}
+		r.consumed = 0
+	}
+	return k, err
+}
@@ -0,0 +1,159 @@
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	circ "ixxoprivacy/pkg/circuit"
+	"ixxoprivacy/pkg/compiler"
+	"ixxoprivacy/pkg/engine"
+	"ixxoprivacy/pkg/garbler"
+	ip "ixxoprivacy/pkg/interpreter"
+)
+
+func TestFlowControl(t *testing.T) {
+	fmt.Println("Starting TestFlowControl")
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	data := make([]byte, 100000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	window := 1000
+	s := NewSender(c1)
+	errs := make(chan error, 1)
+	go func() {
+		for p := data; len(p) > 0; {
+			k := 777
+			if k > len(p) {
+				k = len(p)
+			}
+			if _, err := s.Write(p[:k]); err != nil {
+				errs <- err
+				return
+			}
+			p = p[k:]
+		}
+		errs <- s.Close()
+	}()
+
+	r := NewReceiver(c2, window)
+	received := make([]byte, 0, len(data))
+	buf := make([]byte, 100)
+	for {
+		k, err := r.Read(buf)
+		received = append(received, buf[:k]...)
+		s.mu.Lock()
+		ahead := s.sent - int64(len(received))
+		s.mu.Unlock()
+		if ahead > int64(window) {
+			t.Fatalf("the sender is %d bytes ahead of the receiver", ahead)
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, received) {
+		t.Error("the bytes received differ from the bytes sent")
+	}
+}
+
+func TestPipelined(t *testing.T) {
+	fmt.Println("Starting TestPipelined")
+	security := uint8(4)
+	engine.Init(security)
+	C, err := compiler.CircuitFromJS("../../Tests/test5_matrix4.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := ip.GetAllInputs(C.Inputs, []string{"../../Tests/entry5-0.json", "../../Tests/entry5-1.json"})
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	// the garbler sends the tables while it garbles them, a few at a time
+	enc := garbler.DrawEncoding(C, security)
+	decs := make(chan circ.DecodingSet, 1)
+	go func() {
+		s := NewSender(c1)
+		dec, err := garbler.GarbleStreamEncoded(C, security, s, 2, enc)
+		if err == nil {
+			err = s.Close()
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		decs <- dec
+	}()
+
+	chin := make([]chan circ.GarbledValue, C.Parties)
+	chout := make([]chan circ.DecodingKey, C.Parties)
+	keys := make([][]circ.DecodingKey, C.Parties)
+	done := make(chan bool)
+	for i := range chin {
+		chin[i] = make(chan circ.GarbledValue, 5)
+		chout[i] = make(chan circ.DecodingKey, 5)
+		go func(i int) {
+			for _, v := range enc.User[i].Encode(enc.SecretKey, inputs[i]) {
+				chin[i] <- v
+			}
+		}(i)
+		go func(i int) {
+			for key := range chout[i] {
+				keys[i] = append(keys[i], key)
+			}
+			done <- true
+		}(i)
+	}
+	engine.EvaluateStream(C, NewReceiver(c2, 4096), chin, chout, 1)
+	for i := range chout {
+		close(chout[i])
+		<-done
+	}
+
+	dec := <-decs
+	for party, out := range ip.Interprete(C, inputs) {
+		if out != nil && fmt.Sprint([]bool(*out)) != fmt.Sprint(dec.User[party].Decode(keys[party])) {
+			t.Errorf("wrong outputs of party %d", party)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	fmt.Println("Starting TestSession")
+	C, err := compiler.CircuitFromJS("../../Tests/test5_matrix4.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := ip.GetAllInputs(C.Inputs, []string{"../../Tests/entry5-0.json", "../../Tests/entry5-1.json"})
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- Evaluate(c2, C, 4096, 2)
+	}()
+	outputs, err := Garble(c1, C, 4, 2, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	for party, out := range ip.Interprete(C, inputs) {
+		if out != nil && fmt.Sprint([]bool(*out)) != fmt.Sprint([]bool(outputs[party])) {
+			t.Errorf("wrong outputs of party %d", party)
+		}
+	}
+}
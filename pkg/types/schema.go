@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// SchemaDraft is the version of JSON Schema of the schemas returned by Schema
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// IntegerPattern matches the strings holding an integer in the input files,
+// written in decimal or in hexadecimal with a 0x prefix
+const IntegerPattern = "^-?([0-9]+|0[xX][0-9a-fA-F]+)$"
+
+// Schema returns the JSON Schema of the values of a type, as read from the
+// input files and written to the output files. The bounds of the integers are
+// json.Number so that they are exact whatever their size. The integers may be
+// given as strings too, whose range is not checked by the schema.
+func (t *Type) Schema() map[string]interface{} {
+	switch t.BaseType {
+	case BOOL:
+		return map[string]interface{}{"type": "boolean"}
+	case INT, UINT:
+		min, max := t.Bounds()
+		return map[string]interface{}{
+			"type":    []string{"integer", "string"},
+			"minimum": json.Number(min.String()),
+			"maximum": json.Number(max.String()),
+			"pattern": IntegerPattern,
+		}
+	case ARRAY:
+		return map[string]interface{}{
+			"type":     "array",
+			"items":    t.SubType.Schema(),
+			"minItems": t.L,
+			"maxItems": t.L,
+		}
+	case OBJECT:
+		properties := make(map[string]interface{})
+		for i, st := range t.List {
+			properties[t.Keys[i]] = st.Schema()
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             t.Keys,
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{"type": "null"}
+}
+
+// Bounds returns the smallest and the largest values of an integer type
+func (t *Type) Bounds() (min, max *big.Int) {
+	one := big.NewInt(1)
+	if t.BaseType == UINT {
+		max = new(big.Int).Lsh(one, uint(t.L))
+		return new(big.Int), max.Sub(max, one)
+	}
+	max = new(big.Int).Lsh(one, uint(t.L-1))
+	min = new(big.Int).Neg(max)
+	return min, max.Sub(max, one)
+}
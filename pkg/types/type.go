@@ -89,6 +89,42 @@ func (t Type) Print(indent string) {
 	}
 }
 
+// Name returns a short description of the given type, such as int16,
+// [4]bool or {a: int16, b: [2]uint16}
+func (t Type) Name() string {
+	switch t.BaseType {
+	case VOID:
+		return "void"
+	case BOOL:
+		return "bool"
+	case INT:
+		return fmt.Sprint("int", t.L)
+	case UINT:
+		return fmt.Sprint("uint", t.L)
+	case ARRAY:
+		return fmt.Sprint("[", t.L, "]", t.SubType.Name())
+	case OBJECT:
+		s := "{"
+		for i, ot := range t.List {
+			if i > 0 {
+				s += ", "
+			}
+			s += t.Keys[i] + ": " + ot.Name()
+		}
+		return s + "}"
+	case FUNCTION:
+		s := "function("
+		for i, pt := range t.List {
+			if i > 0 {
+				s += ", "
+			}
+			s += pt.Name()
+		}
+		return s + ") " + t.SubType.Name()
+	}
+	return "?"
+}
+
 // Equals tests the equivalence of two given types
 func (t *Type) Equals(t2 *Type) bool {
 	if t == t2 {
@@ -48,3 +48,26 @@ func CheckRecursiveObj(t *Type, vec []*Type) {
 		}
 	}
 }
+
+// BitPath returns the path to the i-th bit of a variable of type t, such as
+// "[2].field#3" for the fourth bit of the field "field" of the third element.
+func (t Type) BitPath(i Num) string {
+	switch t.BaseType {
+	case ARRAY:
+		s := t.SubType.Size()
+		if s == 0 {
+			break
+		}
+		return fmt.Sprintf("[%d]", i/s) + t.SubType.BitPath(i%s)
+	case OBJECT:
+		for k, ot := range t.List {
+			if i < ot.Size() {
+				return "." + t.Keys[k] + ot.BitPath(i)
+			}
+			i -= ot.Size()
+		}
+	case BOOL:
+		return ""
+	}
+	return fmt.Sprintf("#%d", i)
+}
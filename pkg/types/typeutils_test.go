@@ -0,0 +1,32 @@
+package types
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBitPath(t *testing.T) {
+	fmt.Println("Starting TestBitPath")
+	ot := NewObjType()
+	ot.Keys = []string{"flag", "field"}
+	ot.List = []*Type{BoolType, NewIntType(8)}
+	at := NewArrayType(3, ot)
+	for _, c := range []struct {
+		t    *Type
+		i    Num
+		want string
+	}{
+		{BoolType, 0, ""},
+		{NewIntType(8), 5, "#5"},
+		{NewArrayType(4, BoolType), 2, "[2]"},
+		{NewArrayType(2, NewIntType(8)), 11, "[1]#3"},
+		{ot, 0, ".flag"},
+		{ot, 4, ".field#3"},
+		{at, 2*9 + 4, "[2].field#3"},
+		{NewArrayType(2, NewArrayType(0, BoolType)), 1, "#1"},
+	} {
+		if path := c.t.BitPath(c.i); path != c.want {
+			t.Errorf("the bit %d is at %q instead of %q", c.i, path, c.want)
+		}
+	}
+}
@@ -60,7 +60,7 @@ func (arv ArrayVariable) GetWire(i typ.Num) *wr.Wire {
 func (arv *ArrayVariable) Print(indent string) {
 	for i, v := range arv.Av {
 		if v != nil {
-			v.Print(indent + "[" + string(i) + "]")
+			v.Print(indent + "[" + fmt.Sprint(i) + "]")
 		}
 	}
 }
@@ -3,6 +3,7 @@ package variables
 import (
 	"fmt"
 	typ "ixxoprivacy/pkg/types"
+	"os"
 
 	"github.com/robertkrimen/otto/ast"
 	tk "github.com/robertkrimen/otto/token"
@@ -125,7 +126,21 @@ func (fc FunctionContext) GetNodeType(n ast.Node) *typ.Type {
 		}
 
 	case *ast.CallExpression:
-		return fc.GetNodeType(n2.Callee).SubType
+		if sortingName(n2) != "" {
+			return fc.sortingCallType(n2)
+		}
+		if id, ok := n2.Callee.(*ast.Identifier); ok && fc.isUserFunction(id.Name) {
+			// the functions are compiled once per types of arguments
+			params := make([]*typ.Type, len(n2.ArgumentList))
+			for i, arg := range n2.ArgumentList {
+				params[i] = fc.GetNodeType(arg)
+			}
+			return PC.CallType(id.Name, params)
+		}
+		if t := fc.GetNodeType(n2.Callee); t != nil {
+			return t.SubType
+		}
+		return nil
 
 	case *ast.DotExpression:
 		t := fc.GetNodeType(n2.Left)
@@ -144,7 +159,14 @@ func (fc FunctionContext) GetNodeType(n ast.Node) *typ.Type {
 		} else if t, ok := ReservedFunc[n2.Name]; ok {
 			return t
 		}
-		return PC.FunctionContext[n2.Name].GetType()
+		if funcDecls[n2.Name] != nil {
+			PC.declareFunction(n2.Name)
+		}
+		if v, ok := PC.FunctionContext[n2.Name]; ok {
+			return v.GetType()
+		}
+		// a function whose type is not known yet, as in a recursive call
+		return nil
 		// TODO: add type conversions
 	}
 	return GetVoidType()
@@ -162,7 +184,10 @@ func (rv *returnVisitor) Enter(n ast.Node) ast.Visitor {
 }
 func (rv *returnVisitor) Exit(n ast.Node) {
 	if rs, ok := n.(*ast.ReturnStatement); ok {
-		rv.T = rv.FC.GetNodeType(rs.Argument)
+		// the returns of recursive calls have no type yet, the other ones give it
+		if t := rv.FC.GetNodeType(rs.Argument); t != nil {
+			rv.T = t
+		}
 	}
 }
 
@@ -174,36 +199,117 @@ func (fc FunctionContext) GetReturnType(st ast.Node) *typ.Type {
 
 // GetParams explores the body of a program in order to find a call to the function of
 // name fname and then determine what are the types of arguments given to this function.
+// The calls of the main body are preferred, and the calls of the function itself, in a
+// recursion, are ignored. The arguments of a call in another function are typed in the
+// context of this function.
 type paramVisitor struct {
 	FoundCall bool
 	FName     string
 	FC        *FunctionContext
 	IDs       []*ast.Identifier
+	Skip      func(*ast.FunctionLiteral) bool
+	Caller    FunctionContext
 }
 
 func (pv *paramVisitor) Enter(n ast.Node) ast.Visitor {
 	if pv.FoundCall {
 		return pv
 	}
+	if fl, ok := n.(*ast.FunctionLiteral); ok {
+		if pv.Skip(fl) || generating[fl.Name.Name] {
+			return nil
+		}
+		PC.declareFunction(fl.Name.Name)
+		pv.Caller = PC.Funcs[fl.Name.Name]
+	}
 	if ce, ok := n.(*ast.CallExpression); ok {
-		if id, ok := ce.Callee.(*ast.Identifier); ok && id.Name == pv.FName {
+		if id, ok := ce.Callee.(*ast.Identifier); ok && id.Name == pv.FName && !pv.nested(ce) {
 			pv.FoundCall = true
+			caller := pv.Caller
+			if caller == nil {
+				caller = PC.FunctionContext
+			}
 			for i, exp := range ce.ArgumentList {
 				name := pv.IDs[i].Name
-				(*pv.FC)[name] = VarFromType(PC.GetNodeType(exp), name)
+				(*pv.FC)[name] = VarFromType(caller.GetNodeType(exp), name)
 			}
 			return nil
 		}
 	}
 	return pv
 }
-func (pv *paramVisitor) Exit(n ast.Node) {}
+func (pv *paramVisitor) Exit(n ast.Node) {
+	if _, ok := n.(*ast.FunctionLiteral); ok {
+		pv.Caller = nil
+	}
+}
+
+// nested tells if the arguments of a call to the function contain another
+// call to it, such as max(max(a, b), c), whose type is not known yet
+func (pv *paramVisitor) nested(ce *ast.CallExpression) bool {
+	cf := callFinder{name: pv.FName}
+	for _, exp := range ce.ArgumentList {
+		ast.Walk(&cf, exp)
+	}
+	return cf.found
+}
 
-func (fc *FunctionContext) GetParams(fname string, prog *ast.Program, ids []*ast.Identifier) {
-	pv := paramVisitor{false, fname, fc, ids}
+// callFinder finds the calls to a function in an expression
+type callFinder struct {
+	name  string
+	found bool
+}
+
+func (cf *callFinder) Enter(n ast.Node) ast.Visitor {
+	if ce, ok := n.(*ast.CallExpression); ok {
+		if id, ok := ce.Callee.(*ast.Identifier); ok && id.Name == cf.name {
+			cf.found = true
+		}
+	}
+	if cf.found {
+		return nil
+	}
+	return cf
+}
+func (cf *callFinder) Exit(n ast.Node) {}
+
+func (fc *FunctionContext) GetParams(f *ast.FunctionLiteral, prog *ast.Program) {
+	pv := paramVisitor{false, f.Name.Name, fc, f.ParameterList.List, func(*ast.FunctionLiteral) bool { return true }, nil}
 	ast.Walk(&pv, prog)
+	if !pv.FoundCall {
+		pv.Skip = func(fl *ast.FunctionLiteral) bool { return fl == f }
+		ast.Walk(&pv, prog)
+	}
 
 	if !pv.FoundCall {
-		fmt.Println("Error: no call found for function", fname)
+		fmt.Println("Error: no call found for function", f.Name.Name)
+		os.Exit(64)
+	}
+}
+
+// declare adds to the context of a function the variables declared in it
+func (fc *FunctionContext) declare(f *ast.FunctionLiteral) {
+	for _, fdec := range f.DeclarationList {
+		fd, ok := fdec.(*ast.VariableDeclaration)
+		if !ok {
+			fmt.Println("Error in GenerateContext: only variables should be declared inside functions.")
+			os.Exit(64)
+		}
+		fc.addVarDeclaration(fd)
+	}
+	fc.CheckForRecTypes()
+}
+
+// isUserFunction tells if a name is the one of a function of the program in
+// the given context
+func (fc FunctionContext) isUserFunction(name string) bool {
+	if funcDecls[name] == nil {
+		return false
+	}
+	v, ok := fc[name]
+	if !ok {
+		return true
 	}
+	_, ok = v.(*FunctionVariable)
+	return ok
 }
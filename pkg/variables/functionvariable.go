@@ -4,6 +4,7 @@ import (
 	"fmt"
 	typ "ixxoprivacy/pkg/types"
 	wr "ixxoprivacy/pkg/wires"
+	"strconv"
 
 	"github.com/robertkrimen/otto/ast"
 )
@@ -14,6 +15,7 @@ type FunctionVariable struct {
 	Returnv        VarInterface
 	FunctionNumber typ.Num
 	FunctionNode   *ast.FunctionLiteral
+	Extern         bool // the function is linked from a library instead of being compiled
 }
 
 func NewFunctionVariable(f *ast.FunctionLiteral, fc FunctionContext) *FunctionVariable {
@@ -36,6 +38,39 @@ func NewFunctionVariable(f *ast.FunctionLiteral, fc FunctionContext) *FunctionVa
 	return fv
 }
 
+// Accepts tells if the parameters of a function have the given types, the
+// types not known being accepted
+func (fv *FunctionVariable) Accepts(params []*typ.Type) bool {
+	if len(params) != len(fv.Argsv) {
+		return false
+	}
+	for i, t := range params {
+		if t != nil && !t.Equals(fv.Argsv[i].GetType()) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewExternFunctionVariable returns the variable of a function which is not
+// defined in the program but linked from a library of compiled functions
+func NewExternFunctionVariable(name string, params []*typ.Type, rt *typ.Type) *FunctionVariable {
+	fv := &FunctionVariable{
+		Variable: Variable{
+			Name: name,
+			Type: typ.NewFunctionType(rt),
+		},
+		Returnv: VarFromType(rt, "@return_var"),
+		Argsv:   make([]VarInterface, 0),
+		Extern:  true,
+	}
+	for i, t := range params {
+		fv.AddType(t)
+		fv.Argsv = append(fv.Argsv, VarFromType(t, name+"@param"+strconv.Itoa(i)))
+	}
+	return fv
+}
+
 func (fv FunctionVariable) GetWire(i typ.Num) *wr.Wire {
 	if fv.Returnv != nil {
 		if i < fv.Returnv.Size() {
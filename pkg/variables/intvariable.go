@@ -147,7 +147,19 @@ func (ev *ExtInt) ChangeValue(val int) {
 			ev.Wires = append(ev.Wires, FalseV.W)
 		}
 	} else {
-		ev.Wires = wr.IntToWireSet(1<<uint(ev.Size())-ev.value, FalseV.W, TrueV.W)
+		// two's complement, the sign being extended to the whole size
+		ev.Wires = wr.NewWireSet(0)
+		for i := uint(0); i < uint(ev.Size()); i++ {
+			shift := i
+			if shift > 62 {
+				shift = 62
+			}
+			if ev.value>>shift&1 == 1 {
+				ev.Wires = append(ev.Wires, TrueV.W)
+			} else {
+				ev.Wires = append(ev.Wires, FalseV.W)
+			}
+		}
 	}
 }
 
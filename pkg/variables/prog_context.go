@@ -4,7 +4,6 @@ import (
 	"fmt"
 	typ "ixxoprivacy/pkg/types"
 	wr "ixxoprivacy/pkg/wires"
-	"os"
 	"strconv"
 	str "strings"
 
@@ -36,6 +35,11 @@ var setWiret *typ.Type
 
 var ReservedFunc map[string]*typ.Type
 
+var program *ast.Program
+var funcDecls map[string]*ast.FunctionLiteral // the functions declared in the program
+var generating map[string]bool                // the functions being typed, whose types are not known yet
+var returnTypes map[string]*typ.Type          // the return types of the functions by types of arguments
+
 /*                   Getters                                 */
 /*************************************************************/
 
@@ -108,8 +112,9 @@ func (pc ProgramContext) Print(indent string) {
 	}
 }
 
-// GenerateContext is called by OutputCircuit to create the ProgramContext which will be used in the compilation
-func GenerateContext(prog *ast.Program, intsize typ.Num, w0, w1 *wr.Wire) ProgramContext {
+// GenerateContext is called by OutputCircuit to create the ProgramContext which will be used in the compilation.
+// The functions linked from libraries are given by externs.
+func GenerateContext(prog *ast.Program, intsize typ.Num, w0, w1 *wr.Wire, externs []*FunctionVariable) ProgramContext {
 	PC = NewProgramContext()
 	intt = typ.NewIntType(intsize)
 	uintt = typ.NewUIntType(intsize)
@@ -137,6 +142,10 @@ func GenerateContext(prog *ast.Program, intsize typ.Num, w0, w1 *wr.Wire) Progra
 		"GetWire":    getWiret,
 		"SetWire":    setWiret,
 	}
+	// the types of the sorting functions depend on their arguments, see sorting.go
+	for name := range SortingFunc {
+		ReservedFunc[name] = typ.NewFunctionType(GetVoidType())
+	}
 
 	// First we find all variables declarations in the body
 	for _, dec := range prog.DeclarationList {
@@ -145,33 +154,104 @@ func GenerateContext(prog *ast.Program, intsize typ.Num, w0, w1 *wr.Wire) Progra
 		}
 	}
 
-	// Then we find functions declarations
+	for _, fv := range externs {
+		PC.FunctionContext[fv.GetName()] = fv
+	}
+
+	// Then we find functions declarations, the functions they call being
+	// typed first when needed
+	program = prog
+	funcDecls = make(map[string]*ast.FunctionLiteral)
+	generating = make(map[string]bool)
+	returnTypes = make(map[string]*typ.Type)
 	for _, dec := range prog.DeclarationList {
 		if d, ok := dec.(*ast.FunctionDeclaration); ok {
-			f := d.Function
-			fc := NewFunctionContext()
-
-			// We find the parameter types of the function and put it in the FunctionContext
-			fc.GetParams(f.Name.Name, prog, f.ParameterList.List)
-
-			// We find the types of all other variables in the function to complete the FunctionContext
-			for _, fdec := range f.DeclarationList {
-				fd, ok := fdec.(*ast.VariableDeclaration)
-				if !ok {
-					fmt.Println("Error in GenerateContext: only variables should be declared inside functions.")
-					os.Exit(64)
-				}
-				fc.addVarDeclaration(fd)
-			}
-			fc.CheckForRecTypes()
-			PC.Funcs[f.Name.Name] = fc
-
-			fv := NewFunctionVariable(f, fc)
-			fc[fv.Returnv.GetName()] = fv.Returnv
-			PC.FunctionContext[f.Name.Name] = fv
+			funcDecls[d.Function.Name.Name] = d.Function
+		}
+	}
+	for _, dec := range prog.DeclarationList {
+		if d, ok := dec.(*ast.FunctionDeclaration); ok {
+			PC.declareFunction(d.Function.Name.Name)
 		}
 	}
 	PC.CheckForRecTypes()
 	CheckProgram(prog)
 	return PC
 }
+
+// declareFunction adds to the program context the function of the given name,
+// its parameters being typed by the first call to it found
+func (pc ProgramContext) declareFunction(name string) {
+	if _, ok := pc.Funcs[name]; ok || generating[name] {
+		return
+	}
+	generating[name] = true
+	f := funcDecls[name]
+	fc := NewFunctionContext()
+	fc.GetParams(f, program)
+	fc.declare(f)
+	pc.addFunction(name, f, fc)
+	delete(generating, name)
+}
+
+// addFunction adds to the program context a function whose variables are in
+// the given FunctionContext
+func (pc ProgramContext) addFunction(name string, f *ast.FunctionLiteral, fc FunctionContext) *FunctionVariable {
+	pc.Funcs[name] = fc
+	fv := NewFunctionVariable(f, fc)
+	fv.Name = name
+	if fv.Returnv != nil {
+		fc[fv.Returnv.GetName()] = fv.Returnv
+	}
+	pc.FunctionContext[name] = fv
+	return fv
+}
+
+// instanceContext returns the FunctionContext of a function whose parameters
+// have the given types
+func instanceContext(f *ast.FunctionLiteral, params []*typ.Type) FunctionContext {
+	fc := NewFunctionContext()
+	for i, param := range f.ParameterList.List {
+		fc[param.Name] = VarFromType(params[i], param.Name)
+	}
+	fc.declare(f)
+	return fc
+}
+
+// NewInstance adds to the program context a copy of the function of the given
+// name under the name inst, with parameters of the given types and its own
+// variables, so that the function can be compiled several times
+func (pc ProgramContext) NewInstance(name, inst string, params []*typ.Type) *FunctionVariable {
+	f := pc.FunctionContext[name].(*FunctionVariable).FunctionNode
+	return pc.addFunction(inst, f, instanceContext(f, params))
+}
+
+// CallType returns the type of the value returned by the function of the given
+// name when it is called with arguments of the given types, or nil when it is
+// not known yet
+func (pc ProgramContext) CallType(name string, params []*typ.Type) *typ.Type {
+	pc.declareFunction(name)
+	fv, ok := pc.FunctionContext[name].(*FunctionVariable)
+	if !ok {
+		return nil
+	}
+	if fv.Accepts(params) {
+		return fv.Type.SubType
+	}
+	key := name + "("
+	for i, t := range params {
+		if i > 0 {
+			key += ", "
+		}
+		key += t.Name()
+	}
+	key += ")"
+	if t, ok := returnTypes[key]; ok || generating[key] {
+		return t
+	}
+	generating[key] = true
+	t := instanceContext(fv.FunctionNode, params).GetReturnType(fv.FunctionNode.Body)
+	delete(generating, key)
+	returnTypes[key] = t
+	return t
+}
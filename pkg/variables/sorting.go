@@ -0,0 +1,119 @@
+package variables
+
+import (
+	"fmt"
+	typ "ixxoprivacy/pkg/types"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// SortingFunc are the reserved functions sorting arrays. Their types depend on
+// the array given, so they are checked apart from the other reserved functions:
+//
+//	Sort(arr)                          returns arr sorted in increasing order
+//	SortBy(arr, "key")                 returns the array of objects arr sorted by the integer field key
+//	CompareExchange(arr, i, j)         exchanges arr[i] and arr[j] when arr[i] > arr[j]
+//	CompareExchangeBy(arr, i, j, "key") does the same on the field key of an array of objects
+var SortingFunc = map[string]bool{
+	"Sort":              true,
+	"SortBy":            true,
+	"CompareExchange":   true,
+	"CompareExchangeBy": true,
+}
+
+// sortingArgs are the number of arguments of the sorting functions
+var sortingArgs = map[string]int{
+	"Sort":              1,
+	"SortBy":            2,
+	"CompareExchange":   3,
+	"CompareExchangeBy": 4,
+}
+
+// sortingName returns the name of the sorting function called, or ""
+func sortingName(cExp *ast.CallExpression) string {
+	if id, ok := cExp.Callee.(*ast.Identifier); ok && SortingFunc[id.Name] {
+		return id.Name
+	}
+	return ""
+}
+
+// SortKey returns the type of the field of the items of an array by which
+// the array is sorted, key being "" when the items are integers
+func SortKey(arrt *typ.Type, key string) (*typ.Type, error) {
+	if !arrt.IsArrayType() {
+		return nil, fmt.Errorf("only arrays can be sorted")
+	}
+	t := arrt.SubType
+	if key != "" {
+		if !t.IsObjType() {
+			return nil, fmt.Errorf("the items of the array sorted by %q are no objects", key)
+		}
+		found := false
+		for i, k := range t.Keys {
+			if k == key {
+				t, found = t.List[i], true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("the items of the array have no key %q", key)
+		}
+	}
+	if !t.IsIntType() && !t.IsUIntType() {
+		return nil, fmt.Errorf("arrays are sorted by integers only")
+	}
+	return t, nil
+}
+
+// SortingKeyArg returns the key given to a sorting function, "" for the
+// functions without key
+func SortingKeyArg(cExp *ast.CallExpression) (string, error) {
+	name := sortingName(cExp)
+	if name != "SortBy" && name != "CompareExchangeBy" {
+		return "", nil
+	}
+	s, ok := cExp.ArgumentList[len(cExp.ArgumentList)-1].(*ast.StringLiteral)
+	if !ok {
+		return "", fmt.Errorf("the key given to %s must be a string literal", name)
+	}
+	return s.Value, nil
+}
+
+// sortingCallType returns the type of a call to a sorting function
+func (fc FunctionContext) sortingCallType(cExp *ast.CallExpression) *typ.Type {
+	switch sortingName(cExp) {
+	case "Sort", "SortBy":
+		if len(cExp.ArgumentList) > 0 {
+			return fc.GetNodeType(cExp.ArgumentList[0])
+		}
+	}
+	return GetVoidType()
+}
+
+// checkSortingCall checks the arguments of a call to a sorting function
+func (fc FunctionContext) checkSortingCall(cExp *ast.CallExpression) *typ.Type {
+	name := sortingName(cExp)
+	if len(cExp.ArgumentList) != sortingArgs[name] {
+		fmt.Println("Error: number of arguments is wrong,", name, "should have", sortingArgs[name], "arguments, received", len(cExp.ArgumentList))
+		return GetVoidType()
+	}
+	key, err := SortingKeyArg(cExp)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return GetVoidType()
+	}
+	arrt := fc.CheckNode(cExp.ArgumentList[0])
+	if _, err := SortKey(arrt, key); err != nil {
+		fmt.Println("Error in "+name+":", err)
+		return GetVoidType()
+	}
+	if name == "CompareExchange" || name == "CompareExchangeBy" {
+		for _, ind := range cExp.ArgumentList[1:3] {
+			if t := fc.CheckNode(ind); !t.IsIntType() && !t.IsUIntType() {
+				fmt.Println("Error: the indexes given to", name, "must be integers")
+			}
+		}
+		return GetVoidType()
+	}
+	return arrt
+}
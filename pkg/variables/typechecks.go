@@ -30,7 +30,7 @@ func (fc FunctionContext) CheckNode(n ast.Node) *typ.Type {
 		case tk.EQUAL, tk.NOT_EQUAL:
 			fc.checkBinarySame(n2.Left, n2.Right, n2.Operator)
 			return GetBoolt()
-		case tk.SHIFT_LEFT, tk.SHIFT_RIGHT:
+		case tk.SHIFT_LEFT, tk.SHIFT_RIGHT, tk.UNSIGNED_SHIFT_RIGHT:
 			return fc.checkShift(n2.Left, n2.Right, n2.Operator)
 		case tk.LOGICAL_AND, tk.LOGICAL_OR:
 			fc.checkBool(n2.Left)
@@ -259,6 +259,19 @@ func (fc FunctionContext) checkNumber(operand ast.Expression, op tk.Token) *typ.
 }
 
 func (fc FunctionContext) checkFunctionCall(cExp *ast.CallExpression) *typ.Type {
+	if sortingName(cExp) != "" {
+		return fc.checkSortingCall(cExp)
+	}
+	if id, ok := cExp.Callee.(*ast.Identifier); ok && fc.isUserFunction(id.Name) {
+		// the functions are compiled for the types of their arguments
+		params := make([]*typ.Type, len(cExp.ArgumentList))
+		for i, argExp := range cExp.ArgumentList {
+			params[i] = fc.CheckNode(argExp)
+		}
+		if t := PC.CallType(id.Name, params); t != nil {
+			return t
+		}
+	}
 	t := fc.CheckNode(cExp.Callee)
 	if !t.IsFunctionType() {
 		fmt.Println("Error: Callee is not a function.")